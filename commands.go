@@ -0,0 +1,929 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/github"
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/logging"
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/statemigration"
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/tfvars"
+)
+
+// Exit codes shared by every subcommand.
+const (
+	// exitOK is returned when the subcommand succeeds.
+	exitOK = 0
+
+	// exitFailure is returned when the subcommand runs but fails, or finds problems.
+	exitFailure = 1
+
+	// exitUsage is returned when the subcommand or its flags are invalid.
+	exitUsage = 2
+
+	// exitUnauthorized is returned when the Terraform Cloud token is invalid, expired, or lacks permission.
+	exitUnauthorized = 3
+
+	// exitNotFound is returned when a workspace or other Terraform Cloud resource does not exist.
+	exitNotFound = 4
+
+	// exitLocked is returned when a workspace's state is locked by another operation.
+	exitLocked = 5
+
+	// exitCommandFailed is returned when terraform, tofu, or tfmigrate fails.
+	exitCommandFailed = 6
+
+	// exitTimeout is returned when a command runs for longer than the command timeout.
+	exitTimeout = 7
+
+	// exitAPIError is returned when the Terraform Cloud API fails for any other reason.
+	exitAPIError = 8
+
+	// exitInterrupted is returned when the job is cancelled by SIGINT or SIGTERM.
+	exitInterrupted = 130
+)
+
+// errInvalidFlags is returned when a subcommand's flags are each valid, but not together.
+var errInvalidFlags = errors.New("invalid flags")
+
+// Output formats for subcommand results.
+const (
+	// outputText writes human-readable results.
+	outputText = "text"
+
+	// outputJSON writes a single JSON document to stdout, with all logs written to stderr.
+	outputJSON = "json"
+)
+
+// subcommand is a single action that the CLI can run.
+type subcommand struct {
+
+	// name is the subcommand as typed, such as "vars pull".
+	name string
+
+	// summary describes the subcommand in help output.
+	summary string
+
+	// isApply is the ISAPPLY value the subcommand sets, if any.
+	isApply string
+
+	// registerFlags registers the flags specific to the subcommand, if any.
+	registerFlags func(flagSet *flag.FlagSet)
+
+	// resultOnly is whether stdout holds only the subcommand's result, so that it can be piped
+	// into a file, with progress messages written to stderr as they are with --output=json.
+	resultOnly bool
+
+	// run executes the subcommand, returning a result to be written in the selected output format.
+	run func(ctx context.Context) (commandResult, error)
+}
+
+// commandResult is the outcome of a subcommand.
+type commandResult interface {
+
+	// writeText writes the result in a human-readable format.
+	writeText(w io.Writer)
+
+	// failed reports whether the result itself represents a failure, such as validation errors.
+	failed() bool
+}
+
+// jsonEnvelope is the JSON document written for every subcommand when --output=json is used.
+type jsonEnvelope struct {
+
+	// Command is the subcommand that was run.
+	Command string `json:"command"`
+
+	// Success is whether the subcommand succeeded.
+	Success bool `json:"success"`
+
+	// Error is the error message if the subcommand failed.
+	Error string `json:"error,omitempty"`
+
+	// Result is the subcommand's result.
+	Result commandResult `json:"result,omitempty"`
+}
+
+// subcommands are the subcommands the CLI supports, in the order they are listed in help output.
+var subcommands = []subcommand{
+	{
+		name:    "plan",
+		summary: "Run tfmigrate plan for every workspace",
+		isApply: "false",
+		run:     runMigrate,
+	},
+	{
+		name:    "apply",
+		summary: "Run tfmigrate apply for every workspace",
+		isApply: "true",
+		run:     runMigrate,
+	},
+	{
+		name:    "vars pull",
+		summary: "Write each workspace's zz_dragondrop.auto.tfvars file from Terraform Cloud",
+		run:     runVarsPull,
+	},
+	{
+		name:          "vars export",
+		summary:       "Print the effective variables of workspaces and where each was taken from",
+		registerFlags: registerVarsExportFlags,
+		resultOnly:    true,
+		run:           runVarsExport,
+	},
+	{
+		name:          "vars diff",
+		summary:       "Compare the effective variables of two workspaces, or of a workspace and a tfvars file",
+		registerFlags: registerVarsDiffFlags,
+		run:           runVarsDiff,
+	},
+	{
+		name:          "vars sync",
+		summary:       "Create, update, and delete variables in Terraform Cloud to match a file, once confirmed",
+		registerFlags: registerVarsSyncFlags,
+		run:           runVarsSync,
+	},
+	{
+		name:    "validate",
+		summary: "Check tfmigrate configuration and migration files, without contacting Terraform Cloud",
+		run:     runValidate,
+	},
+	{
+		name:    "unlock",
+		summary: "Force-unlock the state of every locked workspace",
+		run:     runUnlock,
+	},
+	{
+		name:    "status",
+		summary: "Show the state lock and active runs of every workspace",
+		run:     runStatus,
+	},
+}
+
+// findSubcommand returns the subcommand named by the leading arguments, and the remaining arguments.
+func findSubcommand(args []string) (subcommand, []string, bool) {
+	for _, sc := range subcommands {
+		words := strings.Fields(sc.name)
+		if len(args) < len(words) {
+			continue
+		}
+
+		if strings.Join(args[:len(words)], " ") == sc.name {
+			return sc, args[len(words):], true
+		}
+	}
+
+	return subcommand{}, nil, false
+}
+
+// runSubcommand parses the subcommand's flags, runs it, and writes its result, returning the exit code.
+func runSubcommand(ctx context.Context, sc subcommand, args []string, stdout io.Writer, stderr io.Writer) int {
+	flagSet := flag.NewFlagSet(sc.name, flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+	flagSet.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: tfstate-migration %v [flags]\n\n%v.\n\nEach flag overrides the environment variable shown.\n\n", sc.name, sc.summary)
+		flagSet.PrintDefaults()
+	}
+	output := flagSet.String("output", outputText, "output format, text or json")
+	// plan and apply set ISAPPLY themselves, and it has no effect on the other subcommands.
+	registerConfigFlags(flagSet, "is-apply")
+	if sc.registerFlags != nil {
+		sc.registerFlags(flagSet)
+	}
+
+	err := flagSet.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+
+	if flagSet.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %v\n", strings.Join(flagSet.Args(), " "))
+		return exitUsage
+	}
+
+	if *output != outputText && *output != outputJSON {
+		fmt.Fprintf(stderr, "output must be either '%v' or '%v', got %v\n", outputText, outputJSON, *output)
+		return exitUsage
+	}
+
+	err = applyConfigFlags(flagSet)
+	if err == nil && sc.isApply != "" {
+		err = os.Setenv("ISAPPLY", sc.isApply)
+	} else if err == nil && os.Getenv("ISAPPLY") == "" {
+		// ISAPPLY is required by the statemigration Config, but does not affect this subcommand.
+		err = os.Setenv("ISAPPLY", "false")
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error applying flags: %v\n", err)
+		return exitUsage
+	}
+
+	if *output == outputJSON || sc.resultOnly {
+		// Progress messages and command output are written to stdout, so they are moved to
+		// stderr to keep stdout a single JSON document, or the result alone.
+		os.Stdout = os.Stderr
+	}
+
+	err = logging.Setup(os.Stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "error configuring logging: %v\n", err)
+		return exitUsage
+	}
+
+	result, err := sc.run(ctx)
+
+	exitCode := exitCodeFor(ctx, err)
+	if exitCode == exitOK && result != nil && result.failed() {
+		exitCode = exitFailure
+	}
+
+	if *output == outputJSON {
+		envelope := jsonEnvelope{Command: sc.name, Success: exitCode == exitOK, Result: result}
+		if err != nil {
+			envelope.Error = logging.Redact(err.Error())
+		}
+
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(envelope)
+
+		return exitCode
+	}
+
+	if result != nil {
+		result.writeText(stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error running %v: %v\n", sc.name, logging.Redact(err.Error()))
+	}
+
+	return exitCode
+}
+
+// exitCodeFor maps the error returned by a subcommand to an exit code, so that scripts can tell
+// failures apart without parsing messages.
+func exitCodeFor(ctx context.Context, err error) int {
+	var stateMigrationAPIError *statemigration.APIError
+	var tfVarsAPIError *tfvars.APIError
+
+	switch {
+	case ctx.Err() != nil:
+		return exitInterrupted
+	case err == nil:
+		return exitOK
+	case errors.Is(err, statemigration.ErrCommandTimeout):
+		return exitTimeout
+	case errors.Is(err, statemigration.ErrLockConflict):
+		return exitLocked
+	case errors.Is(err, statemigration.ErrCommandFailed):
+		return exitCommandFailed
+	case errors.Is(err, errInvalidFlags), errors.Is(err, tfvars.ErrMissingSensitiveVariables):
+		return exitUsage
+	case errors.Is(err, statemigration.ErrUnauthorized), errors.Is(err, statemigration.ErrForbidden),
+		errors.Is(err, statemigration.ErrMissingPermission),
+		errors.Is(err, tfvars.ErrUnauthorized), errors.Is(err, tfvars.ErrForbidden):
+		return exitUnauthorized
+	case errors.Is(err, statemigration.ErrNotFound), errors.Is(err, tfvars.ErrNotFound):
+		return exitNotFound
+	case errors.As(err, &stateMigrationAPIError), errors.As(err, &tfVarsAPIError):
+		return exitAPIError
+	default:
+		return exitFailure
+	}
+}
+
+// writeUsage writes the list of subcommands.
+func writeUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: tfstate-migration <command> [flags]\n\nCommands:\n")
+	for _, sc := range subcommands {
+		fmt.Fprintf(w, "  %-12v %v\n", sc.name, sc.summary)
+	}
+	fmt.Fprintf(w, "\nRun 'tfstate-migration <command> --help' for the flags of a command.\n")
+	fmt.Fprintf(w, "Without a command, tfmigrate plan or apply is run as set by ISAPPLY.\n")
+}
+
+// messageResult is the result of a subcommand that only reports success.
+type messageResult struct {
+
+	// Message describes what the subcommand did.
+	Message string `json:"message"`
+}
+
+func (r messageResult) writeText(w io.Writer) {
+	fmt.Fprintln(w, r.Message)
+}
+
+func (r messageResult) failed() bool {
+	return false
+}
+
+// migrateResult is the result of the plan and apply subcommands, the same report as is written
+// to the report file.
+type migrateResult struct {
+	statemigration.RunReport
+}
+
+func (r migrateResult) writeText(w io.Writer) {
+	for _, result := range r.Workspaces {
+		if result.Success {
+			fmt.Fprintf(w, "%v: %v succeeded with %v operations\n", result.Workspace, result.Mode, len(result.Operations))
+		} else {
+			fmt.Fprintf(w, "%v: %v failed\n", result.Workspace, result.Mode)
+		}
+	}
+}
+
+func (r migrateResult) failed() bool {
+	return false
+}
+
+// runMigrate runs tfmigrate plan or apply for every workspace, as set by ISAPPLY.
+func runMigrate(ctx context.Context) (commandResult, error) {
+	stateMigrator, err := statemigration.NewStateMigrator()
+	if err != nil {
+		return nil, fmt.Errorf("[statemigration.NewStateMigrator] %w", err)
+	}
+
+	report, err := stateMigrator.MigrateAllWorkspaces(ctx)
+	reportToGitHub(ctx, report.Workspaces, err)
+	if err != nil {
+		return migrateResult{RunReport: report}, fmt.Errorf("[stateMigrator.MigrateAllWorkspaces] %w", err)
+	}
+
+	return migrateResult{RunReport: report}, nil
+}
+
+// reportToGitHub writes step outputs and a step summary describing the results when running
+// within GitHub Actions, and comments on the pull request if enabled. Failures are logged rather
+// than failing the job.
+func reportToGitHub(ctx context.Context, results []statemigration.WorkspaceResult, jobErr error) {
+	actions, err := github.NewActions()
+	if err != nil {
+		slog.Warn("Unable to report results to GitHub Actions.", "error", err)
+		return
+	}
+
+	outputs, err := github.ResultOutputs(results)
+	if err == nil {
+		err = actions.SetOutputs(outputs)
+	}
+	if err != nil {
+		slog.Warn("Unable to set GitHub Actions outputs.", "error", err)
+	}
+
+	err = actions.AppendStepSummary(github.RenderStepSummary(results))
+	if err != nil {
+		slog.Warn("Unable to write the GitHub Actions step summary.", "error", err)
+	}
+
+	commenter, err := github.NewPullRequestCommenter()
+	if err != nil {
+		slog.Warn("Unable to comment on the pull request.", "error", err)
+		return
+	}
+
+	if !commenter.Enabled() {
+		return
+	}
+
+	// The comment is still posted if the job was cancelled, so that it reflects what happened.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+
+	err = commenter.UpsertComment(ctx, github.RenderPullRequestComment(results, jobErr))
+	if err != nil {
+		slog.Warn("Unable to comment on the pull request.", "error", err)
+	}
+}
+
+// runVarsPull writes each workspace's variables file, which is kept rather than cleaned up.
+func runVarsPull(ctx context.Context) (commandResult, error) {
+	tfVar, err := tfvars.NewTFVars()
+	if err != nil {
+		return nil, fmt.Errorf("[tfvars.NewTFVars] %w", err)
+	}
+
+	variables, _, err := tfVar.CreateAllWorkspaceVarsFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("[tfVar.CreateAllWorkspaceVarsFiles] %w", err)
+	}
+
+	return varsPullResult{Variables: variables}, nil
+}
+
+// varsPullResult is the result of the vars pull subcommand.
+type varsPullResult struct {
+
+	// Variables are the names of the variables written for each workspace.
+	Variables map[string]tfvars.SourcedVariables `json:"variables"`
+}
+
+func (r varsPullResult) writeText(w io.Writer) {
+	workspaces := make([]string, 0, len(r.Variables))
+	for workspace := range r.Variables {
+		workspaces = append(workspaces, workspace)
+	}
+	sort.Strings(workspaces)
+
+	for _, workspace := range workspaces {
+		variables := r.Variables[workspace]
+		fmt.Fprintf(
+			w, "%v: %v terraform variables, %v environment variables\n",
+			workspace, len(variables.Terraform), len(variables.Env),
+		)
+		for _, name := range variables.Terraform {
+			fmt.Fprintf(w, "  %v (%v)\n", name, variables.TerraformSources[name])
+		}
+		for _, name := range variables.Env {
+			fmt.Fprintf(w, "  env %v (%v)\n", name, variables.EnvSources[name])
+		}
+	}
+}
+
+func (r varsPullResult) failed() bool {
+	return false
+}
+
+// varsExportOptions are the values of the flags specific to the vars export subcommand.
+var varsExportOptions struct {
+
+	// workspaces are the comma-separated workspaces to export, every configured workspace if empty.
+	workspaces string
+
+	// format is the format in which the variables are written.
+	format tfvars.ExportFormat
+
+	// revealSensitive is whether the values of sensitive variables are written rather than masked.
+	revealSensitive bool
+}
+
+// registerVarsExportFlags registers the flags specific to the vars export subcommand.
+func registerVarsExportFlags(flagSet *flag.FlagSet) {
+	varsExportOptions.format = tfvars.ExportTFVars
+
+	flagSet.StringVar(
+		&varsExportOptions.workspaces, "workspaces", "",
+		"comma-separated workspaces to export (default every workspace of --workspace-to-directories)",
+	)
+	flagSet.Func(
+		"format", "format of the variables, one of tfvars, json, dotenv, or shell (default tfvars)",
+		varsExportOptions.format.Decode,
+	)
+	flagSet.BoolVar(
+		&varsExportOptions.revealSensitive, "reveal-sensitive", false,
+		"write the values of sensitive variables rather than masking them",
+	)
+}
+
+// runVarsExport prints the effective variables of each workspace.
+func runVarsExport(ctx context.Context) (commandResult, error) {
+	tfVar, err := tfvars.NewTFVars()
+	if err != nil {
+		return nil, fmt.Errorf("[tfvars.NewTFVars] %w", err)
+	}
+
+	var workspaces []string
+	for _, workspace := range strings.Split(varsExportOptions.workspaces, ",") {
+		if workspace = strings.TrimSpace(workspace); workspace != "" {
+			workspaces = append(workspaces, workspace)
+		}
+	}
+
+	variables, err := tfVar.ExportWorkspaceVariables(ctx, workspaces)
+	if err != nil {
+		return nil, fmt.Errorf("[tfVar.ExportWorkspaceVariables] %w", err)
+	}
+
+	if !varsExportOptions.revealSensitive {
+		variables = tfvars.MaskSensitiveValues(variables)
+	}
+
+	formatted, err := tfvars.FormatExportedVariables(variables, varsExportOptions.format)
+	if err != nil {
+		return nil, fmt.Errorf("[tfvars.FormatExportedVariables] %w", err)
+	}
+
+	return varsExportResult{Workspaces: variables, formatted: formatted}, nil
+}
+
+// varsExportResult is the result of the vars export subcommand.
+type varsExportResult struct {
+
+	// Workspaces are the effective variables of each workspace.
+	Workspaces map[string][]tfvars.ExportedVariable `json:"workspaces"`
+
+	// formatted are the variables written in the format selected, which is the text output.
+	formatted []byte
+}
+
+func (r varsExportResult) writeText(w io.Writer) {
+	_, _ = w.Write(r.formatted)
+}
+
+func (r varsExportResult) failed() bool {
+	return false
+}
+
+// varsDiffOptions are the values of the flags specific to the vars diff subcommand.
+var varsDiffOptions struct {
+
+	// from is the workspace whose variables are compared.
+	from string
+
+	// to is the workspace whose variables are compared against, if any.
+	to string
+
+	// file is the .tfvars or .tfvars.json file whose variables are compared against, if any.
+	file string
+
+	// revealSensitive is whether the values of sensitive variables are written rather than masked.
+	revealSensitive bool
+}
+
+// registerVarsDiffFlags registers the flags specific to the vars diff subcommand.
+func registerVarsDiffFlags(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&varsDiffOptions.from, "from", "", "workspace whose variables are compared")
+	flagSet.StringVar(&varsDiffOptions.to, "to", "", "workspace whose variables are compared against")
+	flagSet.StringVar(&varsDiffOptions.file, "file", "", ".tfvars or .tfvars.json file whose variables are compared against")
+	flagSet.BoolVar(
+		&varsDiffOptions.revealSensitive, "reveal-sensitive", false,
+		"write the values of sensitive variables rather than masking them",
+	)
+}
+
+// runVarsDiff compares the effective variables of a workspace with those of another workspace or a file.
+func runVarsDiff(ctx context.Context) (commandResult, error) {
+	from, to, file := varsDiffOptions.from, varsDiffOptions.to, varsDiffOptions.file
+	if from == "" || (to == "") == (file == "") {
+		return nil, fmt.Errorf("%w: --from and either --to or --file are required", errInvalidFlags)
+	}
+
+	tfVar, err := tfvars.NewTFVars()
+	if err != nil {
+		return nil, fmt.Errorf("[tfvars.NewTFVars] %w", err)
+	}
+
+	workspaces := []string{from}
+	if to != "" {
+		workspaces = append(workspaces, to)
+	}
+
+	variables, err := tfVar.ExportWorkspaceVariables(ctx, workspaces)
+	if err != nil {
+		return nil, fmt.Errorf("[tfVar.ExportWorkspaceVariables] %w", err)
+	}
+
+	fromVariables, toVariables := variables[from], variables[to]
+
+	if file != "" {
+		toVariables, err = tfvars.ReadTFVarsFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("[tfvars.ReadTFVarsFile] %w", err)
+		}
+		to = file
+
+		// A file only holds Terraform variables, so environment variables are not compared.
+		var terraformVariables []tfvars.ExportedVariable
+		for _, variable := range fromVariables {
+			if variable.Category == "terraform" {
+				terraformVariables = append(terraformVariables, variable)
+			}
+		}
+		fromVariables = terraformVariables
+	}
+
+	differences := tfvars.DiffVariables(fromVariables, toVariables)
+	if !varsDiffOptions.revealSensitive {
+		differences = tfvars.MaskSensitiveDifferences(differences)
+	}
+
+	return varsDiffResult{From: from, To: to, Differences: differences}, nil
+}
+
+// varsDiffResult is the result of the vars diff subcommand.
+type varsDiffResult struct {
+
+	// From is the workspace whose variables were compared.
+	From string `json:"from"`
+
+	// To is the workspace or file whose variables were compared against.
+	To string `json:"to"`
+
+	// Differences are the variables that were added, removed, or changed.
+	Differences []tfvars.VariableDifference `json:"differences"`
+}
+
+func (r varsDiffResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "%v -> %v: %v differences\n", r.From, r.To, len(r.Differences))
+
+	for _, difference := range r.Differences {
+		switch difference.Change {
+		case tfvars.ChangeAdded:
+			fmt.Fprintf(
+				w, "+ %v %v = %v (%v)\n",
+				difference.Category, difference.Key, difference.To.DisplayValue(), difference.To.Source,
+			)
+		case tfvars.ChangeRemoved:
+			fmt.Fprintf(
+				w, "- %v %v = %v (%v)\n",
+				difference.Category, difference.Key, difference.From.DisplayValue(), difference.From.Source,
+			)
+		default:
+			fmt.Fprintf(
+				w, "~ %v %v = %v (%v) -> %v (%v)\n",
+				difference.Category, difference.Key,
+				difference.From.DisplayValue(), difference.From.Source,
+				difference.To.DisplayValue(), difference.To.Source,
+			)
+		}
+	}
+}
+
+func (r varsDiffResult) failed() bool {
+	return false
+}
+
+// varsSyncOptions are the values of the flags specific to the vars sync subcommand.
+var varsSyncOptions struct {
+
+	// file is the JSON file declaring the variables of workspaces and variable sets.
+	file string
+
+	// autoApprove is whether the changes are made without asking for confirmation.
+	autoApprove bool
+
+	// revealSensitive is whether the values of sensitive variables are written rather than masked.
+	revealSensitive bool
+}
+
+// registerVarsSyncFlags registers the flags specific to the vars sync subcommand.
+func registerVarsSyncFlags(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&varsSyncOptions.file, "file", "", "JSON file declaring the variables of workspaces and variable sets")
+	flagSet.BoolVar(
+		&varsSyncOptions.autoApprove, "auto-approve", false,
+		"make the changes without asking for confirmation",
+	)
+	flagSet.BoolVar(
+		&varsSyncOptions.revealSensitive, "reveal-sensitive", false,
+		"write the values of sensitive variables rather than masking them",
+	)
+}
+
+// runVarsSync plans the changes that make the variables within Terraform Cloud match a file, and
+// makes them once confirmed on stdin or with --auto-approve.
+func runVarsSync(ctx context.Context) (commandResult, error) {
+	if varsSyncOptions.file == "" {
+		return nil, fmt.Errorf("%w: --file is required", errInvalidFlags)
+	}
+
+	syncFile, err := tfvars.ReadSyncFile(varsSyncOptions.file)
+	if err != nil {
+		return nil, fmt.Errorf("[tfvars.ReadSyncFile] %w", err)
+	}
+
+	tfVar, err := tfvars.NewTFVars()
+	if err != nil {
+		return nil, fmt.Errorf("[tfvars.NewTFVars] %w", err)
+	}
+
+	changes, err := tfVar.PlanVariableSync(ctx, syncFile)
+	if err != nil {
+		return nil, fmt.Errorf("[tfVar.PlanVariableSync] %w", err)
+	}
+
+	result := varsSyncResult{File: varsSyncOptions.file, Changes: changes}
+	if !varsSyncOptions.revealSensitive {
+		result.Changes = tfvars.MaskSensitiveChanges(changes)
+	}
+
+	if len(changes) == 0 {
+		return result, nil
+	}
+
+	// The plan is written alongside progress messages, ahead of the prompt, so that with
+	// --output=json it is written to stderr.
+	writeSyncPlan(os.Stdout, result.Changes)
+
+	if !varsSyncOptions.autoApprove && !confirmSync(os.Stdin, os.Stderr) {
+		return result, nil
+	}
+
+	result.Approved = true
+	result.Applied, err = tfVar.ApplyVariableSync(ctx, changes)
+	if err != nil {
+		return result, fmt.Errorf("[tfVar.ApplyVariableSync] %w", err)
+	}
+
+	return result, nil
+}
+
+// confirmSync asks whether the planned changes should be made, accepting only "yes".
+func confirmSync(r io.Reader, w io.Writer) bool {
+	fmt.Fprint(w, "Make these changes in Terraform Cloud? Only 'yes' will be accepted: ")
+
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	fmt.Fprintln(w)
+
+	return strings.TrimSpace(answer) == "yes"
+}
+
+// writeSyncPlan writes each planned change on a line of its own.
+func writeSyncPlan(w io.Writer, changes []tfvars.SyncChange) {
+	for _, change := range changes {
+		switch change.Operation {
+		case tfvars.SyncCreate:
+			fmt.Fprintf(
+				w, "+ %v %v %v = %v\n",
+				change.Target, change.Category, change.Key, change.After.DisplayValue(),
+			)
+		case tfvars.SyncDelete:
+			fmt.Fprintf(
+				w, "- %v %v %v = %v\n",
+				change.Target, change.Category, change.Key, change.Before.DisplayValue(),
+			)
+		default:
+			var attributes []string
+			for _, attribute := range change.Changed {
+				switch attribute {
+				case "value":
+					attributes = append(
+						attributes, fmt.Sprintf("value %v -> %v", change.Before.DisplayValue(), change.After.DisplayValue()),
+					)
+				case "hcl":
+					attributes = append(attributes, fmt.Sprintf("hcl %v -> %v", change.Before.HCL, change.After.HCL))
+				case "sensitive":
+					attributes = append(
+						attributes, fmt.Sprintf("sensitive %v -> %v", change.Before.Sensitive, change.After.Sensitive),
+					)
+				case "description":
+					attributes = append(
+						attributes, fmt.Sprintf("description %q -> %q", change.Before.Description, change.After.Description),
+					)
+				}
+			}
+
+			fmt.Fprintf(
+				w, "~ %v %v %v: %v\n",
+				change.Target, change.Category, change.Key, strings.Join(attributes, ", "),
+			)
+		}
+	}
+}
+
+// varsSyncResult is the result of the vars sync subcommand.
+type varsSyncResult struct {
+
+	// File is the file declaring the variables.
+	File string `json:"file"`
+
+	// Changes are the changes that make the variables within Terraform Cloud match the file.
+	Changes []tfvars.SyncChange `json:"changes"`
+
+	// Approved is whether the changes were confirmed.
+	Approved bool `json:"approved"`
+
+	// Applied is the number of changes made.
+	Applied int `json:"applied"`
+}
+
+func (r varsSyncResult) writeText(w io.Writer) {
+	switch {
+	case len(r.Changes) == 0:
+		fmt.Fprintf(w, "%v: no changes, Terraform Cloud matches the file\n", r.File)
+	case !r.Approved:
+		fmt.Fprintf(w, "%v: %v changes planned and not made, confirm them or use --auto-approve to make them\n", r.File, len(r.Changes))
+	default:
+		fmt.Fprintf(w, "%v: %v of %v changes made\n", r.File, r.Applied, len(r.Changes))
+	}
+}
+
+func (r varsSyncResult) failed() bool {
+	return false
+}
+
+// validateResult is the result of the validate subcommand.
+type validateResult struct {
+
+	// Workspaces are the validation results for each workspace.
+	Workspaces []statemigration.WorkspaceValidation `json:"workspaces"`
+}
+
+func (r validateResult) writeText(w io.Writer) {
+	for _, validation := range r.Workspaces {
+		if len(validation.Errors) == 0 {
+			fmt.Fprintf(w, "%v: valid (%v files)\n", validation.Workspace, len(validation.Files))
+			continue
+		}
+
+		fmt.Fprintf(w, "%v: %v problems\n", validation.Workspace, len(validation.Errors))
+		for _, validationError := range validation.Errors {
+			fmt.Fprintf(w, "  %v\n", validationError)
+		}
+	}
+}
+
+func (r validateResult) failed() bool {
+	for _, validation := range r.Workspaces {
+		if len(validation.Errors) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// runValidate checks every workspace's tfmigrate configuration and migration files.
+func runValidate(_ context.Context) (commandResult, error) {
+	config, err := statemigration.NewValidateConfig()
+	if err != nil {
+		return nil, fmt.Errorf("[statemigration.NewValidateConfig] %w", err)
+	}
+
+	return validateResult{Workspaces: statemigration.ValidateWorkspaces(config)}, nil
+}
+
+// unlockResult is the result of the unlock subcommand.
+type unlockResult struct {
+
+	// Workspaces are the unlock results for each workspace.
+	Workspaces []statemigration.WorkspaceUnlock `json:"workspaces"`
+}
+
+func (r unlockResult) writeText(w io.Writer) {
+	for _, unlock := range r.Workspaces {
+		if unlock.WasLocked {
+			fmt.Fprintf(w, "%v: unlocked (was locked by %v)\n", unlock.Workspace, unlock.LockedBy)
+		} else {
+			fmt.Fprintf(w, "%v: not locked\n", unlock.Workspace)
+		}
+	}
+}
+
+func (r unlockResult) failed() bool {
+	return false
+}
+
+// runUnlock force-unlocks the state of every locked workspace.
+func runUnlock(ctx context.Context) (commandResult, error) {
+	stateMigrator, err := statemigration.NewStateMigrator()
+	if err != nil {
+		return nil, fmt.Errorf("[statemigration.NewStateMigrator] %w", err)
+	}
+
+	unlocks, err := stateMigrator.UnlockWorkspaces(ctx)
+	return unlockResult{Workspaces: unlocks}, err
+}
+
+// statusResult is the result of the status subcommand.
+type statusResult struct {
+
+	// Workspaces are the statuses of each workspace.
+	Workspaces []statemigration.WorkspaceStatus `json:"workspaces"`
+}
+
+func (r statusResult) writeText(w io.Writer) {
+	for _, status := range r.Workspaces {
+		lock := "unlocked"
+		if status.Locked {
+			lock = fmt.Sprintf("locked by %v", status.LockedBy)
+		}
+
+		var runs []string
+		for _, run := range status.ActiveRuns {
+			runs = append(runs, fmt.Sprintf("%v (%v)", run.ID, run.Status))
+		}
+		sort.Strings(runs)
+
+		if len(runs) == 0 {
+			fmt.Fprintf(w, "%v: %v, no active runs\n", status.Workspace, lock)
+		} else {
+			fmt.Fprintf(w, "%v: %v, active runs: %v\n", status.Workspace, lock, strings.Join(runs, ", "))
+		}
+	}
+}
+
+func (r statusResult) failed() bool {
+	return false
+}
+
+// runStatus gets the state lock and active runs of every workspace.
+func runStatus(ctx context.Context) (commandResult, error) {
+	stateMigrator, err := statemigration.NewStateMigrator()
+	if err != nil {
+		return nil, fmt.Errorf("[statemigration.NewStateMigrator] %w", err)
+	}
+
+	statuses, err := stateMigrator.WorkspaceStatuses(ctx)
+	return statusResult{Workspaces: statuses}, err
+}
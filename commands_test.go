@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/internal/tfcapi"
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/statemigration"
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/tfvars"
+)
+
+func TestFindSubcommand(t *testing.T) {
+	sc, args, ok := findSubcommand([]string{"vars", "pull", "--output=json"})
+	if !ok || sc.name != "vars pull" {
+		t.Fatalf("expected to find vars pull, got %v", sc.name)
+	}
+
+	if !reflect.DeepEqual(args, []string{"--output=json"}) {
+		t.Errorf("got %v, expected %v", args, []string{"--output=json"})
+	}
+
+	_, _, ok = findSubcommand([]string{"vars"})
+	if ok {
+		t.Errorf("expected vars without pull not to be a subcommand")
+	}
+
+	_, _, ok = findSubcommand([]string{"--is-apply"})
+	if ok {
+		t.Errorf("expected flags alone not to be a subcommand")
+	}
+}
+
+func TestRunSubcommandUsageErrors(t *testing.T) {
+	sc, _, _ := findSubcommand([]string{"validate"})
+
+	var stdout, stderr bytes.Buffer
+	exitCode := runSubcommand(context.Background(), sc, []string{"--output=yaml"}, &stdout, &stderr)
+	if exitCode != exitUsage {
+		t.Errorf("got exit code %v, expected %v", exitCode, exitUsage)
+	}
+
+	exitCode = runSubcommand(context.Background(), sc, []string{"--not-a-flag"}, &stdout, &stderr)
+	if exitCode != exitUsage {
+		t.Errorf("got exit code %v, expected %v", exitCode, exitUsage)
+	}
+
+	exitCode = runSubcommand(context.Background(), sc, []string{"--help"}, &stdout, &stderr)
+	if exitCode != exitOK {
+		t.Errorf("got exit code %v, expected %v", exitCode, exitOK)
+	}
+
+	// Flags specific to one subcommand are only accepted by it.
+	exitCode = runSubcommand(context.Background(), sc, []string{"--format=json"}, &stdout, &stderr)
+	if exitCode != exitUsage {
+		t.Errorf("got exit code %v, expected %v", exitCode, exitUsage)
+	}
+
+	exportSubcommand, _, _ := findSubcommand([]string{"vars", "export"})
+
+	exitCode = runSubcommand(context.Background(), exportSubcommand, []string{"--format=yaml"}, &stdout, &stderr)
+	if exitCode != exitUsage {
+		t.Errorf("got exit code %v, expected %v", exitCode, exitUsage)
+	}
+
+	diffSubcommand, _, _ := findSubcommand([]string{"vars", "diff"})
+
+	exitCode = runSubcommand(
+		context.Background(), diffSubcommand, []string{"--from=dev", "--to=prod", "--file=prod.tfvars"}, &stdout, &stderr,
+	)
+	if exitCode != exitUsage {
+		t.Errorf("got exit code %v, expected %v", exitCode, exitUsage)
+	}
+
+	syncSubcommand, _, _ := findSubcommand([]string{"vars", "sync"})
+
+	exitCode = runSubcommand(context.Background(), syncSubcommand, []string{"--auto-approve"}, &stdout, &stderr)
+	if exitCode != exitUsage {
+		t.Errorf("got exit code %v, expected %v", exitCode, exitUsage)
+	}
+}
+
+func TestWriteSyncPlan(t *testing.T) {
+	changes := []tfvars.SyncChange{
+		{
+			Target: "workspace:prod", Operation: tfvars.SyncCreate, Key: "zones", Category: "terraform",
+			After: &tfvars.SyncAttributes{Value: "[\"a\", \"b\"]", HCL: true},
+		},
+		{
+			Target: "workspace:prod", Operation: tfvars.SyncUpdate, Key: "region", Category: "terraform",
+			Before:  &tfvars.SyncAttributes{Value: "us-east1"},
+			After:   &tfvars.SyncAttributes{Value: "us-west1", Description: "Region"},
+			Changed: []string{"value", "description"},
+		},
+		{
+			Target: "varset:global", Operation: tfvars.SyncUpdate, Key: "db_password", Category: "terraform",
+			Before:  &tfvars.SyncAttributes{Sensitive: true, Withheld: true},
+			After:   &tfvars.SyncAttributes{Value: "***", Sensitive: true, Masked: true},
+			Changed: []string{"value"},
+		},
+		{
+			Target: "varset:global", Operation: tfvars.SyncDelete, Key: "TOKEN", Category: "env",
+			Before: &tfvars.SyncAttributes{Value: "abc"},
+		},
+	}
+
+	expectedOutput := `+ workspace:prod terraform zones = ["a", "b"]
+~ workspace:prod terraform region: value "us-east1" -> "us-west1", description "" -> "Region"
+~ varset:global terraform db_password: value (withheld) -> ***
+- varset:global env TOKEN = "abc"
+`
+
+	var output bytes.Buffer
+	writeSyncPlan(&output, changes)
+
+	if output.String() != expectedOutput {
+		t.Errorf("got:\n%v\nexpected:\n%v", output.String(), expectedOutput)
+	}
+}
+
+func TestConfirmSync(t *testing.T) {
+	inputToExpected := map[string]bool{
+		"yes\n":   true,
+		" yes \n": true,
+		"y\n":     false,
+		"no\n":    false,
+		"":        false,
+	}
+
+	for input, expected := range inputToExpected {
+		var prompt bytes.Buffer
+
+		output := confirmSync(strings.NewReader(input), &prompt)
+		if output != expected {
+			t.Errorf("got %v, expected %v for %q", output, expected, input)
+		}
+	}
+}
+
+func TestVarsDiffResultWriteText(t *testing.T) {
+	result := varsDiffResult{
+		From: "dev",
+		To:   "prod",
+		Differences: []tfvars.VariableDifference{
+			{
+				Key: "region", Category: "terraform", Change: tfvars.ChangeChanged,
+				From: &tfvars.ExportedVariable{Value: "us-east1", Source: "varset:global"},
+				To:   &tfvars.ExportedVariable{Value: "us-west1", Source: "workspace"},
+			},
+			{
+				Key: "zones", Category: "terraform", Change: tfvars.ChangeAdded,
+				To: &tfvars.ExportedVariable{Value: `["a", "b"]`, Source: "workspace", HCL: true},
+			},
+			{
+				Key: "TOKEN", Category: "env", Change: tfvars.ChangeRemoved,
+				From: &tfvars.ExportedVariable{Value: "***", Source: "workspace", Sensitive: true, Masked: true},
+			},
+		},
+	}
+
+	expectedOutput := `dev -> prod: 3 differences
+~ terraform region = "us-east1" (varset:global) -> "us-west1" (workspace)
++ terraform zones = ["a", "b"] (workspace)
+- env TOKEN = *** (workspace)
+`
+
+	var output bytes.Buffer
+	result.writeText(&output)
+
+	if output.String() != expectedOutput {
+		t.Errorf("got:\n%v\nexpected:\n%v", output.String(), expectedOutput)
+	}
+}
+
+func TestRunSubcommandValidateJSON(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "ws", "dragondrop", "tfmigrate", ".tfmigrate.hcl"), `
+tfmigrate {
+  migration_dir = "./dragondrop/tfmigrate"
+}
+`)
+	writeTestFile(t, filepath.Join(root, "ws", "dragondrop", "tfmigrate", "1_move.hcl"), `
+migration "state" "move" {
+  actions = ["mv aws_s3_bucket.a aws_s3_bucket.b"]
+}
+`)
+
+	// validate does not contact Terraform Cloud, so it must not need its credentials or a version.
+	for _, name := range []string{"TERRAFORMCLOUDORGANIZATION", "TERRAFORMCLOUDTOKEN", "TERRAFORMVERSION", "ISAPPLY"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	t.Setenv("WORKSPACETODIRECTORY", "workspace_1:/ws/")
+	t.Setenv("ROOTDIRECTORY", root)
+
+	stdoutFile := os.Stdout
+	t.Cleanup(func() { os.Stdout = stdoutFile })
+
+	sc, _, _ := findSubcommand([]string{"validate"})
+
+	var stdout, stderr bytes.Buffer
+	exitCode := runSubcommand(context.Background(), sc, []string{"--output=json"}, &stdout, &stderr)
+	if exitCode != exitOK {
+		t.Fatalf("got exit code %v, expected %v: %v", exitCode, exitOK, stdout.String())
+	}
+
+	var envelope struct {
+		Command string `json:"command"`
+		Success bool   `json:"success"`
+		Result  struct {
+			Workspaces []struct {
+				Workspace string   `json:"workspace"`
+				Files     []string `json:"files"`
+			} `json:"workspaces"`
+		} `json:"result"`
+	}
+
+	err := json.Unmarshal(stdout.Bytes(), &envelope)
+	if err != nil {
+		t.Fatalf("expected stdout to be JSON: %v", err)
+	}
+
+	if envelope.Command != "validate" || !envelope.Success {
+		t.Errorf("got %+v, expected a successful validate", envelope)
+	}
+
+	if len(envelope.Result.Workspaces) != 1 || len(envelope.Result.Workspaces[0].Files) != 2 {
+		t.Errorf("expected workspace_1 with two files, got %+v", envelope.Result.Workspaces)
+	}
+}
+
+func writeTestFile(t *testing.T, fileName string, content string) {
+	err := os.MkdirAll(filepath.Dir(fileName), 0755)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = os.WriteFile(fileName, []byte(content), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestExitCodeFor(t *testing.T) {
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		ctx      context.Context
+		err      error
+		expected int
+	}{
+		{ctx: context.Background(), err: nil, expected: exitOK},
+		{ctx: cancelledCtx, err: errors.New("interrupted"), expected: exitInterrupted},
+		{ctx: context.Background(), err: errors.New("something went wrong"), expected: exitFailure},
+		{
+			ctx:      context.Background(),
+			err:      fmt.Errorf("[sm.getWorkspaceID] %w", &statemigration.APIError{APIError: tfcapi.APIError{StatusCode: 401}}),
+			expected: exitUnauthorized,
+		},
+		{
+			ctx:      context.Background(),
+			err:      fmt.Errorf("[tfc.getWorkspaceVariables] %w", &tfvars.APIError{APIError: tfcapi.APIError{StatusCode: 403}}),
+			expected: exitUnauthorized,
+		},
+		{
+			ctx: context.Background(),
+			err: fmt.Errorf(
+				"[sm.tfVar.CreateAllWorkspaceVarsFiles] %w",
+				&tfvars.MissingSensitiveVariablesError{Variables: []tfvars.MissingSensitiveVariable{{Key: "db_password"}}},
+			),
+			expected: exitUsage,
+		},
+		{
+			ctx:      context.Background(),
+			err:      fmt.Errorf("%w: --from and either --to or --file are required", errInvalidFlags),
+			expected: exitUsage,
+		},
+		{
+			ctx:      context.Background(),
+			err:      fmt.Errorf("[sm.preflight] %w", &statemigration.PermissionError{Workspace: "workspace_1"}),
+			expected: exitUnauthorized,
+		},
+		{
+			ctx:      context.Background(),
+			err:      fmt.Errorf("[sm.getWorkspaceID] %w", &statemigration.APIError{APIError: tfcapi.APIError{StatusCode: 404}}),
+			expected: exitNotFound,
+		},
+		{
+			ctx:      context.Background(),
+			err:      fmt.Errorf("[sm.getWorkspace] %w", &statemigration.APIError{APIError: tfcapi.APIError{StatusCode: 423}}),
+			expected: exitLocked,
+		},
+		{
+			ctx:      context.Background(),
+			err:      fmt.Errorf("[sm.discardRun] %w", &statemigration.APIError{APIError: tfcapi.APIError{StatusCode: 409}}),
+			expected: exitAPIError,
+		},
+		{
+			ctx:      context.Background(),
+			err:      &statemigration.CommandError{ExitCode: 1, Output: "Error acquiring the state lock"},
+			expected: exitLocked,
+		},
+		{
+			ctx:      context.Background(),
+			err:      &statemigration.CommandError{ExitCode: 1, Err: errors.New("exit status 1")},
+			expected: exitCommandFailed,
+		},
+		{
+			ctx:      context.Background(),
+			err:      &statemigration.CommandError{ExitCode: -1, Err: statemigration.ErrCommandTimeout},
+			expected: exitTimeout,
+		},
+		{
+			ctx:      context.Background(),
+			err:      fmt.Errorf("[tfc.getVarSetVars] %w", &tfvars.APIError{APIError: tfcapi.APIError{StatusCode: 500}}),
+			expected: exitAPIError,
+		},
+	}
+
+	for _, testCase := range testCases {
+		output := exitCodeFor(testCase.ctx, testCase.err)
+
+		if output != testCase.expected {
+			t.Errorf("got %v, expected %v for %v", output, testCase.expected, testCase.err)
+		}
+	}
+}
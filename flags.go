@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// configFlag is a command-line flag that sets one of the environment variables read by each
+// package's Config, so that flags and the GitHub Action's inputs share a single configuration path.
+type configFlag struct {
+
+	// name is the flag name, matching the corresponding action input.
+	name string
+
+	// envVar is the environment variable that the flag sets.
+	envVar string
+
+	// usage describes the flag in --help output.
+	usage string
+
+	// isBool is whether the flag can be passed without a value.
+	isBool bool
+}
+
+// configFlags are the flags that can be used in place of environment variables.
+var configFlags = []configFlag{
+	{name: "is-apply", envVar: "ISAPPLY", usage: "run tfmigrate apply rather than tfmigrate plan", isBool: true},
+	{name: "terraform-cloud-organization", envVar: "TERRAFORMCLOUDORGANIZATION", usage: "Terraform Cloud organization"},
+	{name: "terraform-cloud-token", envVar: "TERRAFORMCLOUDTOKEN", usage: "Terraform Cloud token, prefer the TERRAFORMCLOUDTOKEN environment variable"},
+	{name: "terraform-workspace-sensitive-vars", envVar: "TERRAFORMWORKSPACESENSITIVEVARS", usage: "JSON map of workspace names to sensitive variables"},
+	{name: "terraform-var-set-sensitive-vars", envVar: "TERRAFORMVARSETSENSITIVEVARS", usage: "JSON map of variable set names to sensitive variables"},
+	{name: "sensitive-vars-file", envVar: "SENSITIVEVARSFILE", usage: "path of a JSON file of sensitive variables"},
+	{name: "sensitive-vars-sops-file", envVar: "SENSITIVEVARSSOPSFILE", usage: "path of a SOPS-encrypted YAML or JSON file of sensitive variables"},
+	{name: "vault-address", envVar: "VAULTADDRESS", usage: "address of the Vault server to read sensitive variables from (default $VAULT_ADDR)"},
+	{name: "vault-token", envVar: "VAULTTOKEN", usage: "Vault token, prefer the VAULT_TOKEN environment variable"},
+	{name: "vault-kv-mount", envVar: "VAULTKVMOUNT", usage: "mount path of the Vault KV version 2 secrets engine (default secret)"},
+	{name: "vault-secret-path", envVar: "VAULTSECRETPATH", usage: "path of the Vault secret of sensitive variables"},
+	{name: "include-undeclared-vars", envVar: "INCLUDEUNDECLAREDVARS", usage: "write variables the root module does not declare to the variables file", isBool: true},
+	{name: "keep-vars-files", envVar: "KEEPVARSFILES", usage: "keep the variables files written once the run ends", isBool: true},
+	{name: "tfvars-format", envVar: "TFVARSFORMAT", usage: "format of the variables files written, hcl or json (default hcl)"},
+	{name: "engine", envVar: "ENGINE", usage: "binary used to run migrations, terraform or tofu"},
+	{name: "terraform-version", envVar: "TERRAFORMVERSION", usage: "exact version, version constraint, or auto"},
+	{name: "terraform-mirror-url", envVar: "TERRAFORMMIRRORURL", usage: "base URL of a Terraform releases mirror"},
+	{name: "tofu-mirror-url", envVar: "TOFUMIRRORURL", usage: "base URL of an OpenTofu releases mirror"},
+	{name: "tofu-api-url", envVar: "TOFUAPIURL", usage: "URL listing OpenTofu releases"},
+	{name: "terraform-archive-directory", envVar: "TERRAFORMARCHIVEDIRECTORY", usage: "directory of pre-downloaded release archives"},
+	{name: "terraform-cache-directory", envVar: "TERRAFORMCACHEDIRECTORY", usage: "directory where installed binaries are cached"},
+	{name: "command-timeout", envVar: "COMMANDTIMEOUT", usage: "maximum duration of each command, such as 30m"},
+	{name: "shutdown-grace-period", envVar: "SHUTDOWNGRACEPERIOD", usage: "how long a command has to exit after SIGTERM, such as 5s"},
+	{name: "workspace-to-directories", envVar: "WORKSPACETODIRECTORY", usage: "comma-separated workspace:directory pairs"},
+	{name: "pr-comment", envVar: "PRCOMMENT", usage: "comment the results on the pull request", isBool: true},
+	{name: "github-token", envVar: "GITHUBTOKEN", usage: "token used to comment on pull requests"},
+	{name: "github-api-url", envVar: "GITHUBAPIURL", usage: "base URL of the GitHub API (default $GITHUB_API_URL or https://api.github.com)"},
+	{name: "github-repository", envVar: "GITHUB_REPOSITORY", usage: "owner/name of the repository to comment on"},
+	{name: "pull-request-number", envVar: "PULLREQUESTNUMBER", usage: "pull request to comment on (default the pull request of $GITHUB_EVENT_PATH)"},
+	{name: "root-directory", envVar: "ROOTDIRECTORY", usage: "directory that workspace directories are relative to (default $GITHUB_WORKSPACE or the current directory)"},
+	{name: "log-level", envVar: "LOGLEVEL", usage: "minimum level logged, one of debug, info, warn, or error (default info)"},
+	{name: "log-format", envVar: "LOGFORMAT", usage: "format logs are written in, text or json (default text)"},
+	{name: "report-file", envVar: "REPORTFILE", usage: "path to which a JSON report of the job is written"},
+}
+
+// envFlagValue implements flag.Value, recording the value passed for a configFlag.
+type envFlagValue struct {
+
+	// value is the value passed on the command line.
+	value string
+
+	// isBool is whether the flag can be passed without a value.
+	isBool bool
+}
+
+// String returns the value passed on the command line.
+func (v *envFlagValue) String() string {
+	return v.value
+}
+
+// Set records the value passed on the command line.
+func (v *envFlagValue) Set(value string) error {
+	v.value = value
+	return nil
+}
+
+// IsBoolFlag reports whether the flag can be passed without a value.
+func (v *envFlagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+// registerConfigFlags defines each configFlag on the flag set, other than those named in excluded.
+func registerConfigFlags(flagSet *flag.FlagSet, excluded ...string) {
+	excludedSet := map[string]bool{}
+	for _, name := range excluded {
+		excludedSet[name] = true
+	}
+
+	for _, cf := range configFlags {
+		if excludedSet[cf.name] {
+			continue
+		}
+		flagSet.Var(&envFlagValue{isBool: cf.isBool}, cf.name, fmt.Sprintf("%v (env %v)", cf.usage, cf.envVar))
+	}
+}
+
+// applyConfigFlags sets the environment variable for each configFlag passed on the command
+// line, taking precedence over any value already in the environment.
+func applyConfigFlags(flagSet *flag.FlagSet) error {
+	envVars := map[string]string{}
+	for _, cf := range configFlags {
+		envVars[cf.name] = cf.envVar
+	}
+
+	var err error
+	flagSet.Visit(func(f *flag.Flag) {
+		envVar, ok := envVars[f.Name]
+		if !ok || err != nil {
+			return
+		}
+
+		err = os.Setenv(envVar, f.Value.String())
+	})
+
+	if err != nil {
+		return fmt.Errorf("[os.Setenv] %w", err)
+	}
+
+	return nil
+}
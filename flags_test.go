@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestApplyConfigFlags(t *testing.T) {
+	t.Setenv("ISAPPLY", "false")
+	t.Setenv("ROOTDIRECTORY", "")
+	t.Setenv("ENGINE", "terraform")
+
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	registerConfigFlags(flagSet)
+
+	err := flagSet.Parse([]string{"--is-apply", "--root-directory=/tmp/repo"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = applyConfigFlags(flagSet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedEnv := map[string]string{
+		"ISAPPLY":       "true",
+		"ROOTDIRECTORY": "/tmp/repo",
+		"ENGINE":        "terraform",
+	}
+
+	for envVar, expectedValue := range expectedEnv {
+		if value := os.Getenv(envVar); value != expectedValue {
+			t.Errorf("got %v for %v, expected %v", value, envVar, expectedValue)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package github
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Actions is an interface for reporting results to the GitHub Actions runner.
+type Actions interface {
+
+	// SetOutputs sets step outputs that later steps can read as steps.<id>.outputs.<name>.
+	SetOutputs(outputs map[string]string) error
+
+	// AppendStepSummary appends Markdown to the job's step summary.
+	AppendStepSummary(markdown string) error
+}
+
+// actionsFiles implements the Actions interface by writing to the files provided by the runner.
+type actionsFiles struct {
+
+	// config contains the paths of the runner's files.
+	config *Config
+}
+
+// NewActions instantiates a new implementation of the Actions interface.
+func NewActions() (Actions, error) {
+	conf, err := NewConfig()
+	if err != nil {
+		return nil, fmt.Errorf("[NewConfig] %w", err)
+	}
+
+	return &actionsFiles{config: conf}, nil
+}
+
+// SetOutputs appends each output to the GITHUB_OUTPUT file, using a random delimiter so that
+// values may span multiple lines.
+func (af *actionsFiles) SetOutputs(outputs map[string]string) error {
+	if af.config.OutputFile == "" {
+		return nil
+	}
+
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	for _, name := range names {
+		delimiter, err := randomDelimiter()
+		if err != nil {
+			return fmt.Errorf("[randomDelimiter] %w", err)
+		}
+
+		if strings.Contains(outputs[name], delimiter) {
+			return fmt.Errorf("output %v contains its delimiter", name)
+		}
+
+		fmt.Fprintf(&builder, "%v<<%v\n%v\n%v\n", name, delimiter, outputs[name], delimiter)
+	}
+
+	return appendToFile(af.config.OutputFile, builder.String())
+}
+
+// AppendStepSummary appends Markdown to the GITHUB_STEP_SUMMARY file.
+func (af *actionsFiles) AppendStepSummary(markdown string) error {
+	if af.config.StepSummaryFile == "" {
+		return nil
+	}
+
+	return appendToFile(af.config.StepSummaryFile, markdown)
+}
+
+// randomDelimiter generates a heredoc delimiter that is vanishingly unlikely to appear in a value.
+func randomDelimiter() (string, error) {
+	randomBytes := make([]byte, 16)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", fmt.Errorf("[rand.Read] %w", err)
+	}
+
+	return "ghadelimiter_" + hex.EncodeToString(randomBytes), nil
+}
+
+// appendToFile appends content to the file, which the runner has already created.
+func appendToFile(fileName string, content string) error {
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("[os.OpenFile] %w", err)
+	}
+
+	_, err = file.WriteString(content)
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("[file.WriteString] %w", err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("[file.Close] %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package github
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestSetOutputs(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "output")
+	actions := actionsFiles{config: &Config{OutputFile: outputFile}}
+
+	err := actions.SetOutputs(map[string]string{"state-operations": "2", "failed-workspaces": "[\"a\"]\n"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedPattern := regexp.MustCompile(
+		`^failed-workspaces<<(ghadelimiter_[0-9a-f]+)\n\["a"\]\n\n(ghadelimiter_[0-9a-f]+)\n` +
+			`state-operations<<(ghadelimiter_[0-9a-f]+)\n2\n(ghadelimiter_[0-9a-f]+)\n$`,
+	)
+
+	matches := expectedPattern.FindStringSubmatch(string(content))
+	if matches == nil || matches[1] != matches[2] || matches[3] != matches[4] {
+		t.Errorf("unexpected GITHUB_OUTPUT content:\n%v", string(content))
+	}
+}
+
+func TestAppendStepSummaryWithoutFile(t *testing.T) {
+	actions := actionsFiles{config: &Config{}}
+
+	err := actions.AppendStepSummary("## Summary")
+	if err != nil {
+		t.Errorf("expected no error outside of GitHub Actions, got %v", err)
+	}
+}
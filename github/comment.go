@@ -0,0 +1,229 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+)
+
+// commentsPerPage is the number of comments requested per page when searching for the existing comment.
+const commentsPerPage = 100
+
+// PullRequestCommenter is an interface for posting results to a pull request.
+type PullRequestCommenter interface {
+
+	// Enabled reports whether commenting is configured and the job was triggered by a pull request.
+	Enabled() bool
+
+	// UpsertComment creates the results comment on the pull request, or updates it in place if
+	// it was posted by an earlier job.
+	UpsertComment(ctx context.Context, body string) error
+}
+
+// pullRequestComments implements the PullRequestCommenter interface with the GitHub REST API.
+type pullRequestComments struct {
+
+	// config contains the variables needed to call the GitHub API.
+	config *Config
+
+	// httpClient is an HTTP Client for use in all http calls made by pullRequestComments.
+	httpClient http.Client
+
+	// pullRequestNumber is the number of the pull request commented on, zero if there is none.
+	pullRequestNumber int
+}
+
+// NewPullRequestCommenter instantiates a new implementation of the PullRequestCommenter interface.
+func NewPullRequestCommenter() (PullRequestCommenter, error) {
+	conf, err := NewConfig()
+	if err != nil {
+		return nil, fmt.Errorf("[NewConfig] %w", err)
+	}
+
+	pullRequestNumber := conf.PullRequestNumber
+	if pullRequestNumber == 0 && conf.EventPath != "" {
+		pullRequestNumber, err = readPullRequestNumber(conf.EventPath)
+		if err != nil {
+			return nil, fmt.Errorf("[readPullRequestNumber] %w", err)
+		}
+	}
+
+	return &pullRequestComments{
+		config:            conf,
+		httpClient:        http.Client{},
+		pullRequestNumber: pullRequestNumber,
+	}, nil
+}
+
+// readPullRequestNumber reads the pull request number from the payload of the event that triggered
+// the job, returning zero if the event was not for a pull request.
+func readPullRequestNumber(eventPath string) (int, error) {
+	content, err := os.ReadFile(eventPath)
+	if err != nil {
+		return 0, fmt.Errorf("[os.ReadFile] %w", err)
+	}
+
+	jsonParsed, err := gabs.ParseJSON(content)
+	if err != nil {
+		return 0, fmt.Errorf("[gabs.ParseJSON] %w", err)
+	}
+
+	number, ok := jsonParsed.Path("pull_request.number").Data().(float64)
+	if !ok {
+		return 0, nil
+	}
+
+	return int(number), nil
+}
+
+// Enabled reports whether commenting is configured and the job was triggered by a pull request.
+func (prc *pullRequestComments) Enabled() bool {
+	return prc.config.PRComment && prc.pullRequestNumber != 0
+}
+
+// UpsertComment creates the results comment on the pull request, or updates the existing one.
+func (prc *pullRequestComments) UpsertComment(ctx context.Context, body string) error {
+	if prc.config.GithubToken == "" {
+		return fmt.Errorf("a GitHub token is required to comment on pull requests")
+	}
+
+	if prc.config.Repository == "" {
+		return fmt.Errorf("GITHUB_REPOSITORY is required to comment on pull requests")
+	}
+
+	body = prc.commentMarker() + "\n" + body
+
+	commentID, err := prc.findComment(ctx)
+	if err != nil {
+		return fmt.Errorf("[prc.findComment] %w", err)
+	}
+
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return fmt.Errorf("[json.Marshal] %w", err)
+	}
+
+	if commentID != "" {
+		requestPath := fmt.Sprintf("%v/repos/%v/issues/comments/%v", prc.apiURL(), prc.config.Repository, commentID)
+		_, err = prc.githubRequest(ctx, "updateComment", "PATCH", requestPath, payload)
+		return err
+	}
+
+	requestPath := fmt.Sprintf("%v/repos/%v/issues/%v/comments", prc.apiURL(), prc.config.Repository, prc.pullRequestNumber)
+	_, err = prc.githubRequest(ctx, "createComment", "POST", requestPath, payload)
+	return err
+}
+
+// findComment searches the pull request's comments for one containing the comment marker, returning
+// its ID, or an empty string if there is none.
+func (prc *pullRequestComments) findComment(ctx context.Context) (string, error) {
+	for page := 1; ; page++ {
+		requestPath := fmt.Sprintf(
+			"%v/repos/%v/issues/%v/comments?per_page=%v&page=%v",
+			prc.apiURL(), prc.config.Repository, prc.pullRequestNumber, commentsPerPage, page,
+		)
+
+		jsonResponseBytes, err := prc.githubRequest(ctx, "listComments", "GET", requestPath, nil)
+		if err != nil {
+			return "", err
+		}
+
+		commentID, count, err := extractMarkedCommentID(jsonResponseBytes, prc.commentMarker())
+		if err != nil {
+			return "", fmt.Errorf("[extractMarkedCommentID] %w", err)
+		}
+
+		if commentID != "" || count < commentsPerPage {
+			return commentID, nil
+		}
+	}
+}
+
+// extractMarkedCommentID returns the ID of the comment containing marker within a page of
+// comments, alongside the number of comments on the page.
+func extractMarkedCommentID(jsonBytes []byte, marker string) (string, int, error) {
+	jsonParsed, err := gabs.ParseJSON(jsonBytes)
+	if err != nil {
+		return "", 0, fmt.Errorf("[gabs.ParseJSON] %w", err)
+	}
+
+	comments := jsonParsed.Children()
+
+	for _, comment := range comments {
+		body, _ := comment.Path("body").Data().(string)
+		if !strings.Contains(body, marker) {
+			continue
+		}
+
+		id, ok := comment.Path("id").Data().(float64)
+		if !ok {
+			return "", 0, fmt.Errorf("unable to find comment id")
+		}
+
+		return strconv.FormatInt(int64(id), 10), len(comments), nil
+	}
+
+	return "", len(comments), nil
+}
+
+// commentMarker returns a hidden HTML comment identifying the comment to update on later pushes.
+// It names the workflow and job, so that each job commenting on the same pull request updates
+// its own comment rather than that of another. The names are escaped so that neither can end the
+// HTML comment early.
+func (prc *pullRequestComments) commentMarker() string {
+	return fmt.Sprintf(
+		"<!-- dragondrop-tfstate-migration workflow=%v job=%v -->",
+		url.QueryEscape(prc.config.Workflow), url.QueryEscape(prc.config.Job),
+	)
+}
+
+// apiURL returns the GitHub API base URL without a trailing slash.
+func (prc *pullRequestComments) apiURL() string {
+	return strings.TrimSuffix(prc.config.GithubAPIURL, "/")
+}
+
+// githubRequest builds, executes, and processes a call to the GitHub API.
+func (prc *pullRequestComments) githubRequest(ctx context.Context, requestName string, method string, requestPath string, payload []byte) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, requestPath, body)
+	if err != nil {
+		return nil, fmt.Errorf("[%v] error in newRequest: %w", requestName, err)
+	}
+
+	request.Header.Set("Authorization", "Bearer "+prc.config.GithubToken)
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if payload != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := prc.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("[%v] error in http %v request to GitHub: %w", requestName, method, err)
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("[%v] error in reading response body: %w", requestName, err)
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, fmt.Errorf("[%v] was unsuccessful, with the server returning: %v: %v", requestName, response.StatusCode, string(responseBody))
+	}
+
+	return responseBody, nil
+}
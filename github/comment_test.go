@@ -0,0 +1,204 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testGitHubAPI is a stand-in for the GitHub issue comments API.
+type testGitHubAPI struct {
+	mutex    sync.Mutex
+	comments []map[string]interface{}
+	requests []string
+}
+
+func (api *testGitHubAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+
+	api.requests = append(api.requests, r.Method+" "+r.URL.Path)
+
+	if r.Header.Get("Authorization") != "Bearer example_token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var payload map[string]string
+	if r.Body != nil {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &payload)
+	}
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/repos/org/repo/issues/7/comments":
+		if r.URL.Query().Get("page") != "1" {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(api.comments)
+	case r.Method == "POST" && r.URL.Path == "/repos/org/repo/issues/7/comments":
+		comment := map[string]interface{}{"id": 100 + len(api.comments), "body": payload["body"]}
+		api.comments = append(api.comments, comment)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(comment)
+	case r.Method == "PATCH" && strings.HasPrefix(r.URL.Path, "/repos/org/repo/issues/comments/"):
+		for _, comment := range api.comments {
+			if fmt.Sprintf("/repos/org/repo/issues/comments/%v", comment["id"]) == r.URL.Path {
+				comment["body"] = payload["body"]
+				_ = json.NewEncoder(w).Encode(comment)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestUpsertComment(t *testing.T) {
+	api := &testGitHubAPI{
+		comments: []map[string]interface{}{{"id": 1, "body": "LGTM"}},
+	}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	prc := pullRequestComments{
+		config: &Config{
+			PRComment:    true,
+			GithubToken:  "example_token",
+			GithubAPIURL: server.URL + "/",
+			Repository:   "org/repo",
+		},
+		pullRequestNumber: 7,
+	}
+
+	if !prc.Enabled() {
+		t.Fatalf("expected commenting to be enabled")
+	}
+
+	err := prc.UpsertComment(context.Background(), "first push")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = prc.UpsertComment(context.Background(), "second push")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(api.comments) != 2 {
+		t.Fatalf("expected the comment to be updated in place, got %v", api.comments)
+	}
+
+	expectedBody := "<!-- dragondrop-tfstate-migration workflow= job= -->\nsecond push"
+	if api.comments[1]["body"] != expectedBody {
+		t.Errorf("got %v, expected %v", api.comments[1]["body"], expectedBody)
+	}
+
+	expectedRequests := []string{
+		"GET /repos/org/repo/issues/7/comments",
+		"POST /repos/org/repo/issues/7/comments",
+		"GET /repos/org/repo/issues/7/comments",
+		"PATCH /repos/org/repo/issues/comments/101",
+	}
+
+	if !reflect.DeepEqual(api.requests, expectedRequests) {
+		t.Errorf("got %v, expected %v", api.requests, expectedRequests)
+	}
+}
+
+func TestUpsertCommentPerJob(t *testing.T) {
+	api := &testGitHubAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	for _, job := range []string{"migrate-dev", "migrate-prod", "migrate-dev"} {
+		prc := pullRequestComments{
+			config: &Config{
+				PRComment:    true,
+				GithubToken:  "example_token",
+				GithubAPIURL: server.URL,
+				Repository:   "org/repo",
+				Workflow:     "State migration",
+				Job:          job,
+			},
+			pullRequestNumber: 7,
+		}
+
+		err := prc.UpsertComment(context.Background(), "results of "+job)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(api.comments) != 2 {
+		t.Fatalf("expected one comment per job, got %v", api.comments)
+	}
+
+	expectedBodies := []string{
+		"<!-- dragondrop-tfstate-migration workflow=State+migration job=migrate-dev -->\nresults of migrate-dev",
+		"<!-- dragondrop-tfstate-migration workflow=State+migration job=migrate-prod -->\nresults of migrate-prod",
+	}
+
+	for i, expectedBody := range expectedBodies {
+		if api.comments[i]["body"] != expectedBody {
+			t.Errorf("got %v, expected %v", api.comments[i]["body"], expectedBody)
+		}
+	}
+}
+
+func TestUpsertCommentRequiresToken(t *testing.T) {
+	prc := pullRequestComments{
+		config:            &Config{PRComment: true, Repository: "org/repo"},
+		pullRequestNumber: 7,
+	}
+
+	err := prc.UpsertComment(context.Background(), "body")
+	if err == nil {
+		t.Errorf("expected an error without a GitHub token")
+	}
+}
+
+func TestReadPullRequestNumber(t *testing.T) {
+	directory := t.TempDir()
+
+	pullRequestEvent := filepath.Join(directory, "pull_request.json")
+	err := os.WriteFile(pullRequestEvent, []byte(`{"action": "synchronize", "pull_request": {"number": 42}}`), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output, err := readPullRequestNumber(pullRequestEvent)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if output != 42 {
+		t.Errorf("got %v, expected %v", output, 42)
+	}
+
+	pushEvent := filepath.Join(directory, "push.json")
+	err = os.WriteFile(pushEvent, []byte(`{"ref": "refs/heads/main"}`), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output, err = readPullRequestNumber(pushEvent)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if output != 0 {
+		t.Errorf("got %v, expected %v", output, 0)
+	}
+}
@@ -0,0 +1,69 @@
+package github
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// defaultAPIURL is the GitHub API used when neither GithubAPIURL nor GITHUB_API_URL is set.
+const defaultAPIURL = "https://api.github.com"
+
+// Config contains the variables, most of which are set by the GitHub Actions runner, needed to
+// support the Actions and PullRequestCommenter interfaces.
+type Config struct {
+
+	// OutputFile is the path of the file to which step outputs are written. Outputs are
+	// not written when empty, such as when running outside of GitHub Actions.
+	OutputFile string `envconfig:"GITHUB_OUTPUT" required:"false"`
+
+	// StepSummaryFile is the path of the file to which the Markdown step summary is written.
+	// The summary is not written when empty.
+	StepSummaryFile string `envconfig:"GITHUB_STEP_SUMMARY" required:"false"`
+
+	// PRComment is whether to post the results as a comment on the pull request that triggered the job.
+	PRComment bool `envconfig:"PRCOMMENT" default:"false"`
+
+	// GithubToken is the token used to comment on pull requests.
+	GithubToken string `envconfig:"GITHUBTOKEN" required:"false"`
+
+	// GithubAPIURL is the base URL of the GitHub API. It defaults to GITHUB_API_URL, which the
+	// runner sets, and otherwise https://api.github.com.
+	GithubAPIURL string `envconfig:"GITHUBAPIURL" required:"false"`
+
+	// Repository is the "owner/name" of the repository the job is running in.
+	Repository string `envconfig:"GITHUB_REPOSITORY" required:"false"`
+
+	// EventPath is the path of the JSON payload of the event that triggered the job.
+	EventPath string `envconfig:"GITHUB_EVENT_PATH" required:"false"`
+
+	// Workflow is the name of the workflow the job is running in.
+	Workflow string `envconfig:"GITHUB_WORKFLOW" required:"false"`
+
+	// Job is the ID of the job within the workflow.
+	Job string `envconfig:"GITHUB_JOB" required:"false"`
+
+	// PullRequestNumber is the pull request to comment on. It defaults to the pull request of
+	// the event that triggered the job.
+	PullRequestNumber int `envconfig:"PULLREQUESTNUMBER" required:"false"`
+}
+
+// NewConfig instantiates a new instance of Config
+func NewConfig() (*Config, error) {
+	var c Config
+	err := envconfig.Process("", &c)
+
+	if err != nil {
+		return nil, fmt.Errorf("[envconfig.Process] Error loading config: %w", err)
+	}
+
+	if c.GithubAPIURL == "" {
+		c.GithubAPIURL = os.Getenv("GITHUB_API_URL")
+	}
+	if c.GithubAPIURL == "" {
+		c.GithubAPIURL = defaultAPIURL
+	}
+
+	return &c, err
+}
@@ -0,0 +1,201 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/logging"
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/statemigration"
+)
+
+// ResultOutputs builds the step outputs describing the results of a migration job.
+func ResultOutputs(results []statemigration.WorkspaceResult) (map[string]string, error) {
+	migratedWorkspaces := []string{}
+	failedWorkspaces := []string{}
+	refreshRunURLs := []string{}
+	stateOperations := 0
+
+	for _, result := range results {
+		if result.Success {
+			migratedWorkspaces = append(migratedWorkspaces, result.Workspace)
+		} else {
+			failedWorkspaces = append(failedWorkspaces, result.Workspace)
+		}
+
+		if result.RefreshRunURL != "" {
+			refreshRunURLs = append(refreshRunURLs, result.RefreshRunURL)
+		}
+
+		stateOperations += len(result.Operations)
+	}
+
+	outputs := map[string]string{
+		"state-operations": strconv.Itoa(stateOperations),
+	}
+
+	for name, values := range map[string][]string{
+		"migrated-workspaces": migratedWorkspaces,
+		"failed-workspaces":   failedWorkspaces,
+		"refresh-run-urls":    refreshRunURLs,
+	} {
+		valuesJSON, err := json.Marshal(values)
+		if err != nil {
+			return nil, fmt.Errorf("[json.Marshal] %w", err)
+		}
+		outputs[name] = string(valuesJSON)
+	}
+
+	return outputs, nil
+}
+
+// RenderStepSummary renders a Markdown summary of the results of a migration job, with a table of
+// workspaces followed by the operations run in each.
+func RenderStepSummary(results []statemigration.WorkspaceResult) string {
+	var builder strings.Builder
+
+	builder.WriteString("## dragondrop tfstate migration\n\n")
+
+	if len(results) == 0 {
+		builder.WriteString("No workspaces were migrated.\n")
+		return builder.String()
+	}
+
+	builder.WriteString("| Workspace | Directory | Result | Operations | Refresh run |\n")
+	builder.WriteString("|-----------|-----------|--------|------------|-------------|\n")
+
+	for _, result := range results {
+		refreshRun := "-"
+		if result.RefreshRunURL != "" {
+			refreshRun = fmt.Sprintf("[%v](%v)", result.RefreshRunID, result.RefreshRunURL)
+		}
+
+		fmt.Fprintf(
+			&builder, "| %v | `%v` | %v | %v | %v |\n",
+			escapeTableCell(result.Workspace), escapeTableCell(result.Directory), resultStatus(result),
+			len(result.Operations), refreshRun,
+		)
+	}
+
+	for _, result := range results {
+		if len(result.Operations) == 0 && result.Error == "" {
+			continue
+		}
+
+		fmt.Fprintf(&builder, "\n### %v\n\n", result.Workspace)
+
+		if len(result.Operations) > 0 {
+			builder.WriteString("```\n")
+			for _, operation := range result.Operations {
+				fmt.Fprintf(&builder, "%v  # %v\n", operation.Action, operation.File)
+			}
+			builder.WriteString("```\n")
+		}
+
+		if result.Error != "" {
+			writeError(&builder, result)
+		}
+	}
+
+	return builder.String()
+}
+
+// resultStatus describes the outcome of a workspace's migration for the summary table.
+func resultStatus(result statemigration.WorkspaceResult) string {
+	if !result.Success {
+		return fmt.Sprintf(":x: %v failed", result.Mode)
+	}
+
+	if result.Mode == "apply" {
+		return ":white_check_mark: applied"
+	}
+
+	return ":white_check_mark: planned"
+}
+
+// escapeTableCell escapes characters that would break a Markdown table cell.
+func escapeTableCell(value string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(value)
+}
+
+// RenderPullRequestComment renders a Markdown pull request comment listing each workspace, the
+// tfmigrate operations planned or applied within it, and any errors, including jobErr if the job
+// failed outside of a workspace. Errors are redacted, as the comment is visible to anyone who can
+// read the pull request.
+func RenderPullRequestComment(results []statemigration.WorkspaceResult, jobErr error) string {
+	var builder strings.Builder
+
+	mode := "plan"
+	if len(results) > 0 {
+		mode = results[0].Mode
+	}
+	fmt.Fprintf(&builder, "### dragondrop tfstate migration %v\n\n", mode)
+
+	if len(results) == 0 && jobErr == nil {
+		builder.WriteString("No workspaces were migrated.\n")
+	}
+
+	for _, result := range results {
+		fmt.Fprintf(
+			&builder, "**%v** (`%v`): %v%v\n",
+			result.Workspace, result.Directory, resultStatus(result), operationCounts(result.Operations),
+		)
+
+		if len(result.Operations) > 0 {
+			builder.WriteString("\n```\n")
+			for _, operation := range result.Operations {
+				fmt.Fprintf(&builder, "%v\n", operation.Action)
+			}
+			builder.WriteString("```\n")
+		}
+
+		if result.Error != "" {
+			writeError(&builder, result)
+		}
+
+		builder.WriteString("\n")
+	}
+
+	if jobErr != nil && (len(results) == 0 || results[len(results)-1].Success) {
+		fmt.Fprintf(&builder, ":x: The job failed:\n\n```\n%v\n```\n", logging.Redact(jobErr.Error()))
+	}
+
+	return builder.String()
+}
+
+// operationCounts summarizes the number of operations of each command, such as ", 2 mv, 1 rm".
+func operationCounts(operations []statemigration.MigrationOperation) string {
+	if len(operations) == 0 {
+		return ", no operations"
+	}
+
+	var commands []string
+	counts := map[string]int{}
+	for _, operation := range operations {
+		if counts[operation.Command()] == 0 {
+			commands = append(commands, operation.Command())
+		}
+		counts[operation.Command()]++
+	}
+
+	var builder strings.Builder
+	for _, command := range commands {
+		fmt.Fprintf(&builder, ", %v %v", counts[command], command)
+	}
+
+	return builder.String()
+}
+
+// writeError writes a workspace's error, redacted and collapsed, along with the file that caused it if
+// known.
+func writeError(builder *strings.Builder, result statemigration.WorkspaceResult) {
+	summary := "Error"
+	if result.ErrorFile != "" && result.ErrorLine > 0 {
+		summary = fmt.Sprintf("Error in <code>%v:%v</code>", result.ErrorFile, result.ErrorLine)
+	} else if result.ErrorFile != "" {
+		summary = fmt.Sprintf("Error in <code>%v</code>", result.ErrorFile)
+	}
+
+	fmt.Fprintf(builder, "\n<details><summary>%v</summary>\n\n```\n%v\n```\n\n</details>\n", summary, logging.Redact(result.Error))
+}
@@ -0,0 +1,126 @@
+package github
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/logging"
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/statemigration"
+)
+
+var testResults = []statemigration.WorkspaceResult{
+	{
+		Workspace: "workspace_1",
+		Directory: "/directory_1/",
+		Mode:      "apply",
+		Success:   true,
+		Operations: []statemigration.MigrationOperation{
+			{File: "1_move.hcl", Action: "mv aws_s3_bucket.a aws_s3_bucket.b"},
+			{File: "1_move.hcl", Action: "rm aws_s3_bucket.c"},
+		},
+		RefreshRunID:  "run-456",
+		RefreshRunURL: "https://app.terraform.io/app/org/workspaces/workspace_1/runs/run-456",
+	},
+	{
+		Workspace:  "workspace_2",
+		Directory:  "/directory_2/",
+		Mode:       "apply",
+		Error:      "tfmigrate failed",
+		ErrorFile:  "/directory_2/dragondrop/tfmigrate/1_move.hcl",
+		ErrorLine:  3,
+		Operations: []statemigration.MigrationOperation{},
+	},
+}
+
+func TestResultOutputs(t *testing.T) {
+	output, err := ResultOutputs(testResults)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedOutput := map[string]string{
+		"migrated-workspaces": `["workspace_1"]`,
+		"failed-workspaces":   `["workspace_2"]`,
+		"state-operations":    "2",
+		"refresh-run-urls":    `["https://app.terraform.io/app/org/workspaces/workspace_1/runs/run-456"]`,
+	}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+}
+
+func TestRenderStepSummary(t *testing.T) {
+	output := RenderStepSummary(testResults)
+
+	expectedLines := []string{
+		"| workspace_1 | `/directory_1/` | :white_check_mark: applied | 2 | [run-456](https://app.terraform.io/app/org/workspaces/workspace_1/runs/run-456) |",
+		"| workspace_2 | `/directory_2/` | :x: apply failed | 0 | - |",
+		"mv aws_s3_bucket.a aws_s3_bucket.b  # 1_move.hcl",
+		"tfmigrate failed",
+		"<summary>Error in <code>/directory_2/dragondrop/tfmigrate/1_move.hcl:3</code></summary>",
+	}
+
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(output, expectedLine) {
+			t.Errorf("expected the summary to contain %q, got:\n%v", expectedLine, output)
+		}
+	}
+
+	if !strings.Contains(RenderStepSummary(nil), "No workspaces were migrated.") {
+		t.Errorf("expected an empty summary to say no workspaces were migrated")
+	}
+}
+
+func TestRenderPullRequestComment(t *testing.T) {
+	output := RenderPullRequestComment(testResults, errors.New("[sm.MigrateWorkspace] tfmigrate failed"))
+
+	expectedLines := []string{
+		"### dragondrop tfstate migration apply",
+		"**workspace_1** (`/directory_1/`): :white_check_mark: applied, 1 mv, 1 rm",
+		"rm aws_s3_bucket.c",
+		"**workspace_2** (`/directory_2/`): :x: apply failed, no operations",
+		"tfmigrate failed",
+	}
+
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(output, expectedLine) {
+			t.Errorf("expected the comment to contain %q, got:\n%v", expectedLine, output)
+		}
+	}
+
+	if strings.Contains(output, "The job failed") {
+		t.Errorf("expected the job error not to be repeated when a workspace failed, got:\n%v", output)
+	}
+
+	output = RenderPullRequestComment(nil, errors.New("unable to create variable files"))
+	if !strings.Contains(output, "unable to create variable files") {
+		t.Errorf("expected the comment to contain the job error, got:\n%v", output)
+	}
+}
+
+func TestRenderPullRequestCommentRedactsSecrets(t *testing.T) {
+	logging.AddSecrets("pr-comment-secret")
+
+	results := []statemigration.WorkspaceResult{
+		{
+			Workspace: "workspace_1",
+			Directory: "/directory_1/",
+			Mode:      "plan",
+			Error:     "terraform init failed: token pr-comment-secret is invalid",
+		},
+	}
+
+	outputs := []string{
+		RenderPullRequestComment(results, errors.New("token pr-comment-secret is invalid")),
+		RenderPullRequestComment(nil, errors.New("unable to authenticate with pr-comment-secret")),
+	}
+
+	for _, output := range outputs {
+		if strings.Contains(output, "pr-comment-secret") {
+			t.Errorf("expected the secret to be redacted, got:\n%v", output)
+		}
+	}
+}
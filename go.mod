@@ -1,9 +1,11 @@
 module github.com/dragondrop-cloud/github-action-tfstate-migration
 
-go 1.19
+go 1.21
 
 require (
 	github.com/Jeffail/gabs/v2 v2.7.0
+	github.com/ProtonMail/go-crypto v1.1.6
+	github.com/hashicorp/go-version v1.6.0
 	github.com/hashicorp/hcl/v2 v2.16.2
 	github.com/joho/godotenv v1.4.0
 	github.com/kelseyhightower/envconfig v1.4.0
@@ -13,7 +15,10 @@ require (
 require (
 	github.com/agext/levenshtein v1.2.3 // indirect
 	github.com/apparentlymart/go-textseg/v13 v13.0.0 // indirect
+	github.com/cloudflare/circl v1.3.7 // indirect
 	github.com/google/go-cmp v0.5.9 // indirect
 	github.com/mitchellh/go-wordwrap v1.0.1 // indirect
-	golang.org/x/text v0.8.0 // indirect
+	golang.org/x/crypto v0.17.0 // indirect
+	golang.org/x/sys v0.16.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
 )
@@ -0,0 +1,77 @@
+package installer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+const (
+	// defaultMirrorURL is the official HashiCorp releases server.
+	defaultMirrorURL = "https://releases.hashicorp.com"
+
+	// defaultTofuMirrorURL is where OpenTofu publishes its release files.
+	defaultTofuMirrorURL = "https://github.com/opentofu/opentofu/releases/download"
+
+	// defaultTofuAPIURL is the OpenTofu API listing all releases.
+	defaultTofuAPIURL = "https://get.opentofu.org/tofu/api.json"
+)
+
+// Config contains the variables needed to support the Installer interface.
+type Config struct {
+
+	// TerraformMirrorURL is the base URL of the releases server from which Terraform is downloaded.
+	// Mirrors must follow the layout of https://releases.hashicorp.com.
+	TerraformMirrorURL string `required:"false"`
+
+	// TofuMirrorURL is the base URL from which OpenTofu is downloaded. Mirrors must follow the
+	// layout of https://github.com/opentofu/opentofu/releases/download.
+	TofuMirrorURL string `required:"false"`
+
+	// TofuAPIURL is the URL of the OpenTofu API response listing all releases.
+	TofuAPIURL string `required:"false"`
+
+	// TerraformArchiveDirectory is an optional local directory containing release archives,
+	// SHA256SUMS, and SHA256SUMS signature files. When set, nothing is downloaded. It is used
+	// for both Terraform and OpenTofu releases.
+	TerraformArchiveDirectory string `required:"false"`
+
+	// TerraformCacheDirectory is the directory under which installed Terraform and OpenTofu
+	// binaries are cached. It defaults to a directory within the user's cache directory.
+	TerraformCacheDirectory string `required:"false"`
+}
+
+// NewConfig instantiates a new instance of Config
+func NewConfig() (*Config, error) {
+	var c Config
+	err := envconfig.Process("", &c)
+
+	if err != nil {
+		return nil, fmt.Errorf("[envconfig.Process] Error loading config: %w", err)
+	}
+
+	// The Action passes unset inputs as empty strings, which envconfig does not replace with defaults.
+	if c.TerraformMirrorURL == "" {
+		c.TerraformMirrorURL = defaultMirrorURL
+	}
+
+	if c.TofuMirrorURL == "" {
+		c.TofuMirrorURL = defaultTofuMirrorURL
+	}
+
+	if c.TofuAPIURL == "" {
+		c.TofuAPIURL = defaultTofuAPIURL
+	}
+
+	if c.TerraformCacheDirectory == "" {
+		cacheDirectory, err := os.UserCacheDir()
+		if err != nil {
+			cacheDirectory = os.TempDir()
+		}
+		c.TerraformCacheDirectory = filepath.Join(cacheDirectory, "dragondrop-tfstate-migration")
+	}
+
+	return &c, nil
+}
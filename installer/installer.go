@@ -0,0 +1,59 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/go-version"
+)
+
+// Installer is an interface for installing verified Terraform or OpenTofu releases.
+type Installer interface {
+
+	// Install ensures that the specified version is installed and returns the path to its binary.
+	Install(ctx context.Context, version string) (string, error)
+
+	// ListVersions lists all versions available to install, sorted from oldest to newest.
+	ListVersions(ctx context.Context) ([]*version.Version, error)
+}
+
+// releaseInstaller implements the Installer interface for the releases of a single product.
+type releaseInstaller struct {
+
+	// config contains the configuration needed for releaseInstaller methods to run.
+	config *Config
+
+	// product describes where the releases being installed are published.
+	product product
+
+	// httpClient contains an http.Client struct
+	httpClient http.Client
+
+	// publicKey is the armored PGP public key that release checksums must be signed with.
+	publicKey string
+
+	// versions caches the versions available to install.
+	versions []*version.Version
+}
+
+// NewInstaller instantiates a new implementation of the Installer interface for the named
+// binary, either Terraform or OpenTofu.
+func NewInstaller(binary string) (Installer, error) {
+	conf, err := NewConfig()
+	if err != nil {
+		return nil, fmt.Errorf("[NewConfig] %w", err)
+	}
+
+	releaseProduct, ok := products[binary]
+	if !ok {
+		return nil, fmt.Errorf("no installer exists for %v, expected %v or %v", binary, Terraform, OpenTofu)
+	}
+
+	return &releaseInstaller{
+		config:     conf,
+		product:    releaseProduct,
+		httpClient: http.Client{},
+		publicKey:  releaseProduct.publicKey,
+	}, nil
+}
@@ -0,0 +1,123 @@
+package installer
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/hashicorp/go-version"
+)
+
+const (
+	// Terraform is the name of the Terraform binary and its releases.
+	Terraform = "terraform"
+
+	// OpenTofu is the name of the OpenTofu binary and its releases.
+	OpenTofu = "tofu"
+)
+
+// product describes where and how the releases of an installable binary are published.
+type product struct {
+
+	// name is the name of the binary and the prefix of its release file names.
+	name string
+
+	// publicKey is the armored PGP public key that release checksums are signed with.
+	publicKey string
+
+	// signatureSuffix is appended to the SHA256SUMS file name to get its signature's file name.
+	signatureSuffix string
+
+	// fileURL builds the download URL of a release file.
+	fileURL func(config *Config, releaseVersion string, fileName string) string
+
+	// indexURL builds the URL of the index listing all releases.
+	indexURL func(config *Config) string
+
+	// extractVersions pulls out the versions listed within the index of all releases.
+	extractVersions func(indexBytes []byte) ([]*version.Version, error)
+}
+
+// products maps the name of each supported binary to where its releases are published.
+var products = map[string]product{
+	Terraform: {
+		name:            Terraform,
+		publicKey:       hashicorpPublicKey,
+		signatureSuffix: ".sig",
+		fileURL: func(config *Config, releaseVersion string, fileName string) string {
+			return fmt.Sprintf("%v/terraform/%v/%v", strings.TrimSuffix(config.TerraformMirrorURL, "/"), releaseVersion, fileName)
+		},
+		indexURL: func(config *Config) string {
+			return fmt.Sprintf("%v/terraform/index.json", strings.TrimSuffix(config.TerraformMirrorURL, "/"))
+		},
+		extractVersions: extractReleaseVersions,
+	},
+	OpenTofu: {
+		name:            OpenTofu,
+		publicKey:       opentofuPublicKey,
+		signatureSuffix: ".gpgsig",
+		fileURL: func(config *Config, releaseVersion string, fileName string) string {
+			return fmt.Sprintf("%v/v%v/%v", strings.TrimSuffix(config.TofuMirrorURL, "/"), releaseVersion, fileName)
+		},
+		indexURL: func(config *Config) string {
+			return config.TofuAPIURL
+		},
+		extractVersions: extractTofuVersions,
+	},
+}
+
+// binaryName is the name of the product's binary for the current operating system.
+func (p product) binaryName() string {
+	if runtime.GOOS == "windows" {
+		return p.name + ".exe"
+	}
+	return p.name
+}
+
+// extractReleaseVersions pulls out the versions listed within a HashiCorp releases index.
+func extractReleaseVersions(jsonBytes []byte) ([]*version.Version, error) {
+	jsonParsed, err := gabs.ParseJSON(jsonBytes)
+	if err != nil {
+		return nil, fmt.Errorf("[gabs.ParseJSON] %w", err)
+	}
+
+	var versions []*version.Version
+
+	for versionString := range jsonParsed.Search("versions").ChildrenMap() {
+		release, err := version.NewVersion(versionString)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, release)
+	}
+
+	sort.Sort(version.Collection(versions))
+
+	return versions, nil
+}
+
+// extractTofuVersions pulls out the versions listed within the OpenTofu download API response.
+func extractTofuVersions(jsonBytes []byte) ([]*version.Version, error) {
+	jsonParsed, err := gabs.ParseJSON(jsonBytes)
+	if err != nil {
+		return nil, fmt.Errorf("[gabs.ParseJSON] %w", err)
+	}
+
+	var versions []*version.Version
+
+	for _, versionContainer := range jsonParsed.Search("versions").Children() {
+		versionString, _ := versionContainer.Search("id").Data().(string)
+
+		release, err := version.NewVersion(versionString)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, release)
+	}
+
+	sort.Sort(version.Collection(versions))
+
+	return versions, nil
+}
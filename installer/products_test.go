@@ -0,0 +1,117 @@
+package installer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+)
+
+func TestExtractReleaseVersions(t *testing.T) {
+	jsonBytes := []byte(`{
+		"name": "terraform",
+		"versions": {
+			"1.5.7": {"version": "1.5.7"},
+			"1.3.0": {"version": "1.3.0"},
+			"1.6.0-beta1": {"version": "1.6.0-beta1"}
+		}
+	}`)
+
+	output, err := extractReleaseVersions(jsonBytes)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	expectedOutput := []string{"1.3.0", "1.5.7", "1.6.0-beta1"}
+
+	if len(output) != len(expectedOutput) {
+		t.Fatalf("got %v, expected %v", output, expectedOutput)
+	}
+
+	for i, release := range output {
+		if release.String() != expectedOutput[i] {
+			t.Errorf("got %v, expected %v", release, expectedOutput[i])
+		}
+	}
+}
+
+func TestExtractTofuVersions(t *testing.T) {
+	jsonBytes := []byte(`{
+		"versions": [
+			{"id": "1.8.0", "files": ["tofu_1.8.0_linux_amd64.zip"]},
+			{"id": "1.6.2", "files": ["tofu_1.6.2_linux_amd64.zip"]},
+			{"id": "1.9.0-alpha1", "files": []}
+		]
+	}`)
+
+	output, err := extractTofuVersions(jsonBytes)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	expectedOutput := []string{"1.6.2", "1.8.0", "1.9.0-alpha1"}
+
+	if len(output) != len(expectedOutput) {
+		t.Fatalf("got %v, expected %v", output, expectedOutput)
+	}
+
+	for i, release := range output {
+		if release.String() != expectedOutput[i] {
+			t.Errorf("got %v, expected %v", release, expectedOutput[i])
+		}
+	}
+}
+
+func TestInstallTofuFromMirror(t *testing.T) {
+	publicKey, releaseFiles := createTestRelease(t, products[OpenTofu], "1.8.0")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(
+		"/v1.8.0/",
+		func(w http.ResponseWriter, r *http.Request) {
+			content, ok := releaseFiles[path.Base(r.URL.Path)]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(content)
+		})
+	mux.HandleFunc(
+		"/api.json",
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"versions": [{"id": "1.8.0"}]}`))
+		})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ri := releaseInstaller{
+		product: products[OpenTofu],
+		config: &Config{
+			TofuMirrorURL:           server.URL,
+			TofuAPIURL:              server.URL + "/api.json",
+			TerraformCacheDirectory: t.TempDir(),
+		},
+		httpClient: http.Client{},
+		publicKey:  publicKey,
+	}
+
+	versions, err := ri.ListVersions(context.Background())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if len(versions) != 1 || versions[0].String() != "1.8.0" {
+		t.Errorf("got %v, expected [1.8.0]", versions)
+	}
+
+	binaryPath, err := ri.Install(context.Background(), "1.8.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if path.Base(binaryPath) != products[OpenTofu].binaryName() {
+		t.Errorf("got %v, expected a %v binary", binaryPath, products[OpenTofu].binaryName())
+	}
+}
@@ -0,0 +1,262 @@
+package installer
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/hashicorp/go-version"
+)
+
+// maxBinarySize bounds the size of a binary extracted from a release archive.
+const maxBinarySize = 1 << 30
+
+// Install ensures that the specified version is installed and returns the path to its binary.
+// Binaries are cached under a directory per version, and are only installed after the release's
+// SHA256SUMS signature and the archive's checksum have been verified.
+func (ri *releaseInstaller) Install(ctx context.Context, releaseVersion string) (string, error) {
+	binaryPath := filepath.Join(ri.config.TerraformCacheDirectory, ri.product.name, releaseVersion, ri.product.binaryName())
+
+	if _, err := os.Stat(binaryPath); err == nil {
+		slog.Info("Using cached release", "product", ri.product.name, "version", releaseVersion, "path", binaryPath)
+		return binaryPath, nil
+	}
+
+	archiveName := fmt.Sprintf("%v_%v_%v_%v.zip", ri.product.name, releaseVersion, runtime.GOOS, runtime.GOARCH)
+	sumsName := fmt.Sprintf("%v_%v_SHA256SUMS", ri.product.name, releaseVersion)
+
+	sums, err := ri.fetchReleaseFile(ctx, releaseVersion, sumsName)
+	if err != nil {
+		return "", fmt.Errorf("[ri.fetchReleaseFile] %w", err)
+	}
+
+	signature, err := ri.fetchReleaseFile(ctx, releaseVersion, sumsName+ri.product.signatureSuffix)
+	if err != nil {
+		return "", fmt.Errorf("[ri.fetchReleaseFile] %w", err)
+	}
+
+	err = verifySignature(ri.publicKey, sums, signature)
+	if err != nil {
+		return "", fmt.Errorf("[verifySignature] %w", err)
+	}
+
+	expectedChecksum, err := findChecksum(sums, archiveName)
+	if err != nil {
+		return "", fmt.Errorf("[findChecksum] %w", err)
+	}
+
+	archive, err := ri.fetchReleaseFile(ctx, releaseVersion, archiveName)
+	if err != nil {
+		return "", fmt.Errorf("[ri.fetchReleaseFile] %w", err)
+	}
+
+	err = verifyChecksum(archive, expectedChecksum)
+	if err != nil {
+		return "", fmt.Errorf("[verifyChecksum] %w", err)
+	}
+
+	err = extractBinary(archive, ri.product.binaryName(), binaryPath)
+	if err != nil {
+		return "", fmt.Errorf("[extractBinary] %w", err)
+	}
+
+	slog.Info("Installed release", "product", ri.product.name, "version", releaseVersion, "path", binaryPath)
+	return binaryPath, nil
+}
+
+// ListVersions lists all versions available to install, sorted from oldest to newest.
+func (ri *releaseInstaller) ListVersions(ctx context.Context) ([]*version.Version, error) {
+	if ri.versions != nil {
+		return ri.versions, nil
+	}
+
+	var versions []*version.Version
+	var err error
+
+	if ri.config.TerraformArchiveDirectory != "" {
+		versions, err = listArchiveDirectoryVersions(ri.config.TerraformArchiveDirectory, ri.product.name)
+		if err != nil {
+			return nil, fmt.Errorf("[listArchiveDirectoryVersions] %w", err)
+		}
+	} else {
+		indexBytes, err := ri.download(ctx, ri.product.indexURL(ri.config))
+		if err != nil {
+			return nil, fmt.Errorf("[ri.download] %w", err)
+		}
+
+		versions, err = ri.product.extractVersions(indexBytes)
+		if err != nil {
+			return nil, fmt.Errorf("[ri.product.extractVersions] %w", err)
+		}
+	}
+
+	ri.versions = versions
+	return versions, nil
+}
+
+// fetchReleaseFile reads a release file from the local archive directory if configured,
+// and otherwise downloads it from the mirror.
+func (ri *releaseInstaller) fetchReleaseFile(ctx context.Context, releaseVersion string, fileName string) ([]byte, error) {
+	if ri.config.TerraformArchiveDirectory != "" {
+		content, err := os.ReadFile(filepath.Join(ri.config.TerraformArchiveDirectory, fileName))
+		if err != nil {
+			return nil, fmt.Errorf("[os.ReadFile] %w", err)
+		}
+		return content, nil
+	}
+
+	return ri.download(ctx, ri.product.fileURL(ri.config, releaseVersion, fileName))
+}
+
+// download performs a GET request and returns the response body.
+func (ri *releaseInstaller) download(ctx context.Context, requestPath string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", requestPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("[http.NewRequestWithContext] %w", err)
+	}
+
+	response, err := ri.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("[ri.httpClient.Do] %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %v was unsuccessful, with the server returning: %v", requestPath, response.StatusCode)
+	}
+
+	outputBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("[io.ReadAll] %w", err)
+	}
+
+	return outputBytes, nil
+}
+
+// verifySignature checks that signature is a valid detached signature of sums made by the
+// holder of the armored publicKey.
+func verifySignature(publicKey string, sums []byte, signature []byte) error {
+	keyRing, err := openpgp.ReadArmoredKeyRing(strings.NewReader(publicKey))
+	if err != nil {
+		return fmt.Errorf("[openpgp.ReadArmoredKeyRing] %w", err)
+	}
+
+	_, err = openpgp.CheckDetachedSignature(keyRing, bytes.NewReader(sums), bytes.NewReader(signature), nil)
+	if err != nil {
+		return fmt.Errorf("[openpgp.CheckDetachedSignature] SHA256SUMS signature is invalid: %w", err)
+	}
+
+	return nil
+}
+
+// findChecksum finds the hex-encoded checksum of fileName within a SHA256SUMS file.
+func findChecksum(sums []byte, fileName string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == fileName {
+			return fields[0], nil
+		}
+	}
+
+	return "", fmt.Errorf("no checksum found for %v", fileName)
+}
+
+// verifyChecksum checks that the SHA256 checksum of content matches expectedChecksum.
+func verifyChecksum(content []byte, expectedChecksum string) error {
+	checksum := sha256.Sum256(content)
+
+	if hex.EncodeToString(checksum[:]) != expectedChecksum {
+		return fmt.Errorf("checksum mismatch: got %x, expected %v", checksum, expectedChecksum)
+	}
+
+	return nil
+}
+
+// extractBinary writes the file named binaryName within a zip archive to binaryPath.
+// The binary is written to a temporary file first so that a partial install is never cached.
+func extractBinary(archive []byte, binaryName string, binaryPath string) error {
+	zipReader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return fmt.Errorf("[zip.NewReader] %w", err)
+	}
+
+	for _, file := range zipReader.File {
+		if file.Name != binaryName {
+			continue
+		}
+
+		err = os.MkdirAll(filepath.Dir(binaryPath), 0755)
+		if err != nil {
+			return fmt.Errorf("[os.MkdirAll] %w", err)
+		}
+
+		fileReader, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("[file.Open] %w", err)
+		}
+		defer fileReader.Close()
+
+		tempFile, err := os.CreateTemp(filepath.Dir(binaryPath), binaryName+".tmp")
+		if err != nil {
+			return fmt.Errorf("[os.CreateTemp] %w", err)
+		}
+		defer os.Remove(tempFile.Name())
+
+		_, err = io.Copy(tempFile, io.LimitReader(fileReader, maxBinarySize))
+		if closeErr := tempFile.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("[io.Copy] %w", err)
+		}
+
+		err = os.Chmod(tempFile.Name(), 0755) // #nosec G302 -- the binary must be executable
+		if err != nil {
+			return fmt.Errorf("[os.Chmod] %w", err)
+		}
+
+		return os.Rename(tempFile.Name(), binaryPath)
+	}
+
+	return fmt.Errorf("no %v binary found within the release archive", binaryName)
+}
+
+// listArchiveDirectoryVersions lists the versions that have a SHA256SUMS file within
+// a local archive directory.
+func listArchiveDirectoryVersions(directory string, productName string) ([]*version.Version, error) {
+	fileNames, err := filepath.Glob(filepath.Join(directory, productName+"_*_SHA256SUMS"))
+	if err != nil {
+		return nil, fmt.Errorf("[filepath.Glob] %w", err)
+	}
+
+	var versions []*version.Version
+
+	for _, fileName := range fileNames {
+		versionString := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(fileName), productName+"_"), "_SHA256SUMS")
+
+		release, err := version.NewVersion(versionString)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, release)
+	}
+
+	sort.Sort(version.Collection(versions))
+
+	return versions, nil
+}
@@ -0,0 +1,218 @@
+package installer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// createTestRelease builds a signed release for the version, returning the armored public key
+// and a map of release file names to their contents.
+func createTestRelease(t *testing.T, releaseProduct product, releaseVersion string) (string, map[string][]byte) {
+	entity, err := openpgp.NewEntity("test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatalf("[openpgp.NewEntity] %v", err)
+	}
+
+	var publicKey bytes.Buffer
+	armorWriter, err := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("[armor.Encode] %v", err)
+	}
+	if err = entity.Serialize(armorWriter); err != nil {
+		t.Fatalf("[entity.Serialize] %v", err)
+	}
+	armorWriter.Close()
+
+	var archive bytes.Buffer
+	zipWriter := zip.NewWriter(&archive)
+	fileWriter, err := zipWriter.Create(releaseProduct.binaryName())
+	if err != nil {
+		t.Fatalf("[zipWriter.Create] %v", err)
+	}
+	_, _ = fileWriter.Write([]byte("#!/bin/sh\necho " + releaseVersion + "\n"))
+	zipWriter.Close()
+
+	archiveName := fmt.Sprintf("%v_%v_%v_%v.zip", releaseProduct.name, releaseVersion, runtime.GOOS, runtime.GOARCH)
+	sums := []byte(fmt.Sprintf("%x  %v\n", sha256.Sum256(archive.Bytes()), archiveName))
+
+	var signature bytes.Buffer
+	if err = openpgp.DetachSign(&signature, entity, bytes.NewReader(sums), nil); err != nil {
+		t.Fatalf("[openpgp.DetachSign] %v", err)
+	}
+
+	sumsName := fmt.Sprintf("%v_%v_SHA256SUMS", releaseProduct.name, releaseVersion)
+
+	return publicKey.String(), map[string][]byte{
+		archiveName: archive.Bytes(),
+		sumsName:    sums,
+		sumsName + releaseProduct.signatureSuffix: signature.Bytes(),
+	}
+}
+
+func TestInstallFromMirror(t *testing.T) {
+	publicKey, releaseFiles := createTestRelease(t, products[Terraform], "1.5.7")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(
+		"/terraform/1.5.7/",
+		func(w http.ResponseWriter, r *http.Request) {
+			content, ok := releaseFiles[filepath.Base(r.URL.Path)]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(content)
+		})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ri := releaseInstaller{
+		product: products[Terraform],
+		config: &Config{
+			TerraformMirrorURL:      server.URL,
+			TerraformCacheDirectory: t.TempDir(),
+		},
+		httpClient: http.Client{},
+		publicKey:  publicKey,
+	}
+
+	binaryPath, err := ri.Install(context.Background(), "1.5.7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedPath := filepath.Join(ri.config.TerraformCacheDirectory, "terraform", "1.5.7", products[Terraform].binaryName())
+	if binaryPath != expectedPath {
+		t.Errorf("got %v, expected %v", binaryPath, expectedPath)
+	}
+
+	// The second install must come from the cache, so the server is no longer needed.
+	server.Close()
+
+	binaryPath, err = ri.Install(context.Background(), "1.5.7")
+	if err != nil {
+		t.Errorf("unexpected error installing from the cache: %v", err)
+	}
+
+	if binaryPath != expectedPath {
+		t.Errorf("got %v, expected %v", binaryPath, expectedPath)
+	}
+}
+
+func TestInstallFromArchiveDirectory(t *testing.T) {
+	publicKey, releaseFiles := createTestRelease(t, products[Terraform], "1.4.6")
+
+	archiveDirectory := t.TempDir()
+	for fileName, content := range releaseFiles {
+		err := os.WriteFile(filepath.Join(archiveDirectory, fileName), content, 0600)
+		if err != nil {
+			t.Fatalf("[os.WriteFile] %v", err)
+		}
+	}
+
+	ri := releaseInstaller{
+		product: products[Terraform],
+		config: &Config{
+			TerraformArchiveDirectory: archiveDirectory,
+			TerraformCacheDirectory:   t.TempDir(),
+		},
+		httpClient: http.Client{},
+		publicKey:  publicKey,
+	}
+
+	versions, err := ri.ListVersions(context.Background())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if len(versions) != 1 || versions[0].String() != "1.4.6" {
+		t.Errorf("got %v, expected [1.4.6]", versions)
+	}
+
+	binaryPath, err := ri.Install(context.Background(), "1.4.6")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(binaryPath)
+	if err != nil {
+		t.Fatalf("[os.Stat] %v", err)
+	}
+
+	if info.Mode().Perm()&0100 == 0 {
+		t.Errorf("expected the installed binary to be executable, got mode %v", info.Mode())
+	}
+}
+
+func TestInstallRejectsInvalidSignature(t *testing.T) {
+	_, releaseFiles := createTestRelease(t, products[Terraform], "1.4.6")
+	otherPublicKey, _ := createTestRelease(t, products[Terraform], "1.4.6")
+
+	archiveDirectory := t.TempDir()
+	for fileName, content := range releaseFiles {
+		err := os.WriteFile(filepath.Join(archiveDirectory, fileName), content, 0600)
+		if err != nil {
+			t.Fatalf("[os.WriteFile] %v", err)
+		}
+	}
+
+	ri := releaseInstaller{
+		product: products[Terraform],
+		config: &Config{
+			TerraformArchiveDirectory: archiveDirectory,
+			TerraformCacheDirectory:   t.TempDir(),
+		},
+		httpClient: http.Client{},
+		publicKey:  otherPublicKey,
+	}
+
+	_, err := ri.Install(context.Background(), "1.4.6")
+	if err == nil {
+		t.Errorf("expected an error for a signature made by an unknown key")
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	content := []byte("example content")
+
+	err := verifyChecksum(content, fmt.Sprintf("%x", sha256.Sum256(content)))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = verifyChecksum([]byte("tampered content"), fmt.Sprintf("%x", sha256.Sum256(content)))
+	if err == nil {
+		t.Errorf("expected an error for mismatched checksums")
+	}
+}
+
+func TestFindChecksum(t *testing.T) {
+	sums := []byte("abc123  terraform_1.5.7_linux_amd64.zip\ndef456  terraform_1.5.7_darwin_arm64.zip\n")
+
+	output, err := findChecksum(sums, "terraform_1.5.7_darwin_arm64.zip")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if output != "def456" {
+		t.Errorf("got %v, expected 'def456'", output)
+	}
+
+	_, err = findChecksum(sums, "terraform_1.5.7_windows_amd64.zip")
+	if err == nil {
+		t.Errorf("expected an error for a missing checksum")
+	}
+}
@@ -0,0 +1,13 @@
+package rootdir
+
+import "os"
+
+// Default returns GITHUB_WORKSPACE when running within GitHub Actions, and otherwise the current
+// working directory, as the directory that workspace directories are relative to.
+func Default() (string, error) {
+	if githubWorkspace := os.Getenv("GITHUB_WORKSPACE"); githubWorkspace != "" {
+		return githubWorkspace, nil
+	}
+
+	return os.Getwd()
+}
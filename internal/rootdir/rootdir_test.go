@@ -0,0 +1,34 @@
+package rootdir
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	t.Setenv("GITHUB_WORKSPACE", "/github/workspace")
+
+	output, err := Default()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if output != "/github/workspace" {
+		t.Errorf("got %v, expected %v", output, "/github/workspace")
+	}
+
+	t.Setenv("GITHUB_WORKSPACE", "")
+	directory, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output, err = Default()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if output != directory {
+		t.Errorf("got %v, expected %v", output, directory)
+	}
+}
@@ -0,0 +1,136 @@
+package tfcapi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+)
+
+// APIError describes an unsuccessful response from the Terraform Cloud API. It is embedded by the
+// APIError of each package that calls the API, which supplies the hints for its own requests and
+// unwraps to its own sentinel errors.
+type APIError struct {
+
+	// Request is the name of the request, such as "getWorkspace".
+	Request string
+
+	// Method is the HTTP method of the request.
+	Method string
+
+	// Path is the path of the request, including the organization, workspace, or variable set it was for.
+	Path string
+
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Body is the body of the response, which describes the error.
+	Body string
+
+	// Errors are the errors within the JSON:API errors array of the response body.
+	Errors []ErrorDetail
+}
+
+// ErrorDetail is a single error from the JSON:API errors array of a Terraform Cloud response.
+type ErrorDetail struct {
+
+	// Title is a short summary of the error.
+	Title string
+
+	// Detail explains the error.
+	Detail string
+
+	// Source is the JSON pointer or query parameter that caused the error, if any.
+	Source string
+}
+
+// String renders the detail as "title: detail (source)", omitting the parts that are empty.
+func (d ErrorDetail) String() string {
+	text := d.Title
+	switch {
+	case text == "":
+		text = d.Detail
+	case d.Detail != "" && d.Detail != d.Title:
+		text += ": " + d.Detail
+	}
+
+	if d.Source != "" {
+		text += fmt.Sprintf(" (%v)", d.Source)
+	}
+
+	return text
+}
+
+// Message renders the error, ending with the hint for its request and status code, if any.
+func (e *APIError) Message(hints map[string]map[int]string) string {
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "[%v] ", e.Request)
+	if e.Method != "" {
+		fmt.Fprintf(&builder, "%v %v ", e.Method, e.Path)
+	}
+	fmt.Fprintf(&builder, "was unsuccessful, with the server returning: %v", e.StatusCode)
+
+	var details []string
+	for _, detail := range e.Errors {
+		details = append(details, detail.String())
+	}
+	if len(details) > 0 {
+		fmt.Fprintf(&builder, ": %v", strings.Join(details, "; "))
+	}
+
+	if hint := e.Hint(hints); hint != "" {
+		fmt.Fprintf(&builder, " (hint: %v)", hint)
+	}
+
+	return builder.String()
+}
+
+// Hint returns the likely cause of the error from hints, which map a request name and status code
+// to a hint, falling back to hints that apply to every request. An empty string is returned if the
+// cause is not known.
+func (e *APIError) Hint(hints map[string]map[int]string) string {
+	if hint, ok := hints[e.Request][e.StatusCode]; ok {
+		return hint
+	}
+
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return "check that the Terraform Cloud token is valid and has not expired"
+	case http.StatusTooManyRequests:
+		return "Terraform Cloud is rate limiting requests, retry later"
+	default:
+		return ""
+	}
+}
+
+// ExtractErrorDetails is a helper function that uses the gabs library to pull out the JSON:API
+// errors array from a Terraform Cloud API response, returning nil if there is none.
+func ExtractErrorDetails(jsonBytes []byte) []ErrorDetail {
+	jsonParsed, err := gabs.ParseJSON(jsonBytes)
+	if err != nil {
+		return nil
+	}
+
+	var details []ErrorDetail
+
+	for _, apiError := range jsonParsed.Path("errors").Children() {
+		// Some endpoints return the errors array as plain strings.
+		if message, ok := apiError.Data().(string); ok {
+			details = append(details, ErrorDetail{Title: message})
+			continue
+		}
+
+		title, _ := apiError.Path("title").Data().(string)
+		detail, _ := apiError.Path("detail").Data().(string)
+		source, _ := apiError.Path("source.pointer").Data().(string)
+		if parameter, ok := apiError.Path("source.parameter").Data().(string); ok {
+			source = parameter
+		}
+
+		details = append(details, ErrorDetail{Title: title, Detail: detail, Source: source})
+	}
+
+	return details
+}
@@ -0,0 +1,63 @@
+package tfcapi
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestExtractErrorDetails(t *testing.T) {
+	jsonBytes := []byte(`{"errors": [
+		{"status": "404", "title": "not found"},
+		{"status": "422", "title": "invalid attribute", "detail": "Workspace must be set", "source": {"pointer": "/data/relationships/workspace"}},
+		{"status": "400", "title": "bad request", "detail": "bad request", "source": {"parameter": "filter[status]"}},
+		"unauthorized"
+	]}`)
+
+	output := ExtractErrorDetails(jsonBytes)
+	expectedOutput := []ErrorDetail{
+		{Title: "not found"},
+		{Title: "invalid attribute", Detail: "Workspace must be set", Source: "/data/relationships/workspace"},
+		{Title: "bad request", Detail: "bad request", Source: "filter[status]"},
+		{Title: "unauthorized"},
+	}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+
+	if ExtractErrorDetails([]byte("<html>Bad Gateway</html>")) != nil {
+		t.Errorf("expected no details from a body that is not JSON")
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	hints := map[string]map[int]string{
+		"getWorkspace": {http.StatusNotFound: "check that the workspace exists"},
+	}
+
+	err := &APIError{
+		Request:    "getWorkspace",
+		Method:     "GET",
+		Path:       "/api/v2/organizations/dragondrop-cloud/workspaces/missing_workspace",
+		StatusCode: http.StatusNotFound,
+		Errors:     []ErrorDetail{{Title: "not found"}, {Title: "invalid attribute", Detail: "Name is taken"}},
+	}
+
+	expectedMessage := "[getWorkspace] GET /api/v2/organizations/dragondrop-cloud/workspaces/missing_workspace was " +
+		"unsuccessful, with the server returning: 404: not found; invalid attribute: Name is taken (hint: check " +
+		"that the workspace exists)"
+	if err.Message(hints) != expectedMessage {
+		t.Errorf("got %v, expected %v", err.Message(hints), expectedMessage)
+	}
+
+	err = &APIError{Request: "getWorkspace", StatusCode: http.StatusTooManyRequests}
+	if err.Hint(hints) != "Terraform Cloud is rate limiting requests, retry later" {
+		t.Errorf("expected the rate limiting hint for a 429, got %v", err.Hint(hints))
+	}
+
+	err = &APIError{Request: "getWorkspace", StatusCode: http.StatusInternalServerError}
+	if err.Hint(hints) != "" {
+		t.Errorf("expected no hint for a 500, got %v", err.Hint(hints))
+	}
+}
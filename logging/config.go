@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Log formats supported by Setup.
+const (
+	// FormatText writes logs as key=value pairs.
+	FormatText = "text"
+
+	// FormatJSON writes each log as a JSON object.
+	FormatJSON = "json"
+)
+
+// Config contains the variables needed to configure logging.
+type Config struct {
+
+	// LogLevel is the minimum level logged, one of "debug", "info", "warn", or "error".
+	LogLevel string `required:"false"`
+
+	// LogFormat is the format logs are written in, either "text" or "json".
+	LogFormat string `required:"false"`
+
+	// TerraformCloudToken is a Terraform Cloud Token, masked in all logs.
+	TerraformCloudToken string `required:"false"`
+
+	// GithubToken is the token used to call the GitHub API, masked in all logs.
+	GithubToken string `required:"false"`
+
+	// VaultToken is the token used to read sensitive variables from Vault, masked in all logs.
+	VaultToken string `required:"false"`
+
+	// TerraformWorkspaceSensitiveVars is the JSON mapping between a Terraform Cloud workspace and its
+	// sensitive variables, whose values are masked in all logs.
+	TerraformWorkspaceSensitiveVars string `required:"false"`
+
+	// TerraformVarSetSensitiveVars is the JSON mapping between a Terraform Cloud variable set and its
+	// sensitive variables, whose values are masked in all logs.
+	TerraformVarSetSensitiveVars string `required:"false"`
+}
+
+// NewConfig instantiates a new instance of Config
+func NewConfig() (*Config, error) {
+	var c Config
+	err := envconfig.Process("", &c)
+
+	if err != nil {
+		return nil, fmt.Errorf("[envconfig.Process] Error loading config: %w", err)
+	}
+
+	// Action inputs left blank are passed as empty environment variables.
+	if c.LogLevel == "" {
+		c.LogLevel = "info"
+	}
+
+	if c.LogFormat == "" {
+		c.LogFormat = FormatText
+	}
+
+	return &c, nil
+}
+
+// level parses LogLevel.
+func (c *Config) level() (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(c.LogLevel))
+	if err != nil {
+		return level, fmt.Errorf("log level must be one of debug, info, warn, or error, got %v", c.LogLevel)
+	}
+
+	return level, nil
+}
+
+// secrets returns every value that must be masked in logs.
+func (c *Config) secrets() ([]string, error) {
+	secrets := []string{c.TerraformCloudToken, c.GithubToken, c.VaultToken}
+
+	for _, sensitiveVars := range []string{c.TerraformWorkspaceSensitiveVars, c.TerraformVarSetSensitiveVars} {
+		values, err := extractSensitiveValues(sensitiveVars)
+		if err != nil {
+			return nil, fmt.Errorf("[extractSensitiveValues] %w", err)
+		}
+		secrets = append(secrets, values...)
+	}
+
+	return secrets, nil
+}
+
+// extractSensitiveValues returns the value of every variable within a JSON mapping between a group
+// name and its sensitive variables, as accepted by TerraformWorkspaceSensitiveVars.
+func extractSensitiveValues(jsonString string) ([]string, error) {
+	if jsonString == "" {
+		return nil, nil
+	}
+
+	jsonParsed, err := gabs.ParseJSON([]byte(jsonString))
+	if err != nil {
+		return nil, fmt.Errorf("[gabs.ParseJSON] Error parsing JSON: %w", err)
+	}
+
+	var values []string
+
+	for _, variables := range jsonParsed.ChildrenMap() {
+		for _, variableData := range variables.ChildrenMap() {
+			value, ok := variableData.Search("value").Data().(string)
+			if ok {
+				values = append(values, value)
+			}
+		}
+	}
+
+	return values, nil
+}
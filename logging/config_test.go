@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"log/slog"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestExtractSensitiveValues(t *testing.T) {
+	jsonString := `{
+		"workspace_1": {
+			"db_password": {"value": "hunter22", "category": "terraform"},
+			"AWS_SECRET_ACCESS_KEY": {"value": "abc123secret", "category": "env"}
+		},
+		"workspace_2": {
+			"api_key": {"value": "key-456", "category": "terraform"}
+		}
+	}`
+
+	output, err := extractSensitiveValues(jsonString)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	sort.Strings(output)
+
+	expectedOutput := []string{"abc123secret", "hunter22", "key-456"}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+
+	output, err = extractSensitiveValues("")
+	if err != nil || output != nil {
+		t.Errorf("got %v and error %v, expected no values", output, err)
+	}
+
+	_, err = extractSensitiveValues("{not json")
+	if err == nil {
+		t.Errorf("expected an error for invalid JSON")
+	}
+}
+
+func TestConfigLevel(t *testing.T) {
+	for logLevel, expectedLevel := range map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"info":  slog.LevelInfo,
+		"WARN":  slog.LevelWarn,
+		"error": slog.LevelError,
+	} {
+		conf := Config{LogLevel: logLevel}
+
+		output, err := conf.level()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if output != expectedLevel {
+			t.Errorf("got %v, expected %v", output, expectedLevel)
+		}
+	}
+
+	conf := Config{LogLevel: "verbose"}
+
+	_, err := conf.level()
+	if err == nil {
+		t.Errorf("said 'verbose' is a valid log level, but it is not")
+	}
+}
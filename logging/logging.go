@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// defaultRedactor is the Redactor used by Redact and AddSecrets, replaced by Setup.
+var defaultRedactor = NewRedactor(nil)
+
+// Setup configures the default slog logger to write to output at the configured level and in the
+// configured format, redacting the Terraform Cloud token, the GitHub token, and every sensitive
+// variable value. Within GitHub Actions, each secret is also registered with ::add-mask:: so that
+// the runner masks it wherever it appears, and StartGroup and Error write workflow commands to output.
+func Setup(output io.Writer) error {
+	conf, err := NewConfig()
+	if err != nil {
+		return fmt.Errorf("[NewConfig] %w", err)
+	}
+
+	level, err := conf.level()
+	if err != nil {
+		return fmt.Errorf("[conf.level] %w", err)
+	}
+
+	secrets, err := conf.secrets()
+	if err != nil {
+		return fmt.Errorf("[conf.secrets] %w", err)
+	}
+
+	var masks io.Writer
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		masks = output
+	}
+
+	redactor := NewRedactor(masks)
+	redactor.AddSecrets(secrets...)
+
+	handler, err := NewHandler(output, conf.LogFormat, level, redactor)
+	if err != nil {
+		return fmt.Errorf("[NewHandler] %w", err)
+	}
+
+	defaultRedactor = redactor
+	slog.SetDefault(slog.New(handler))
+
+	workflow.mutex.Lock()
+	workflow.output = masks
+	workflow.groupOpen = false
+	workflow.mutex.Unlock()
+
+	return nil
+}
+
+// NewHandler instantiates a slog.Handler writing logs at or above level to output in format,
+// with every secret known to redactor replaced.
+func NewHandler(output io.Writer, format string, level slog.Level, redactor *Redactor) (slog.Handler, error) {
+	options := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactor.replaceAttr,
+	}
+
+	switch format {
+	case FormatText:
+		return slog.NewTextHandler(output, options), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(output, options), nil
+	default:
+		return nil, fmt.Errorf("log format must be either '%v' or '%v', got %v", FormatText, FormatJSON, format)
+	}
+}
+
+// Redact returns text with every secret known to the default logger replaced. It is used for
+// output written outside of the logger, such as that of terraform and tfmigrate.
+func Redact(text string) string {
+	return defaultRedactor.Redact(text)
+}
+
+// AddSecrets adds values discovered while the job runs to those redacted by the default logger.
+func AddSecrets(values ...string) {
+	defaultRedactor.AddSecrets(values...)
+}
@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSetup(t *testing.T) {
+	defaultLogger := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+		defaultRedactor = NewRedactor(nil)
+	})
+
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("LOGLEVEL", "debug")
+	t.Setenv("LOGFORMAT", "json")
+	t.Setenv("TERRAFORMCLOUDTOKEN", "example.atlasv1.token")
+	t.Setenv("GITHUBTOKEN", "")
+	t.Setenv("TERRAFORMWORKSPACESENSITIVEVARS", `{"workspace_1": {"db_password": {"value": "hunter22", "category": "terraform"}}}`)
+	t.Setenv("TERRAFORMVARSETSENSITIVEVARS", "")
+
+	var output bytes.Buffer
+	err := Setup(&output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	slog.Debug("Authenticating.", "token", "example.atlasv1.token")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	expectedMasks := []string{"::add-mask::example.atlasv1.token", "::add-mask::hunter22"}
+
+	if len(lines) != 3 || lines[0] != expectedMasks[0] || lines[1] != expectedMasks[1] {
+		t.Fatalf("got %v, expected %v followed by a log", lines, expectedMasks)
+	}
+
+	if !strings.Contains(lines[2], `"token":"***"`) || !strings.Contains(lines[2], `"level":"DEBUG"`) {
+		t.Errorf("expected a redacted debug log in JSON, got: %v", lines[2])
+	}
+
+	output.Reset()
+	if Redact("terraform.tfvars: db_password = hunter22") != "terraform.tfvars: db_password = ***" {
+		t.Errorf("expected Redact to use the secrets of the default logger")
+	}
+
+	AddSecrets("vault-secret")
+	if Redact("vault-secret") != "***" || output.String() != "::add-mask::vault-secret\n" {
+		t.Errorf("expected AddSecrets to redact and mask the secret, got masks: %v", output.String())
+	}
+
+	t.Setenv("LOGFORMAT", "yaml")
+	err = Setup(&output)
+	if err == nil {
+		t.Errorf("said 'yaml' is a valid log format, but it is not")
+	}
+}
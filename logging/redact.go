@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redactedValue replaces every secret within redacted text.
+const redactedValue = "***"
+
+// Redactor replaces secrets within text, and registers each secret with GitHub Actions so that
+// the runner masks it too.
+type Redactor struct {
+
+	// mutex guards secrets and replacer, as secrets can be added while logs are written.
+	mutex sync.RWMutex
+
+	// secrets are the values redacted, longest first so that a secret containing another is
+	// redacted whole.
+	secrets []string
+
+	// replacer replaces each secret with redactedValue, nil if there are no secrets.
+	replacer *strings.Replacer
+
+	// masks is where ::add-mask:: workflow commands are written, nil outside of GitHub Actions.
+	masks io.Writer
+}
+
+// NewRedactor instantiates a Redactor with no secrets. When masks is not nil, an ::add-mask::
+// workflow command is written to it for each secret added.
+func NewRedactor(masks io.Writer) *Redactor {
+	return &Redactor{masks: masks}
+}
+
+// AddSecrets adds values to be redacted. Empty values are ignored, and each line of a multi-line
+// value is also redacted on its own, since command output is handled line by line.
+func (r *Redactor) AddSecrets(values ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	known := map[string]bool{}
+	for _, secret := range r.secrets {
+		known[secret] = true
+	}
+
+	for _, value := range values {
+		candidates := append([]string{value}, strings.Split(value, "\n")...)
+
+		for _, candidate := range candidates {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "" || known[candidate] {
+				continue
+			}
+			known[candidate] = true
+			r.secrets = append(r.secrets, candidate)
+
+			if r.masks != nil && !strings.Contains(candidate, "\n") {
+				fmt.Fprintf(r.masks, "::add-mask::%v\n", candidate)
+			}
+		}
+	}
+
+	sort.SliceStable(r.secrets, func(i, j int) bool {
+		return len(r.secrets[i]) > len(r.secrets[j])
+	})
+
+	var oldNew []string
+	for _, secret := range r.secrets {
+		oldNew = append(oldNew, secret, redactedValue)
+	}
+	r.replacer = strings.NewReplacer(oldNew...)
+}
+
+// Redact returns text with every secret replaced.
+func (r *Redactor) Redact(text string) string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if r.replacer == nil {
+		return text
+	}
+
+	return r.replacer.Replace(text)
+}
+
+// replaceAttr redacts the message and attribute values of each log, for use as
+// slog.HandlerOptions.ReplaceAttr.
+func (r *Redactor) replaceAttr(_ []string, a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(r.Redact(a.Value.String()))
+	case slog.KindAny:
+		var text string
+		switch v := a.Value.Any().(type) {
+		case error:
+			text = v.Error()
+		case fmt.Stringer:
+			text = v.String()
+		default:
+			text = fmt.Sprintf("%+v", v)
+		}
+
+		if redacted := r.Redact(text); redacted != text {
+			a.Value = slog.StringValue(redacted)
+		}
+	}
+
+	return a
+}
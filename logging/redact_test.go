@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"testing"
+)
+
+func TestRedactorRedact(t *testing.T) {
+	var masks bytes.Buffer
+	redactor := NewRedactor(&masks)
+	redactor.AddSecrets("token", "token-with-suffix", "", "-----BEGIN KEY-----\nline-two\n-----END KEY-----")
+
+	output := redactor.Redact("using token-with-suffix and token, then line-two")
+	expectedOutput := "using *** and ***, then ***"
+
+	if output != expectedOutput {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+
+	expectedMasks := "::add-mask::token\n::add-mask::token-with-suffix\n" +
+		"::add-mask::-----BEGIN KEY-----\n::add-mask::line-two\n::add-mask::-----END KEY-----\n"
+
+	if masks.String() != expectedMasks {
+		t.Errorf("got %q, expected %q", masks.String(), expectedMasks)
+	}
+
+	redactor.AddSecrets("token")
+	if masks.String() != expectedMasks {
+		t.Errorf("expected a secret added twice to be masked once, got %q", masks.String())
+	}
+}
+
+func TestRedactorWithoutSecrets(t *testing.T) {
+	redactor := NewRedactor(nil)
+
+	output := redactor.Redact("nothing to hide")
+	if output != "nothing to hide" {
+		t.Errorf("got %v, expected %v", output, "nothing to hide")
+	}
+}
+
+func TestHandlerRedactsAttributes(t *testing.T) {
+	redactor := NewRedactor(nil)
+	redactor.AddSecrets("hunter22")
+
+	for _, format := range []string{FormatText, FormatJSON} {
+		var output bytes.Buffer
+		handler, err := NewHandler(&output, format, 0, redactor)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		logger := slog.New(handler)
+		logger.Info(
+			"password is hunter22",
+			"value", "hunter22",
+			"error", fmt.Errorf("[terraform] invalid password hunter22"),
+			"values", []string{"hunter22"},
+		)
+
+		if bytes.Contains(output.Bytes(), []byte("hunter22")) {
+			t.Errorf("expected the %v log to be redacted, got: %v", format, output.String())
+		}
+
+		if !bytes.Contains(output.Bytes(), []byte("invalid password ***")) {
+			t.Errorf("expected the %v log to keep the error message, got: %v", format, output.String())
+		}
+	}
+
+	_, err := NewHandler(&bytes.Buffer{}, "yaml", 0, redactor)
+	if err == nil {
+		t.Errorf("said 'yaml' is a valid log format, but it is not")
+	}
+}
@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// workflow writes GitHub Actions workflow commands, such as groups and annotations.
+var workflow = &workflowCommands{}
+
+// Annotation is an error surfaced in the GitHub Actions UI, pointing at the file that caused it.
+type Annotation struct {
+
+	// Title is shown above the message.
+	Title string
+
+	// File is the path of the file, relative to the repository root.
+	File string
+
+	// Line is the line within File, zero if unknown.
+	Line int
+
+	// Message describes the error.
+	Message string
+}
+
+// workflowCommands writes workflow commands while running within GitHub Actions, and does nothing otherwise.
+type workflowCommands struct {
+
+	// mutex guards groupOpen, as commands can be written while logs are written.
+	mutex sync.Mutex
+
+	// output is where workflow commands are written, nil outside of GitHub Actions.
+	output io.Writer
+
+	// groupOpen is whether a group has been started and not yet ended.
+	groupOpen bool
+}
+
+// StartGroup begins a collapsible group of log lines. GitHub Actions does not nest groups, so any
+// group already started is ended first.
+func StartGroup(title string) {
+	workflow.mutex.Lock()
+	defer workflow.mutex.Unlock()
+
+	if workflow.output == nil {
+		return
+	}
+
+	if workflow.groupOpen {
+		fmt.Fprintln(workflow.output, "::endgroup::")
+	}
+	fmt.Fprintf(workflow.output, "::group::%v\n", escapeData(Redact(title)))
+	workflow.groupOpen = true
+}
+
+// EndGroup ends the group started by StartGroup, if any.
+func EndGroup() {
+	workflow.mutex.Lock()
+	defer workflow.mutex.Unlock()
+
+	if workflow.output == nil || !workflow.groupOpen {
+		return
+	}
+
+	fmt.Fprintln(workflow.output, "::endgroup::")
+	workflow.groupOpen = false
+}
+
+// Error surfaces an error annotation. Any open group is ended first so that the annotation is
+// visible without expanding the group.
+func Error(annotation Annotation) {
+	EndGroup()
+
+	workflow.mutex.Lock()
+	defer workflow.mutex.Unlock()
+
+	if workflow.output == nil {
+		return
+	}
+
+	var properties []string
+	if annotation.Title != "" {
+		properties = append(properties, "title="+escapeProperty(Redact(annotation.Title)))
+	}
+	if annotation.File != "" {
+		properties = append(properties, "file="+escapeProperty(annotation.File))
+	}
+	if annotation.File != "" && annotation.Line > 0 {
+		properties = append(properties, fmt.Sprintf("line=%v", annotation.Line))
+	}
+
+	command := "error"
+	if len(properties) > 0 {
+		command += " " + strings.Join(properties, ",")
+	}
+
+	fmt.Fprintf(workflow.output, "::%v::%v\n", command, escapeData(Redact(annotation.Message)))
+}
+
+// escapeData escapes the message of a workflow command.
+func escapeData(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(value)
+}
+
+// escapeProperty escapes a property value of a workflow command.
+func escapeProperty(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(value)
+}
@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWorkflowCommands(t *testing.T) {
+	var output bytes.Buffer
+	workflow.output = &output
+	t.Cleanup(func() {
+		workflow.output = nil
+		workflow.groupOpen = false
+	})
+
+	StartGroup("workspace_1 (directory_1)")
+	StartGroup("workspace_1: terraform init")
+	Error(Annotation{
+		Title:   "Migration of workspace workspace_1 failed",
+		File:    "directory_1/dragondrop/tfmigrate/2_move.hcl",
+		Line:    3,
+		Message: "[tfmigrate] failed, 100% sure\nsecond line",
+	})
+	EndGroup()
+	Error(Annotation{Message: "no file"})
+
+	expectedOutput := "::group::workspace_1 (directory_1)\n" +
+		"::endgroup::\n" +
+		"::group::workspace_1: terraform init\n" +
+		"::endgroup::\n" +
+		"::error title=Migration of workspace workspace_1 failed,file=directory_1/dragondrop/tfmigrate/2_move.hcl,line=3::" +
+		"[tfmigrate] failed, 100%25 sure%0Asecond line\n" +
+		"::error::no file\n"
+
+	if output.String() != expectedOutput {
+		t.Errorf("got %q, expected %q", output.String(), expectedOutput)
+	}
+}
+
+func TestWorkflowCommandsOutsideGitHubActions(t *testing.T) {
+	StartGroup("workspace_1")
+	EndGroup()
+	Error(Annotation{Message: "failed"})
+
+	if workflow.groupOpen {
+		t.Errorf("expected no group to be started outside of GitHub Actions")
+	}
+}
+
+func TestEscapeProperty(t *testing.T) {
+	output := escapeProperty("a:b,c%d\ne")
+	expectedOutput := "a%3Ab%2Cc%25d%0Ae"
+
+	if output != expectedOutput {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+}
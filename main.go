@@ -1,24 +1,94 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/logging"
 	"github.com/dragondrop-cloud/github-action-tfstate-migration/statemigration"
 )
 
 func main() {
+	// Cancelling the job sends SIGTERM (or SIGINT locally), which stops the running command and
+	// unwinds the current workspace rather than leaving its state locked.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	exitCode := run(ctx, os.Args[1:])
+	stop()
+	os.Exit(exitCode)
+}
+
+// run dispatches to the subcommand named by args, falling back to the original single code
+// path, in which ISAPPLY chooses between plan and apply, when no subcommand is given.
+func run(ctx context.Context, args []string) int {
+	if len(args) > 0 && args[0] == "help" {
+		writeUsage(os.Stdout)
+		return exitOK
+	}
+
+	if sc, subcommandArgs, ok := findSubcommand(args); ok {
+		return runSubcommand(ctx, sc, subcommandArgs, os.Stdout, os.Stderr)
+	}
+
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		fmt.Fprintf(os.Stderr, "unknown command: %v\n\n", args[0])
+		writeUsage(os.Stderr)
+		return exitUsage
+	}
+
+	return runLegacy(ctx, args)
+}
+
+// runLegacy runs tfmigrate plan or apply for every workspace as set by ISAPPLY, as the GitHub
+// Action does.
+func runLegacy(ctx context.Context, args []string) int {
+	flagSet := flag.NewFlagSet("tfstate-migration", flag.ContinueOnError)
+	flagSet.Usage = func() {
+		writeUsage(flagSet.Output())
+		fmt.Fprintf(flagSet.Output(), "\nFlags, each of which overrides the environment variable shown:\n\n")
+		flagSet.PrintDefaults()
+	}
+	registerConfigFlags(flagSet)
+
+	err := flagSet.Parse(args)
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+
+	err = applyConfigFlags(flagSet)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error applying flags: %v\n", err)
+		return exitUsage
+	}
+
+	err = logging.Setup(os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error configuring logging: %v\n", err)
+		return exitUsage
+	}
+
 	stateMigrator, err := statemigration.NewStateMigrator()
 	if err != nil {
-		fmt.Printf("error in statemigration.NewStateMigrator(config): %v", err)
-		os.Exit(1)
+		slog.Error("Unable to create the state migrator.", "error", err)
+		return exitFailure
 	}
 
-	err = stateMigrator.MigrateAllWorkspaces()
+	report, err := stateMigrator.MigrateAllWorkspaces(ctx)
+	reportToGitHub(ctx, report.Workspaces, err)
 
 	if err != nil {
-		fmt.Printf("error migrating all workspace's state: %v", err)
-		os.Exit(1)
+		slog.Error("Unable to migrate all workspaces' state.", "error", err)
+		return exitCodeFor(ctx, err)
 	}
-	fmt.Println("Successfully ran tfstate-migration job.")
+	slog.Info("Successfully ran tfstate-migration job.")
+
+	return exitOK
 }
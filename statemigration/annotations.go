@@ -0,0 +1,171 @@
+package statemigration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/logging"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// workspaceGroupTitle is the title of the log group of a workspace.
+func workspaceGroupTitle(result *WorkspaceResult) string {
+	return fmt.Sprintf("%v (%v)", result.Workspace, result.Directory)
+}
+
+// annotateFailure surfaces the failure of a workspace as an error annotation, pointing at the
+// migration file that failed, or otherwise the workspace's directory.
+func (sm *stateMigrator) annotateFailure(result *WorkspaceResult) {
+	file := result.ErrorFile
+	if file == "" {
+		file = result.Directory
+	}
+
+	logging.Error(logging.Annotation{
+		Title:   fmt.Sprintf("Migration of workspace %v failed", result.Workspace),
+		File:    sm.repositoryPath(file),
+		Line:    result.ErrorLine,
+		Message: result.Error,
+	})
+}
+
+// repositoryPath converts a path relative to the root directory into one relative to the
+// repository checked out at GITHUB_WORKSPACE, as annotations require.
+func (sm *stateMigrator) repositoryPath(path string) string {
+	absolutePath := filepath.Join(sm.config.RootDirectory, path)
+
+	if githubWorkspace := os.Getenv("GITHUB_WORKSPACE"); githubWorkspace != "" {
+		relativePath, err := filepath.Rel(githubWorkspace, absolutePath)
+		if err == nil && !strings.HasPrefix(relativePath, "..") {
+			return relativePath
+		}
+	}
+
+	return filepath.Clean(strings.TrimPrefix(path, "/"))
+}
+
+// failedMigrationLocation determines the migration file that tfmigrate failed on, relative to the
+// workspace's directory, and the line within it. tfmigrate runs migration files in order and logs
+// the path of each as it is loaded, so the last line naming a single migration file by its path
+// names the one that failed. The line is taken from an HCL diagnostic naming the file if there is one, and
+// otherwise from failedActionLine. An empty path is returned if no migration file is named.
+func failedMigrationLocation(workspaceDirectory string, tfmigrateOutput string) (string, int) {
+	migrationDir, err := readMigrationDir(filepath.Join(workspaceDirectory, tfmigrateConfigPath))
+	if err != nil {
+		return "", 0
+	}
+
+	fileNames, err := filepath.Glob(filepath.Join(workspaceDirectory, migrationDir, "*.hcl"))
+	if err != nil {
+		return "", 0
+	}
+
+	failedFile := ""
+	outputLines := strings.Split(tfmigrateOutput, "\n")
+
+	for i := len(outputLines) - 1; i >= 0 && failedFile == ""; i-- {
+		lineWords := outputWords(outputLines[i])
+
+		var namedFiles []string
+		for _, fileName := range fileNames {
+			if isLoggedMigrationFile(lineWords, migrationDir, fileName) {
+				namedFiles = append(namedFiles, fileName)
+			}
+		}
+
+		// Lines listing several files, such as the list of unapplied files, do not say which failed.
+		if len(namedFiles) == 1 {
+			failedFile = namedFiles[0]
+		}
+	}
+
+	if failedFile == "" {
+		return "", 0
+	}
+
+	relativePath, err := filepath.Rel(workspaceDirectory, failedFile)
+	if err != nil {
+		return "", 0
+	}
+
+	// The file name must start a word or follow a path separator, so that "1.hcl" does not match "11.hcl".
+	diagnostic := regexp.MustCompile(`(?m)(?:^|[\s/])` + regexp.QuoteMeta(filepath.Base(failedFile)) + `:(\d+)`)
+	if match := diagnostic.FindStringSubmatch(tfmigrateOutput); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		return relativePath, line
+	}
+
+	return relativePath, failedActionLine(failedFile, tfmigrateOutput)
+}
+
+// failedActionLine returns the line of the action within a migration file whose first address
+// appears last in tfmigrate's output, or the line of the first migration block if none appear.
+func failedActionLine(fileName string, tfmigrateOutput string) int {
+	src, err := os.ReadFile(fileName)
+	if err != nil {
+		return 0
+	}
+
+	file, diags := hclsyntax.ParseConfig(src, fileName, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return 0
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return 0
+	}
+
+	blockLine := 0
+	actionLine := 0
+	lastIndex := -1
+
+	for _, block := range body.Blocks {
+		if block.Type != "migration" {
+			continue
+		}
+
+		if blockLine == 0 {
+			blockLine = block.TypeRange.Start.Line
+		}
+
+		attribute, ok := block.Body.Attributes["actions"]
+		if !ok {
+			continue
+		}
+
+		tuple, ok := attribute.Expr.(*hclsyntax.TupleConsExpr)
+		if !ok {
+			continue
+		}
+
+		for _, expr := range tuple.Exprs {
+			value, diags := expr.Value(nil)
+			if diags.HasErrors() || !value.Type().Equals(cty.String) || value.IsNull() {
+				continue
+			}
+
+			fields := strings.Fields(value.AsString())
+			if len(fields) < 2 {
+				continue
+			}
+
+			if index := strings.LastIndex(tfmigrateOutput, fields[1]); index > lastIndex {
+				actionLine = expr.Range().Start.Line
+				lastIndex = index
+			}
+		}
+	}
+
+	if actionLine > 0 {
+		return actionLine
+	}
+
+	return blockLine
+}
@@ -0,0 +1,115 @@
+package statemigration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFailedMigrationLocation(t *testing.T) {
+	workspaceDirectory := t.TempDir()
+	migrationDir := filepath.Join(workspaceDirectory, "dragondrop", "tfmigrate")
+	err := os.MkdirAll(migrationDir, 0755)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files := map[string]string{
+		".tfmigrate.hcl": `tfmigrate {
+  migration_dir = "./dragondrop/tfmigrate"
+}`,
+		"1_move.hcl": `migration "state" "move" {
+  actions = [
+    "mv aws_s3_bucket.a aws_s3_bucket.b",
+    "mv aws_s3_bucket.c aws_s3_bucket.d",
+  ]
+}`,
+		"2_remove.hcl": `migration "state" "remove" {
+  actions = ["rm aws_s3_bucket.e"]
+}`,
+		"11_move.hcl": `migration "state" "move" {
+  actions = [
+    "mv aws_s3_bucket.f aws_s3_bucket.g",
+  ]
+}`,
+	}
+	for fileName, content := range files {
+		err = os.WriteFile(filepath.Join(migrationDir, fileName), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		output       string
+		expectedFile string
+		expectedLine int
+	}{
+		{
+			output: "[INFO] [runner] unapplied migration files: [1_move.hcl 2_remove.hcl]\n" +
+				"[INFO] [runner] load migration file: dragondrop/tfmigrate/1_move.hcl\n" +
+				"Error: Invalid target address aws_s3_bucket.c",
+			expectedFile: "dragondrop/tfmigrate/1_move.hcl",
+			expectedLine: 4,
+		},
+		{
+			output: "[INFO] [runner] load migration file: dragondrop/tfmigrate/1_move.hcl\n" +
+				"[INFO] [runner] load migration file: dragondrop/tfmigrate/2_remove.hcl\n" +
+				"failed to parse: 2_remove.hcl:2,3-10: Unsupported argument",
+			expectedFile: "dragondrop/tfmigrate/2_remove.hcl",
+			expectedLine: 2,
+		},
+		{
+			output: "[INFO] [runner] load migration file: dragondrop/tfmigrate/2_remove.hcl\n" +
+				"Error: failed to lock state",
+			expectedFile: "dragondrop/tfmigrate/2_remove.hcl",
+			expectedLine: 1,
+		},
+		{
+			// 1_move.hcl is within the name of 11_move.hcl, but is not the file that failed.
+			output: "[INFO] [runner] load migration file: dragondrop/tfmigrate/11_move.hcl\n" +
+				"failed to parse: dragondrop/tfmigrate/11_move.hcl:3,5-40: Invalid address",
+			expectedFile: "dragondrop/tfmigrate/11_move.hcl",
+			expectedLine: 3,
+		},
+		{
+			output: "[INFO] [runner] load migration file: dragondrop/tfmigrate/2_remove.hcl\n" +
+				"failed to parse: 12_remove.hcl:7,1-4: Unsupported argument",
+			expectedFile: "dragondrop/tfmigrate/2_remove.hcl",
+			expectedLine: 1,
+		},
+		{
+			output:       "Error: required token could not be found",
+			expectedFile: "",
+			expectedLine: 0,
+		},
+	}
+
+	for _, testCase := range testCases {
+		file, line := failedMigrationLocation(workspaceDirectory, testCase.output)
+
+		if file != testCase.expectedFile || line != testCase.expectedLine {
+			t.Errorf("got %v:%v, expected %v:%v", file, line, testCase.expectedFile, testCase.expectedLine)
+		}
+	}
+}
+
+func TestRepositoryPath(t *testing.T) {
+	t.Setenv("GITHUB_WORKSPACE", "/github/workspace")
+
+	sm := stateMigrator{config: &Config{RootDirectory: "/github/workspace/infrastructure"}}
+
+	output := sm.repositoryPath("/directory_1/dragondrop/tfmigrate/1_move.hcl")
+	expectedOutput := "infrastructure/directory_1/dragondrop/tfmigrate/1_move.hcl"
+
+	if output != expectedOutput {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+
+	sm.config.RootDirectory = "/somewhere/else"
+
+	output = sm.repositoryPath("/directory_1/")
+	if output != "directory_1" {
+		t.Errorf("got %v, expected %v", output, "directory_1")
+	}
+}
@@ -0,0 +1,247 @@
+package statemigration
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/logging"
+)
+
+// maxOutputTailLines is the number of trailing output lines included in a failed command's error.
+const maxOutputTailLines = 50
+
+// Command describes a single external command for a CommandRunner to execute.
+type Command struct {
+
+	// Name is the name or path of the executable.
+	Name string
+
+	// Args are the arguments passed to the executable.
+	Args []string
+
+	// Env are "KEY=value" entries added to the current process's environment.
+	Env []string
+
+	// Dir is the working directory of the command.
+	Dir string
+
+	// Timeout is the maximum duration of the command. Zero means no timeout.
+	Timeout time.Duration
+}
+
+// String renders the command as it would be typed into a shell.
+func (c Command) String() string {
+	return strings.TrimSpace(c.Name + " " + strings.Join(c.Args, " "))
+}
+
+// CommandResult describes the outcome of a Command.
+type CommandResult struct {
+
+	// ExitCode is the exit code of the command, or -1 if it did not exit normally.
+	ExitCode int
+
+	// Duration is how long the command ran for.
+	Duration time.Duration
+
+	// Output is the command's combined stdout and stderr.
+	Output string
+}
+
+// CommandRunner is an interface for executing external commands.
+type CommandRunner interface {
+
+	// Run executes the command, returning an error if it fails, times out, or the
+	// context is cancelled.
+	Run(ctx context.Context, command Command) (CommandResult, error)
+}
+
+// execCommandRunner implements the CommandRunner interface with os/exec, streaming
+// each line of output as it is written.
+type execCommandRunner struct {
+
+	// output is where each line of command output is streamed.
+	output io.Writer
+
+	// gracePeriod is how long a command has to exit after SIGTERM before it is killed.
+	gracePeriod time.Duration
+}
+
+// NewCommandRunner instantiates a CommandRunner which streams output to stdout. Commands that
+// time out or whose context is cancelled are sent SIGTERM, and killed if they have not exited
+// after gracePeriod.
+func NewCommandRunner(gracePeriod time.Duration) CommandRunner {
+	return &execCommandRunner{output: os.Stdout, gracePeriod: gracePeriod}
+}
+
+// Run executes the command, streaming its output line by line.
+func (r *execCommandRunner) Run(ctx context.Context, command Command) (CommandResult, error) {
+	if command.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, command.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, command.Name, command.Args...)
+	cmd.Dir = command.Dir
+	cmd.Env = append(os.Environ(), command.Env...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = r.gracePeriod
+
+	streamer := newOutputStreamer(r.output, command.Name)
+	cmd.Stdout = streamer.writer()
+	cmd.Stderr = streamer.writer()
+
+	slog.Info("Running command.", "command", command.String(), "directory", command.Dir)
+
+	start := time.Now()
+	err := cmd.Run()
+	streamer.flush()
+
+	result := CommandResult{
+		ExitCode: -1,
+		Duration: time.Since(start),
+		Output:   streamer.output(),
+	}
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	commandErr := &CommandError{
+		Command:  logging.Redact(command.String()),
+		ExitCode: result.ExitCode,
+		Timeout:  command.Timeout,
+		Output:   streamer.tail(),
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		commandErr.Err = ErrCommandTimeout
+		return result, commandErr
+	case errors.Is(ctx.Err(), context.Canceled):
+		commandErr.Err = ctx.Err()
+		return result, commandErr
+	case err != nil:
+		commandErr.Err = err
+		return result, commandErr
+	}
+
+	return result, nil
+}
+
+// outputStreamer writes each complete line of a command's stdout and stderr to an output,
+// keeping the trailing lines for error messages.
+type outputStreamer struct {
+
+	// mutex guards destination, lines, and tailLines, which are shared by the stdout and stderr writers.
+	mutex sync.Mutex
+
+	// destination is where each line is written.
+	destination io.Writer
+
+	// prefix is written before each line to show which command it came from.
+	prefix string
+
+	// lines are all lines written.
+	lines []string
+
+	// tailLines are the most recent lines written.
+	tailLines []string
+
+	// writers are the line writers created for the command's output streams.
+	writers []*lineWriter
+}
+
+// newOutputStreamer instantiates an outputStreamer that prefixes lines with the command name.
+func newOutputStreamer(output io.Writer, commandName string) *outputStreamer {
+	return &outputStreamer{
+		destination: output,
+		prefix:      fmt.Sprintf("[%v] ", commandName[strings.LastIndex(commandName, "/")+1:]),
+	}
+}
+
+// writer creates a new io.Writer for one of the command's output streams.
+func (s *outputStreamer) writer() io.Writer {
+	w := &lineWriter{streamer: s}
+	s.writers = append(s.writers, w)
+	return w
+}
+
+// writeLine writes a single line to the output and records it in the tail.
+func (s *outputStreamer) writeLine(line string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	line = logging.Redact(line)
+	fmt.Fprintf(s.destination, "%v%v\n", s.prefix, line)
+
+	s.lines = append(s.lines, line)
+	s.tailLines = append(s.tailLines, line)
+	if len(s.tailLines) > maxOutputTailLines {
+		s.tailLines = s.tailLines[1:]
+	}
+}
+
+// flush writes any partial lines remaining once the command has exited.
+func (s *outputStreamer) flush() {
+	for _, w := range s.writers {
+		if len(w.buffer) > 0 {
+			s.writeLine(string(w.buffer))
+			w.buffer = nil
+		}
+	}
+}
+
+// output returns every line of output.
+func (s *outputStreamer) output() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return strings.Join(s.lines, "\n")
+}
+
+// tail returns the most recent lines of output.
+func (s *outputStreamer) tail() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return strings.Join(s.tailLines, "\n")
+}
+
+// lineWriter buffers a single output stream until a complete line has been written.
+type lineWriter struct {
+
+	// streamer receives each complete line.
+	streamer *outputStreamer
+
+	// buffer holds the current partial line.
+	buffer []byte
+}
+
+// Write implements io.Writer, passing each complete line to the streamer.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buffer = append(w.buffer, p...)
+
+	for {
+		i := bytes.IndexByte(w.buffer, '\n')
+		if i < 0 {
+			break
+		}
+
+		w.streamer.writeLine(strings.TrimSuffix(string(w.buffer[:i]), "\r"))
+		w.buffer = w.buffer[i+1:]
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,128 @@
+package statemigration
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/logging"
+)
+
+func TestExecCommandRunnerStreamsLines(t *testing.T) {
+	var output bytes.Buffer
+	runner := execCommandRunner{output: &output}
+
+	result, err := runner.Run(context.Background(), Command{
+		Name: "sh",
+		Args: []string{"-c", "echo first; echo second 1>&2; printf partial"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.ExitCode != 0 {
+		t.Errorf("got exit code %v, expected 0", result.ExitCode)
+	}
+
+	if !strings.Contains(result.Output, "first") || !strings.Contains(result.Output, "partial") {
+		t.Errorf("expected the result to capture the output, got:\n%v", result.Output)
+	}
+
+	for _, expectedLine := range []string{"[sh] first\n", "[sh] second\n", "[sh] partial\n"} {
+		if !strings.Contains(output.String(), expectedLine) {
+			t.Errorf("expected output to contain %q, got:\n%v", expectedLine, output.String())
+		}
+	}
+}
+
+func TestExecCommandRunnerRedactsSecrets(t *testing.T) {
+	logging.AddSecrets("s3cr3t-tfvar-value")
+
+	var output bytes.Buffer
+	runner := execCommandRunner{output: &output}
+
+	result, err := runner.Run(context.Background(), Command{
+		Name: "sh",
+		Args: []string{"-c", "echo db_password = s3cr3t-tfvar-value; exit 1"},
+	})
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	for _, text := range []string{output.String(), result.Output, err.Error()} {
+		if strings.Contains(text, "s3cr3t-tfvar-value") || !strings.Contains(text, "db_password = ***") {
+			t.Errorf("expected the secret to be redacted, got:\n%v", text)
+		}
+	}
+}
+
+func TestExecCommandRunnerFailure(t *testing.T) {
+	var output bytes.Buffer
+	runner := execCommandRunner{output: &output}
+
+	result, err := runner.Run(context.Background(), Command{
+		Name: "sh",
+		Args: []string{"-c", "echo something went wrong 1>&2; exit 3"},
+	})
+	if err == nil {
+		t.Fatalf("expected an error from a failing command")
+	}
+
+	if result.ExitCode != 3 {
+		t.Errorf("got exit code %v, expected 3", result.ExitCode)
+	}
+
+	if !strings.Contains(err.Error(), "something went wrong") {
+		t.Errorf("expected the error to include the command output, got: %v", err)
+	}
+}
+
+func TestExecCommandRunnerTimeout(t *testing.T) {
+	var output bytes.Buffer
+	runner := execCommandRunner{output: &output}
+
+	start := time.Now()
+	_, err := runner.Run(context.Background(), Command{
+		Name:    "sleep",
+		Args:    []string{"10"},
+		Timeout: 100 * time.Millisecond,
+	})
+	if err == nil {
+		t.Fatalf("expected an error from a command that timed out")
+	}
+
+	if !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout error, got: %v", err)
+	}
+
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("command was not stopped at its timeout")
+	}
+}
+
+func TestExecCommandRunnerInterrupt(t *testing.T) {
+	var output bytes.Buffer
+	runner := execCommandRunner{output: &output, gracePeriod: time.Second}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	// The command handles SIGTERM itself, showing that it is given a chance to clean up.
+	_, err := runner.Run(ctx, Command{
+		Name: "sh",
+		Args: []string{"-c", "trap 'echo cleaning up; exit 1' TERM; while true; do sleep 0.05; done"},
+	})
+	if err == nil {
+		t.Fatalf("expected an error from an interrupted command")
+	}
+
+	if !strings.Contains(err.Error(), "interrupted") {
+		t.Errorf("expected an interrupted error, got: %v", err)
+	}
+
+	if !strings.Contains(output.String(), "[sh] cleaning up") {
+		t.Errorf("expected the command to handle SIGTERM, got output: %v", output.String())
+	}
+}
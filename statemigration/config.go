@@ -2,12 +2,38 @@ package statemigration
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
+	"time"
 
+	"github.com/hashicorp/go-version"
 	"github.com/kelseyhightower/envconfig"
+
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/installer"
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/internal/rootdir"
+)
+
+// AutoVersion is the Version value that resolves the Terraform version for each workspace
+// from Terraform Cloud, a .terraform-version file, or a required_version constraint.
+const AutoVersion = "auto"
+
+// exactVersionRegex matches an exact "major.minor.patch" version, optionally followed by a
+// prerelease such as "-beta1".
+var exactVersionRegex = regexp.MustCompile(`^\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?$`)
+
+// Engine is the binary used to run migrations, either Terraform or OpenTofu.
+type Engine string
+
+const (
+	// EngineTerraform runs migrations with Terraform.
+	EngineTerraform Engine = installer.Terraform
+
+	// EngineTofu runs migrations with OpenTofu.
+	EngineTofu Engine = installer.OpenTofu
 )
 
-// Version is a type representing a Terraform Version.
+// Version is a type representing a Terraform Version, which can be an exact version,
+// a version constraint, or AutoVersion.
 type Version string
 
 // Config contains environment variables needed to run StateMigrator methods.
@@ -19,7 +45,11 @@ type Config struct {
 	// TerraformCloudToken is a token to access terraform cloud remote state.
 	TerraformCloudToken string `required:"true"`
 
-	// TerraformVersion is the default version of terraform to use for migrations. It is optional.
+	// Engine is the binary used to run migrations, either "terraform" (the default) or "tofu".
+	Engine Engine `required:"false"`
+
+	// TerraformVersion is the default version of terraform to use for migrations. It can be an
+	// exact version, a version constraint, or "auto". It is optional.
 	TerraformVersion Version `required:"true"`
 
 	// IsApply is a Boolean of whether to run `tfmigrate apply` ("true") or
@@ -29,6 +59,20 @@ type Config struct {
 	// WorkspaceToDirectory is a map between workspace name and the relative directory
 	// for a workspace's configuration.
 	WorkspaceToDirectory map[string]string `required:"true"`
+
+	// RootDirectory is the directory that workspace directories are relative to. It defaults to
+	// GITHUB_WORKSPACE when set, and otherwise the current working directory.
+	RootDirectory string `required:"false"`
+
+	// ReportFile is the path to which a JSON report of the job is written. No report is written when empty.
+	ReportFile string `required:"false"`
+
+	// CommandTimeout is the maximum duration of each engine and tfmigrate command.
+	CommandTimeout time.Duration `default:"30m"`
+
+	// ShutdownGracePeriod is how long a running command is given to exit after being sent
+	// SIGTERM, due to a timeout or the job being cancelled, before it is killed.
+	ShutdownGracePeriod time.Duration `default:"5s"`
 }
 
 // NewConfig instantiates a new instance of the Config struct.
@@ -37,29 +81,116 @@ func NewConfig() (*Config, error) {
 	err := envconfig.Process("", &c)
 
 	if err != nil {
-		return nil, fmt.Errorf("[envconfig.Process] Error loading config: %v", err)
+		return nil, fmt.Errorf("[envconfig.Process] Error loading config: %w", err)
+	}
+
+	if c.Engine == "" {
+		c.Engine = EngineTerraform
+	}
+
+	if c.RootDirectory == "" {
+		c.RootDirectory, err = rootdir.Default()
+		if err != nil {
+			return nil, fmt.Errorf("[rootdir.Default] %w", err)
+		}
+	}
+
+	return &c, err
+}
+
+// ValidateConfig contains the environment variables needed to validate workspaces, which, unlike
+// Config, do not include Terraform Cloud credentials.
+type ValidateConfig struct {
+
+	// WorkspaceToDirectory is a map between workspace name and the relative directory
+	// for a workspace's configuration.
+	WorkspaceToDirectory map[string]string `required:"true"`
+
+	// RootDirectory is the directory that workspace directories are relative to. It defaults to
+	// GITHUB_WORKSPACE when set, and otherwise the current working directory.
+	RootDirectory string `required:"false"`
+}
+
+// NewValidateConfig instantiates a new instance of the ValidateConfig struct.
+func NewValidateConfig() (*ValidateConfig, error) {
+	var c ValidateConfig
+	err := envconfig.Process("", &c)
+
+	if err != nil {
+		return nil, fmt.Errorf("[envconfig.Process] Error loading config: %w", err)
+	}
+
+	if c.RootDirectory == "" {
+		c.RootDirectory, err = rootdir.Default()
+		if err != nil {
+			return nil, fmt.Errorf("[rootdir.Default] %w", err)
+		}
 	}
 
 	return &c, err
 }
 
+// Decode parses a string into an Engine, defaulting to Terraform when empty.
+func (e *Engine) Decode(value string) error {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", string(EngineTerraform):
+		*e = EngineTerraform
+	case string(EngineTofu), "opentofu":
+		*e = EngineTofu
+	default:
+		return fmt.Errorf("engine must be either '%v' or '%v', got %v", EngineTerraform, EngineTofu, value)
+	}
+
+	return nil
+}
+
+// Decode parses a string into a Version. Accepted values are an exact version ("1.2.3",
+// "1.6.0-beta1"), a version constraint ("~> 1.5", ">= 1.3, < 2.0"), or "auto" (or empty), which
+// resolves the version separately for each workspace.
 func (v *Version) Decode(value string) error {
-	if string(value[1]) != "." {
-		return fmt.Errorf("terraform version should start with 'major version[.]'")
+	value = strings.TrimSpace(value)
+
+	if value == "" || value == AutoVersion {
+		*v = Version(value)
+		return nil
 	}
 
-	stringComponents := strings.Split(value, ".")
-	versionLength := len(stringComponents)
-	if versionLength != 3 {
-		return fmt.Errorf("expected three pieces of the version once split by '.', instead got %v", versionLength)
+	if exactVersionRegex.MatchString(value) {
+		err := checkMajorVersion(value)
+		if err != nil {
+			return err
+		}
+
+		*v = Version(value)
+		return nil
+	}
+
+	_, err := version.NewConstraint(value)
+	if err != nil {
+		return fmt.Errorf("terraform version must be 'auto', an exact version, or a version constraint, got %v: %w", value, err)
 	}
 
-	majorVersion := stringComponents[0]
+	*v = Version(value)
+	return nil
+}
+
+// checkMajorVersion returns an error unless the major version of an exact version is 0 or 1.
+func checkMajorVersion(value string) error {
+	majorVersion := strings.Split(value, ".")[0]
 
 	if (majorVersion != "0") && (majorVersion != "1") {
 		return fmt.Errorf("terraform major version must be either '0' or '1', got %v", majorVersion)
 	}
 
-	*v = Version(value)
 	return nil
 }
+
+// IsAuto returns whether the Version should be resolved separately for each workspace.
+func (v Version) IsAuto() bool {
+	return v == "" || v == AutoVersion
+}
+
+// IsExact returns whether the Version is an exact version rather than a constraint.
+func (v Version) IsExact() bool {
+	return exactVersionRegex.MatchString(string(v))
+}
@@ -19,22 +19,91 @@ func TestVersionDecoder(t *testing.T) {
 	var envVarTwo Version
 
 	err = envVarTwo.Decode("~>1.2.3")
-	if err == nil {
-		t.Errorf("said '1.2.3' is valid, but it is not")
+	if err != nil {
+		t.Errorf("said '~>1.2.3' is invalid, but it is a valid constraint: %v", err)
 	}
 
 	var envVarThree Version
 
-	err = envVarThree.Decode("~>1.2")
-	if err == nil {
-		t.Errorf("said '~>1.2' is valid, but it is not")
+	err = envVarThree.Decode(">= 1.3, < 2.0")
+	if err != nil {
+		t.Errorf("said '>= 1.3, < 2.0' is invalid, but it is a valid constraint: %v", err)
 	}
 
 	var envVarFour Version
 
 	err = envVarFour.Decode("2.6.5")
 	if err == nil {
-		t.Errorf("said '~>2.6.5' is valid, but it is not")
+		t.Errorf("said '2.6.5' is valid, but it is not")
+	}
+
+	var envVarFive Version
+
+	err = envVarFive.Decode("x")
+	if err == nil {
+		t.Errorf("said 'x' is valid, but it is not")
+	}
+
+	var envVarSix Version
+
+	err = envVarSix.Decode("auto")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if !envVarSix.IsAuto() || envVarSix.IsExact() {
+		t.Errorf("expected 'auto' to be an auto version")
+	}
+
+	var envVarSeven Version
+
+	err = envVarSeven.Decode("not a version")
+	if err == nil {
+		t.Errorf("said 'not a version' is valid, but it is not")
+	}
+
+	var envVarEight Version
+
+	err = envVarEight.Decode("1.6.0-beta1")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if !envVarEight.IsExact() {
+		t.Errorf("expected '1.6.0-beta1' to be an exact version")
+	}
+
+	var envVarNine Version
+
+	err = envVarNine.Decode("2.0.0-beta1")
+	if err == nil {
+		t.Errorf("said '2.0.0-beta1' is valid, but it is not")
 	}
+}
+
+func TestEngineDecoder(t *testing.T) {
+	var engine Engine
 
+	err := engine.Decode("tofu")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if engine != EngineTofu {
+		t.Errorf("got %v, expected %v", engine, EngineTofu)
+	}
+
+	err = engine.Decode("")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if engine != EngineTerraform {
+		t.Errorf("got %v, expected %v", engine, EngineTerraform)
+	}
+
+	err = engine.Decode("pulumi")
+	if err == nil {
+		t.Errorf("said 'pulumi' is a valid engine, but it is not")
+	}
 }
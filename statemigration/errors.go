@@ -0,0 +1,209 @@
+package statemigration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/internal/tfcapi"
+)
+
+// Sentinel errors identifying why a migration failed, for use with errors.Is.
+var (
+	// ErrUnauthorized is returned when Terraform Cloud rejects the token as invalid or expired.
+	ErrUnauthorized = errors.New("the Terraform Cloud token is invalid or expired")
+
+	// ErrForbidden is returned when the token is valid but lacks permission for a request.
+	ErrForbidden = errors.New("the Terraform Cloud token lacks permission")
+
+	// ErrNotFound is returned when a workspace or run does not exist, or the token cannot see it.
+	ErrNotFound = errors.New("not found in Terraform Cloud")
+
+	// ErrMissingPermission is returned when the token lacks a workspace permission that the job needs.
+	ErrMissingPermission = errors.New("the Terraform Cloud token lacks a required workspace permission")
+
+	// ErrLockConflict is returned when a workspace's state is locked by another operation.
+	ErrLockConflict = errors.New("the workspace state is locked")
+
+	// ErrRunConflict is returned when another operation got to a run or workspace first, such as a
+	// run that already finished or was cancelled, or a workspace that was already unlocked.
+	ErrRunConflict = errors.New("the run or workspace was changed by another operation")
+
+	// ErrCommandFailed is returned when terraform, tofu, or tfmigrate fails, times out, or is interrupted.
+	ErrCommandFailed = errors.New("command failed")
+
+	// ErrCommandTimeout is returned when a command runs for longer than the CommandTimeout.
+	ErrCommandTimeout = errors.New("command timed out")
+)
+
+// stateLockMessages are the messages terraform and tfmigrate print when the state is already locked.
+var stateLockMessages = []string{"Error acquiring the state lock", "workspace is already locked"}
+
+// APIError is returned when the Terraform Cloud API responds with an unsuccessful status code.
+type APIError struct {
+
+	// APIError is the unsuccessful request and the response to it.
+	tfcapi.APIError
+}
+
+// apiErrorHints explain the likely cause of an unsuccessful status code for a request.
+var apiErrorHints = map[string]map[int]string{
+	"getWorkspace": {
+		http.StatusNotFound: "check that the workspace exists in the organization and that the token can access it",
+	},
+	"getMostRecentRuns": {
+		http.StatusForbidden: "the token needs read-runs access to the workspace",
+		http.StatusNotFound:  "the token needs read-runs access to the workspace",
+	},
+	"discardRun": {
+		http.StatusForbidden: "the token needs apply access to the workspace to discard runs",
+		http.StatusConflict:  "the run may have already finished or been discarded",
+	},
+	"cancelRun": {
+		http.StatusForbidden: "the token needs apply access to the workspace to cancel runs",
+		http.StatusConflict:  "the run may have already finished or been cancelled",
+	},
+	"createPlanOnlyRefreshRun": {
+		http.StatusForbidden: "the token needs queue-run access to the workspace",
+		http.StatusNotFound:  "the token needs queue-run access to the workspace",
+	},
+	"getAccountDetails": {
+		http.StatusUnauthorized: "check that the Terraform Cloud token is valid and has not expired, and is a user or team token",
+	},
+	"forceUnlockWorkspace": {
+		http.StatusForbidden: "the token needs admin access to the workspace to force-unlock it",
+		http.StatusConflict:  "the workspace was unlocked by another operation",
+	},
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	return e.Message(apiErrorHints)
+}
+
+// Unwrap returns the sentinel error for the status code, so that errors.Is(err, ErrNotFound) and
+// similar identify the failure.
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusLocked:
+		return ErrLockConflict
+	case http.StatusConflict:
+		return ErrRunConflict
+	default:
+		return nil
+	}
+}
+
+// PermissionError is returned when the token lacks workspace permissions that the job needs.
+type PermissionError struct {
+
+	// Workspace is the name of the Terraform Cloud workspace.
+	Workspace string
+
+	// Permissions are the missing permissions, such as "can-queue-run".
+	Permissions []string
+}
+
+// Error implements the error interface.
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("the token lacks %v on workspace %v", strings.Join(e.Permissions, ", "), e.Workspace)
+}
+
+// Unwrap returns ErrMissingPermission.
+func (e *PermissionError) Unwrap() error {
+	return ErrMissingPermission
+}
+
+// RunError is returned when a Terraform Cloud run cannot be discarded, cancelled, or created.
+type RunError struct {
+
+	// RunID is the Terraform Cloud ID of the run, empty if the run was being created.
+	RunID string
+
+	// Action is what was being done to the run, such as "discard".
+	Action string
+
+	// Err is the cause of the failure.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *RunError) Error() string {
+	if e.RunID == "" {
+		return fmt.Sprintf("unable to %v run: %v", e.Action, e.Err)
+	}
+
+	return fmt.Sprintf("unable to %v run %v: %v", e.Action, e.RunID, e.Err)
+}
+
+// Unwrap returns the cause of the failure.
+func (e *RunError) Unwrap() error {
+	return e.Err
+}
+
+// CommandError is returned when a command exits unsuccessfully, times out, or is interrupted.
+type CommandError struct {
+
+	// Command is the command as it would be typed into a shell.
+	Command string
+
+	// ExitCode is the exit code of the command, or -1 if it did not exit normally.
+	ExitCode int
+
+	// Timeout is the timeout of the command, zero if there was none.
+	Timeout time.Duration
+
+	// Output is the trailing output of the command.
+	Output string
+
+	// Err is the cause of the failure: ErrCommandTimeout, context.Canceled, or the error from os/exec.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *CommandError) Error() string {
+	var reason string
+	switch {
+	case errors.Is(e.Err, ErrCommandTimeout):
+		reason = fmt.Sprintf("timed out after %v", e.Timeout)
+	case errors.Is(e.Err, context.Canceled):
+		reason = fmt.Sprintf("was interrupted: %v", e.Err)
+	case e.ExitCode >= 0:
+		reason = fmt.Sprintf("exited with code %v", e.ExitCode)
+	default:
+		reason = fmt.Sprintf("failed: %v", e.Err)
+	}
+
+	return fmt.Sprintf("`%v` %v\n\n%v", e.Command, reason, e.Output)
+}
+
+// Unwrap returns the cause of the failure.
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports every CommandError as ErrCommandFailed, and as ErrLockConflict when its output shows
+// that the state was already locked.
+func (e *CommandError) Is(target error) bool {
+	switch target {
+	case ErrCommandFailed:
+		return true
+	case ErrLockConflict:
+		for _, message := range stateLockMessages {
+			if strings.Contains(e.Output, message) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,141 @@
+package statemigration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/internal/tfcapi"
+)
+
+func TestAPIErrorSentinels(t *testing.T) {
+	testCases := []struct {
+		statusCode int
+		expected   error
+	}{
+		{statusCode: http.StatusUnauthorized, expected: ErrUnauthorized},
+		{statusCode: http.StatusForbidden, expected: ErrForbidden},
+		{statusCode: http.StatusNotFound, expected: ErrNotFound},
+		{statusCode: http.StatusConflict, expected: ErrRunConflict},
+		{statusCode: http.StatusLocked, expected: ErrLockConflict},
+	}
+
+	for _, testCase := range testCases {
+		err := fmt.Errorf("[sm.getWorkspaceID] %w", &APIError{APIError: tfcapi.APIError{Request: "getWorkspace", StatusCode: testCase.statusCode}})
+
+		if !errors.Is(err, testCase.expected) {
+			t.Errorf("expected status code %v to be %v", testCase.statusCode, testCase.expected)
+		}
+	}
+
+	err := fmt.Errorf("[sm.getWorkspaceID] %w", &APIError{APIError: tfcapi.APIError{Request: "getWorkspace", StatusCode: http.StatusInternalServerError}})
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected status code 500 to match no sentinel error")
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected errors.As to find the APIError, got %v", apiErr)
+	}
+}
+
+func TestRunErrorUnwrap(t *testing.T) {
+	err := fmt.Errorf("[sm.discardRun] %w", &RunError{
+		RunID:  "run-123",
+		Action: "discard",
+		Err:    &APIError{APIError: tfcapi.APIError{Request: "discardRun", StatusCode: http.StatusConflict}},
+	})
+
+	var runErr *RunError
+	if !errors.As(err, &runErr) || runErr.RunID != "run-123" {
+		t.Errorf("expected errors.As to find the RunError, got %v", runErr)
+	}
+
+	if !errors.Is(err, ErrRunConflict) || errors.Is(err, ErrLockConflict) {
+		t.Errorf("expected the RunError to unwrap to ErrRunConflict only")
+	}
+
+	expectedMessage := "[sm.discardRun] unable to discard run run-123: [discardRun] was unsuccessful, with the " +
+		"server returning: 409 (hint: the run may have already finished or been discarded)"
+	if err.Error() != expectedMessage {
+		t.Errorf("got %v, expected %v", err.Error(), expectedMessage)
+	}
+}
+
+func TestCommandErrorIs(t *testing.T) {
+	failed := &CommandError{Command: "tfmigrate plan", ExitCode: 1, Output: "Error: invalid address", Err: errors.New("exit status 1")}
+	locked := &CommandError{Command: "tfmigrate apply", ExitCode: 1, Output: "Error acquiring the state lock", Err: errors.New("exit status 1")}
+	timedOut := &CommandError{Command: "terraform init", ExitCode: -1, Err: ErrCommandTimeout}
+	interrupted := &CommandError{Command: "terraform init", ExitCode: -1, Err: context.Canceled}
+
+	if !errors.Is(failed, ErrCommandFailed) || errors.Is(failed, ErrLockConflict) || errors.Is(failed, ErrCommandTimeout) {
+		t.Errorf("expected a failed command to only be ErrCommandFailed")
+	}
+
+	if !errors.Is(locked, ErrLockConflict) || !errors.Is(locked, ErrCommandFailed) {
+		t.Errorf("expected a command that could not lock the state to be ErrLockConflict")
+	}
+
+	if !errors.Is(timedOut, ErrCommandTimeout) || !errors.Is(interrupted, context.Canceled) {
+		t.Errorf("expected timed out and interrupted commands to wrap their cause")
+	}
+
+	var commandErr *CommandError
+	if !errors.As(fmt.Errorf("[sm.runner.Run `tfmigrate`] %w", failed), &commandErr) || commandErr.ExitCode != 1 {
+		t.Errorf("expected errors.As to find the CommandError, got %v", commandErr)
+	}
+
+	expectedMessage := "`tfmigrate plan` exited with code 1\n\nError: invalid address"
+	if failed.Error() != expectedMessage {
+		t.Errorf("got %q, expected %q", failed.Error(), expectedMessage)
+	}
+}
+
+func TestTerraformCloudRequestAPIError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors": [{"status": "404", "title": "not found"}]}`))
+	})
+	_, httpClient := newTestTFCServer(t, mux)
+
+	sm := stateMigrator{
+		config:     &Config{TerraformCloudOrganization: "dragondrop-cloud", TerraformCloudToken: "example_token"},
+		httpClient: httpClient,
+	}
+
+	_, err := sm.getWorkspaceID(context.Background(), "missing_workspace")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an APIError, got %v", err)
+	}
+
+	expectedMessage := "[getWorkspace] GET /api/v2/organizations/dragondrop-cloud/workspaces/missing_workspace was " +
+		"unsuccessful, with the server returning: 404: not found (hint: check that the workspace exists in the " +
+		"organization and that the token can access it)"
+	if apiErr.Error() != expectedMessage {
+		t.Errorf("got %v, expected %v", apiErr.Error(), expectedMessage)
+	}
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the error to be ErrNotFound")
+	}
+}
+
+func TestAPIErrorHint(t *testing.T) {
+	err := &APIError{APIError: tfcapi.APIError{Request: "getMostRecentRuns", Method: "GET", Path: "/api/v2/workspaces/ws-123/runs", StatusCode: http.StatusUnauthorized}}
+
+	expectedMessage := "[getMostRecentRuns] GET /api/v2/workspaces/ws-123/runs was unsuccessful, with the server " +
+		"returning: 401 (hint: check that the Terraform Cloud token is valid and has not expired)"
+	if err.Error() != expectedMessage {
+		t.Errorf("got %v, expected %v", err.Error(), expectedMessage)
+	}
+
+	err = &APIError{APIError: tfcapi.APIError{Request: "getMostRecentRuns", StatusCode: http.StatusInternalServerError}}
+	if err.Hint(apiErrorHints) != "" {
+		t.Errorf("expected no hint for a 500, got %v", err.Hint(apiErrorHints))
+	}
+}
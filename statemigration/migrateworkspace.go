@@ -1,102 +1,215 @@
 package statemigration
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"os"
-	"os/exec"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/logging"
 )
 
-// MigrateAllWorkspaces runs migrations for all workspaces by coordinating calls to MigrateWorkspace.
-func (sm *stateMigrator) MigrateAllWorkspaces() error {
-	fmt.Println("Beginning to create all workspace variable files.")
-	err := sm.tfVar.CreateAllWorkspaceVarsFiles()
+// MigrateAllWorkspaces runs migrations for all workspaces by coordinating calls to MigrateWorkspace,
+// removing the workspace variable files written once done. The report covers each workspace
+// attempted, including the one that failed, if any, and is also written to the ReportFile when
+// configured.
+func (sm *stateMigrator) MigrateAllWorkspaces(ctx context.Context) (RunReport, error) {
+	report := sm.newRunReport()
+
+	err := sm.migrateAllWorkspaces(ctx, &report)
+	report.finish(err)
+
+	cleanupErr := sm.tfVar.Cleanup()
+	if cleanupErr != nil {
+		slog.Error("Unable to remove the workspace variable files.", "error", cleanupErr)
+	}
+
+	if sm.config.ReportFile != "" {
+		reportErr := report.WriteFile(sm.config.ReportFile)
+		if reportErr != nil {
+			slog.Error("Unable to write the run report.", "path", sm.config.ReportFile, "error", reportErr)
+		}
+	}
+
+	return report, err
+}
+
+// migrateAllWorkspaces creates variable files and migrates each workspace, recording what was done in report.
+func (sm *stateMigrator) migrateAllWorkspaces(ctx context.Context, report *RunReport) error {
+	err := sm.preflight(ctx)
+	if err != nil {
+		return fmt.Errorf("[sm.preflight] %w", err)
+	}
+
+	slog.Info("Beginning to create all workspace variable files.")
+	logging.StartGroup("Workspace variables")
+	variables, workspaceEnv, err := sm.tfVar.CreateAllWorkspaceVarsFiles(ctx)
+	logging.EndGroup()
 
 	if err != nil {
-		return fmt.Errorf("[sm.tfVar.CreateAllWorkspaceVarsFiles] %v", err)
+		return fmt.Errorf("[sm.tfVar.CreateAllWorkspaceVarsFiles] %w", err)
 	}
-	fmt.Println("Done creating workspace variable files.")
+	report.Variables = variables
+	sm.workspaceEnv = workspaceEnv
+	slog.Info("Done creating workspace variable files.")
+
+	for _, workspace := range sm.workspaces() {
+		directory := sm.config.WorkspaceToDirectory[workspace]
 
-	for workspace, directory := range sm.config.WorkspaceToDirectory {
-		if directory == "null" {
-			continue
+		if ctx.Err() != nil {
+			return fmt.Errorf("stopped before migrating the directory %v: %v", directory, ctx.Err())
 		}
 
-		fmt.Printf("Beginning to migrate the directory %v\n", directory)
-		err = sm.MigrateWorkspace(workspace, WorkspaceDirectory(directory))
+		slog.Info("Beginning to migrate the directory.", "workspace", workspace, "directory", directory)
+		result, err := sm.MigrateWorkspace(ctx, workspace, WorkspaceDirectory(directory))
+		report.Workspaces = append(report.Workspaces, result)
 		if err != nil {
-			return fmt.Errorf("[sm.MigrateWorkspace] Error migrating %v workspace: %v", directory, err)
+			return fmt.Errorf("[sm.MigrateWorkspace] Error migrating %v workspace: %w", directory, err)
 		}
-		fmt.Printf("Done migrating the directory %v\n", directory)
+		slog.Info("Done migrating the directory.", "workspace", workspace, "directory", directory)
 	}
 
-	fmt.Println("Done migrating all workspaces.")
+	slog.Info("Done migrating all workspaces.")
 	return nil
 }
 
 // MigrateWorkspace runs migrations for the workspace specified.
-func (sm *stateMigrator) MigrateWorkspace(workspace string, directory WorkspaceDirectory) error {
-	ctx := context.Background()
+func (sm *stateMigrator) MigrateWorkspace(ctx context.Context, workspace string, directory WorkspaceDirectory) (WorkspaceResult, error) {
+	planOrApply, _ := sm.BuildTFMigrateArgs()
+
+	result := WorkspaceResult{
+		Workspace:   workspace,
+		Directory:   string(directory),
+		Mode:        planOrApply,
+		StoppedRuns: []StoppedRun{},
+		Commands:    []CommandRecord{},
+		Operations:  []MigrationOperation{},
+	}
+
+	logging.StartGroup(workspaceGroupTitle(&result))
+	err := sm.migrateWorkspace(ctx, workspace, directory, &result)
+	logging.EndGroup()
 
-	err := os.Chdir(fmt.Sprintf("/github/workspace%v", string(directory)))
 	if err != nil {
-		return fmt.Errorf("[os.Chdir] %v", err)
+		result.Error = logging.Redact(err.Error())
+		sm.annotateFailure(&result)
+		return result, err
 	}
 
-	if sm.config.TerraformVersion != "" {
-		tfSwitchArgs := []string{string(sm.config.TerraformVersion)}
-		err = executeCommand("tfswitch", tfSwitchArgs...)
+	result.Success = true
+	return result, nil
+}
 
-		if err != nil {
-			return fmt.Errorf("[executeCommand `tfswitch`] %v", err)
-		}
-	} else {
-		err = executeCommand("tfswitch", []string{}...)
-		if err != nil {
-			return fmt.Errorf("[executeCommand `tfswitch`] %v", err)
-		}
+// migrateWorkspace runs migrations for the workspace specified, recording what was done in result.
+func (sm *stateMigrator) migrateWorkspace(ctx context.Context, workspace string, directory WorkspaceDirectory, result *WorkspaceResult) error {
+	workspaceDirectory := filepath.Join(sm.config.RootDirectory, string(directory))
+
+	terraformVersion, err := sm.resolveTerraformVersion(ctx, workspace, workspaceDirectory)
+	if err != nil {
+		return fmt.Errorf("[sm.resolveTerraformVersion] %w", err)
+	}
+	result.TerraformVersion = terraformVersion
+
+	slog.Info("Resolved version.", "workspace", workspace, "engine", sm.config.Engine, "version", terraformVersion)
+	terraformPath, err := sm.installer.Install(ctx, terraformVersion)
+
+	if err != nil {
+		return fmt.Errorf("[sm.installer.Install] %w", err)
 	}
 
+	commandEnv := sm.buildCommandEnv(workspace, terraformPath)
+
 	terraformInitArgs := []string{"init"}
-	err = executeCommand("terraform", terraformInitArgs...)
+	_, err = sm.runCommand(ctx, result, Command{
+		Name:    terraformPath,
+		Args:    terraformInitArgs,
+		Env:     commandEnv,
+		Dir:     workspaceDirectory,
+		Timeout: sm.config.CommandTimeout,
+	})
 
 	if err != nil {
-		return fmt.Errorf("[executeCommand `terraform init`] %v", err)
+		return fmt.Errorf("[sm.runner.Run `%v init`] %w", sm.config.Engine, err)
 	}
 
-	fmt.Printf("Running migrations for: %v", directory)
+	slog.Info("Running migrations.", "workspace", workspace, "directory", directory)
 
 	planOrApply, tfMigrateArgs := sm.BuildTFMigrateArgs()
 
 	workspaceID, err := sm.getWorkspaceID(ctx, workspace)
 	if err != nil {
-		return fmt.Errorf("[sm.getWorkspaceID] %v", err)
+		return fmt.Errorf("[sm.getWorkspaceID] %w", err)
 	}
 
+	var stoppedRuns []RunStatus
+
 	if planOrApply == "apply" {
-		err = sm.discardActiveRunsUnlockState(ctx, workspaceID)
+		stoppedRuns, err = sm.discardActiveRunsUnlockState(ctx, workspaceID)
+		result.StoppedRuns = stoppedRunsFromStatuses(stoppedRuns)
 		if err != nil {
-			return fmt.Errorf("[sm.clearPendingRunsAndUnlockState`] %v", err)
+			return fmt.Errorf("[sm.clearPendingRunsAndUnlockState`] %w", err)
 		}
 	}
 
-	err = executeCommand("tfmigrate", tfMigrateArgs...)
+	tfmigrateResult, err := sm.runCommand(ctx, result, Command{
+		Name:    "tfmigrate",
+		Args:    tfMigrateArgs,
+		Env:     commandEnv,
+		Dir:     workspaceDirectory,
+		Timeout: sm.config.CommandTimeout,
+	})
+
+	if err != nil {
+		if (ctx.Err() != nil || errors.Is(err, ErrCommandTimeout)) && planOrApply == "apply" {
+			sm.unwindInterruptedWorkspace(workspace, stoppedRuns)
+		}
+
+		errorFile, errorLine := failedMigrationLocation(workspaceDirectory, tfmigrateResult.Output)
+		if errorFile != "" {
+			result.ErrorFile = filepath.Join(string(directory), errorFile)
+			result.ErrorLine = errorLine
+		}
+		return fmt.Errorf("[sm.runner.Run `tfmigrate`] %w", err)
+	}
 
+	operations, err := executedOperations(workspaceDirectory, tfmigrateResult.Output)
 	if err != nil {
-		return fmt.Errorf("[executeCommand `tfmigrate`] %v", err)
+		slog.Warn("Unable to determine the tfmigrate operations run.", "workspace", workspace, "error", err)
+	} else if operations != nil {
+		result.Operations = operations
 	}
 
 	if planOrApply == "apply" {
-		err = sm.createPlanOnlyRefreshRun(ctx, workspaceID)
+		refreshRunID, err := sm.createPlanOnlyRefreshRun(ctx, workspaceID)
 		if err != nil {
-			return fmt.Errorf("[sm.createPlanOnlyRefreshRun`] %v", err)
+			return fmt.Errorf("[sm.createPlanOnlyRefreshRun`] %w", err)
 		}
+		result.RefreshRunID = refreshRunID
+		result.RefreshRunURL = sm.runURL(workspace, refreshRunID)
 	}
 
 	return nil
 }
 
+// runCommand runs a command, recording its exit code and duration in result.
+func (sm *stateMigrator) runCommand(ctx context.Context, result *WorkspaceResult, command Command) (CommandResult, error) {
+	recordedCommand := command
+	recordedCommand.Name = filepath.Base(command.Name)
+
+	logging.StartGroup(fmt.Sprintf("%v: %v", result.Workspace, recordedCommand))
+	commandResult, err := sm.runner.Run(ctx, command)
+	logging.StartGroup(workspaceGroupTitle(result))
+	result.Commands = append(result.Commands, CommandRecord{
+		Command:         recordedCommand.String(),
+		ExitCode:        commandResult.ExitCode,
+		DurationSeconds: commandResult.Duration.Seconds(),
+	})
+
+	return commandResult, err
+}
+
 // BuildTFMigrateArgs constructs a slice of strings for use within
 // a tfmigrate command
 func (sm *stateMigrator) BuildTFMigrateArgs() (string, []string) {
@@ -108,26 +221,27 @@ func (sm *stateMigrator) BuildTFMigrateArgs() (string, []string) {
 		tfMigrateCMD = "plan"
 	}
 
-	tfMigrateArgs := []string{tfMigrateCMD, "--config=./dragondrop/tfmigrate/.tfmigrate.hcl"}
+	tfMigrateArgs := []string{tfMigrateCMD, "--config=" + tfmigrateConfigPath}
 
 	return tfMigrateCMD, tfMigrateArgs
 }
 
-// executeCommand wraps os.exec.Command with capturing of std output and errors.
-func executeCommand(command string, args ...string) error {
-	cmd := exec.Command(command, args...)
-
-	// Setting up logging objects
-	var out bytes.Buffer
-	cmd.Stdout = &out
-
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	err := cmd.Run()
+// buildCommandEnv constructs the environment variables shared by the engine and tfmigrate
+// commands of a workspace, starting with the workspace's environment variables within Terraform
+// Cloud. tfmigrate runs the installed binary rather than whichever terraform is on the PATH, and
+// logs the migration files it runs.
+func (sm *stateMigrator) buildCommandEnv(workspace string, binaryPath string) []string {
+	return append(
+		sm.workspaceEnv[workspace].Environ(),
+		"TFMIGRATE_EXEC_PATH="+binaryPath,
+		"TFMIGRATE_LOG="+tfmigrateLogLevel,
+		tokenEnvVarName(terraformCloudHostname)+"="+sm.config.TerraformCloudToken,
+	)
+}
 
-	if err != nil {
-		return fmt.Errorf("%v\n\n%v", err, stderr.String()+out.String())
-	}
-	fmt.Printf("\n`%s %s` output:\n\n%v\n", command, args, out.String())
-	return nil
+// tokenEnvVarName returns the name of the environment variable from which the engine reads
+// the API token for a hostname. Terraform and OpenTofu both read TF_TOKEN_ variables, in which
+// periods become underscores and hyphens become double underscores.
+func tokenEnvVarName(hostname string) string {
+	return "TF_TOKEN_" + strings.NewReplacer(".", "_", "-", "__").Replace(hostname)
 }
@@ -1,10 +1,77 @@
 package statemigration
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/hashicorp/go-version"
+
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/tfvars"
 )
 
+// fakeInstaller implements the installer.Installer interface without downloading anything.
+type fakeInstaller struct{}
+
+func (fi fakeInstaller) Install(ctx context.Context, releaseVersion string) (string, error) {
+	return "/cache/terraform/" + releaseVersion + "/terraform", nil
+}
+
+func (fi fakeInstaller) ListVersions(ctx context.Context) ([]*version.Version, error) {
+	return []*version.Version{version.Must(version.NewVersion("1.5.7"))}, nil
+}
+
+// redirectTransport sends every request to a test server, regardless of the requested host.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt redirectTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	request.URL.Scheme = rt.target.Scheme
+	request.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(request)
+}
+
+// newTestTFCServer starts a stand-in for the Terraform Cloud API, returning it alongside an
+// http.Client that sends all requests to it.
+func newTestTFCServer(t *testing.T, mux *http.ServeMux) (*httptest.Server, http.Client) {
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	target, _ := url.Parse(server.URL)
+	return server, http.Client{Transport: redirectTransport{target: target}}
+}
+
+// newTestTFCMux creates a mux serving a single workspace with no active runs.
+func newTestTFCMux(requests *[]string) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.Method+" "+r.URL.Path)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/workspaces/workspace_1"):
+			_, _ = w.Write([]byte(`{"data": {"id": "ws-123", "attributes": {"terraform-version": "1.5.7"}}}`))
+		case strings.HasSuffix(r.URL.Path, "/runs") && r.Method == "GET":
+			_, _ = w.Write([]byte(`{"data": []}`))
+		case r.URL.Path == "/api/v2/runs" && r.Method == "POST":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"data": {"id": "run-456"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	return mux
+}
+
 func TestBuildTFMigrateArgs(t *testing.T) {
 	sm := stateMigrator{
 		config: &Config{
@@ -33,3 +100,306 @@ func TestBuildTFMigrateArgs(t *testing.T) {
 	}
 
 }
+
+func TestBuildCommandEnv(t *testing.T) {
+	sm := stateMigrator{
+		config: &Config{
+			Engine:              EngineTofu,
+			TerraformCloudToken: "example_token",
+		},
+		workspaceEnv: map[string]tfvars.VariableMap{
+			"workspace_1": {"AWS_REGION": "us-east-1"},
+			"workspace_2": {"AWS_REGION": "eu-west-1"},
+		},
+	}
+
+	output := sm.buildCommandEnv("workspace_1", "/cache/tofu/1.8.0/tofu")
+	expectedOutput := []string{
+		"AWS_REGION=us-east-1",
+		"TFMIGRATE_EXEC_PATH=/cache/tofu/1.8.0/tofu",
+		"TFMIGRATE_LOG=INFO",
+		"TF_TOKEN_app_terraform_io=example_token",
+	}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+
+	output = sm.buildCommandEnv("workspace_3", "/cache/tofu/1.8.0/tofu")
+	expectedOutput = []string{
+		"TFMIGRATE_EXEC_PATH=/cache/tofu/1.8.0/tofu",
+		"TFMIGRATE_LOG=INFO",
+		"TF_TOKEN_app_terraform_io=example_token",
+	}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+}
+
+func TestTokenEnvVarName(t *testing.T) {
+	output := tokenEnvVarName("tfe.my-company.example.com")
+	expectedOutput := "TF_TOKEN_tfe_my__company_example_com"
+
+	if output != expectedOutput {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+}
+
+func TestMigrateWorkspaceCommandSequence(t *testing.T) {
+	var requests []string
+	_, httpClient := newTestTFCServer(t, newTestTFCMux(&requests))
+
+	runner := &RecordingCommandRunner{}
+	sm := stateMigrator{
+		config: &Config{
+			TerraformCloudOrganization: "dragondrop-cloud",
+			Engine:                     EngineTerraform,
+			TerraformVersion:           "1.5.7",
+			IsApply:                    true,
+			RootDirectory:              "/github/workspace",
+		},
+		httpClient: httpClient,
+		runner:     runner,
+		installer:  fakeInstaller{},
+	}
+
+	_, err := sm.MigrateWorkspace(context.Background(), "workspace_1", "/directory_1/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedCommands := []string{
+		"terraform init",
+		"tfmigrate apply --config=./dragondrop/tfmigrate/.tfmigrate.hcl",
+	}
+
+	if !reflect.DeepEqual(runner.CommandStrings(), expectedCommands) {
+		t.Errorf("got %v, expected %v", runner.CommandStrings(), expectedCommands)
+	}
+
+	for _, command := range runner.Commands {
+		if command.Dir != "/github/workspace/directory_1" {
+			t.Errorf("expected %v to run within the workspace directory, got %v", command, command.Dir)
+		}
+	}
+
+	expectedRequests := []string{
+		"GET /api/v2/organizations/dragondrop-cloud/workspaces/workspace_1",
+		"GET /api/v2/workspaces/ws-123/runs",
+		"POST /api/v2/runs",
+	}
+
+	if !reflect.DeepEqual(requests, expectedRequests) {
+		t.Errorf("got %v, expected %v", requests, expectedRequests)
+	}
+}
+
+func TestMigrateWorkspaceStopsOnFailure(t *testing.T) {
+	var requests []string
+	_, httpClient := newTestTFCServer(t, newTestTFCMux(&requests))
+
+	runner := &RecordingCommandRunner{
+		Errors: map[string]error{"terraform": errors.New("init failed")},
+	}
+	sm := stateMigrator{
+		config: &Config{
+			Engine:           EngineTerraform,
+			TerraformVersion: "1.5.7",
+		},
+		httpClient: httpClient,
+		runner:     runner,
+		installer:  fakeInstaller{},
+	}
+
+	result, err := sm.MigrateWorkspace(context.Background(), "workspace_1", "/directory_1/")
+	if err == nil {
+		t.Fatalf("expected an error when terraform init fails")
+	}
+
+	if result.Success || !strings.Contains(result.Error, "init failed") {
+		t.Errorf("expected a failed result with the error, got %+v", result)
+	}
+
+	if !reflect.DeepEqual(runner.CommandStrings(), []string{"terraform init"}) {
+		t.Errorf("expected tfmigrate not to run after a failed init, got %v", runner.CommandStrings())
+	}
+}
+
+func TestMigrateWorkspaceUnwindsOnCancel(t *testing.T) {
+	lockHolders := map[string][]string{
+		"api-team_123": {
+			"GET /api/v2/organizations/dragondrop-cloud/workspaces/workspace_1",
+			"GET /api/v2/workspaces/ws-123/runs",
+			"GET /api/v2/organizations/dragondrop-cloud/workspaces/workspace_1",
+			"GET /api/v2/account/details",
+			"POST /api/v2/workspaces/ws-123/actions/force-unlock",
+		},
+		"someone-else": {
+			"GET /api/v2/organizations/dragondrop-cloud/workspaces/workspace_1",
+			"GET /api/v2/workspaces/ws-123/runs",
+			"GET /api/v2/organizations/dragondrop-cloud/workspaces/workspace_1",
+			"GET /api/v2/account/details",
+		},
+	}
+
+	runners := map[string]func(cancel context.CancelFunc) CommandRunner{
+		"cancelled": func(cancel context.CancelFunc) CommandRunner {
+			return &cancellingCommandRunner{cancel: cancel}
+		},
+		"timed out": func(cancel context.CancelFunc) CommandRunner {
+			return &RecordingCommandRunner{Errors: map[string]error{"tfmigrate": ErrCommandTimeout}}
+		},
+	}
+
+	for lockHolder, expectedRequests := range lockHolders {
+		for interruption, newRunner := range runners {
+			requests := migrateInterruptedWorkspace(t, lockHolder, newRunner)
+
+			if !reflect.DeepEqual(requests, expectedRequests) {
+				t.Errorf("%v with the lock held by %v: got %v, expected %v", interruption, lockHolder, requests, expectedRequests)
+			}
+		}
+	}
+}
+
+// migrateInterruptedWorkspace applies a migration to a workspace locked by lockHolder, with the
+// token belonging to "api-team_123", and returns the Terraform Cloud requests made.
+func migrateInterruptedWorkspace(t *testing.T, lockHolder string, newRunner func(cancel context.CancelFunc) CommandRunner) []string {
+	var requests []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/workspaces/workspace_1"):
+			_, _ = w.Write([]byte(fmt.Sprintf(`{
+  "data": {"id": "ws-123", "attributes": {"locked": true}, "relationships": {"locked-by": {"data": {"id": "user-1", "type": "users"}}}},
+  "included": [{"id": "user-1", "type": "users", "attributes": {"username": %q}}]
+}`, lockHolder)))
+		case strings.HasSuffix(r.URL.Path, "/account/details"):
+			_, _ = w.Write([]byte(`{"data": {"id": "user-1", "attributes": {"username": "api-team_123"}}}`))
+		case strings.HasSuffix(r.URL.Path, "/runs") && r.Method == "GET":
+			_, _ = w.Write([]byte(`{"data": []}`))
+		case strings.HasSuffix(r.URL.Path, "/actions/force-unlock"):
+			_, _ = w.Write([]byte(`{"data": {"id": "ws-123"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	_, httpClient := newTestTFCServer(t, mux)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sm := stateMigrator{
+		config: &Config{
+			TerraformCloudOrganization: "dragondrop-cloud",
+			Engine:                     EngineTerraform,
+			TerraformVersion:           "1.5.7",
+			IsApply:                    true,
+		},
+		httpClient: httpClient,
+		runner:     newRunner(cancel),
+		installer:  fakeInstaller{},
+	}
+
+	_, err := sm.MigrateWorkspace(ctx, "workspace_1", "/directory_1/")
+	if err == nil {
+		t.Fatalf("expected an error when the migration is interrupted")
+	}
+
+	return requests
+}
+
+// cancellingCommandRunner cancels its context when tfmigrate runs, as a SIGTERM would.
+type cancellingCommandRunner struct {
+	RecordingCommandRunner
+	cancel context.CancelFunc
+}
+
+func (r *cancellingCommandRunner) Run(ctx context.Context, command Command) (CommandResult, error) {
+	if command.Name == "tfmigrate" {
+		r.cancel()
+	}
+	return r.RecordingCommandRunner.Run(ctx, command)
+}
+
+func TestMigrateWorkspaceResult(t *testing.T) {
+	var requests []string
+	_, httpClient := newTestTFCServer(t, newTestTFCMux(&requests))
+
+	root := t.TempDir()
+	migrationDir := filepath.Join(root, "directory_1", "dragondrop", "tfmigrate")
+	err := os.MkdirAll(migrationDir, 0755)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files := map[string]string{
+		".tfmigrate.hcl": `tfmigrate {
+  migration_dir = "./dragondrop/tfmigrate"
+}`,
+		"1_applied_previously.hcl": `migration "state" "old" {
+  actions = ["rm aws_s3_bucket.old"]
+}`,
+		"2_move.hcl": `migration "state" "move" {
+  actions = [
+    "mv aws_s3_bucket.a aws_s3_bucket.b",
+    "import aws_s3_bucket.c c",
+  ]
+}`,
+	}
+	for fileName, content := range files {
+		err = os.WriteFile(filepath.Join(migrationDir, fileName), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	runner := &RecordingCommandRunner{
+		Outputs: map[string]string{
+			"tfmigrate": "[INFO] [runner] unapplied migration files: [2_move.hcl]\n[INFO] [runner] load migration file: dragondrop/tfmigrate/2_move.hcl",
+		},
+	}
+	sm := stateMigrator{
+		config: &Config{
+			TerraformCloudOrganization: "dragondrop-cloud",
+			Engine:                     EngineTerraform,
+			TerraformVersion:           "1.5.7",
+			IsApply:                    true,
+			RootDirectory:              root,
+		},
+		httpClient: httpClient,
+		runner:     runner,
+		installer:  fakeInstaller{},
+	}
+
+	output, err := sm.MigrateWorkspace(context.Background(), "workspace_1", "/directory_1/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedOutput := WorkspaceResult{
+		Workspace:        "workspace_1",
+		Directory:        "/directory_1/",
+		Mode:             "apply",
+		Success:          true,
+		TerraformVersion: "1.5.7",
+		StoppedRuns:      []StoppedRun{},
+		Commands: []CommandRecord{
+			{Command: "terraform init"},
+			{Command: "tfmigrate apply --config=./dragondrop/tfmigrate/.tfmigrate.hcl"},
+		},
+		Operations: []MigrationOperation{
+			{File: "2_move.hcl", Action: "mv aws_s3_bucket.a aws_s3_bucket.b"},
+			{File: "2_move.hcl", Action: "import aws_s3_bucket.c c"},
+		},
+		RefreshRunID:  "run-456",
+		RefreshRunURL: "https://app.terraform.io/app/dragondrop-cloud/workspaces/workspace_1/runs/run-456",
+	}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %+v, expected %+v", output, expectedOutput)
+	}
+}
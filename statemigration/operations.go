@@ -0,0 +1,141 @@
+package statemigration
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// tfmigrateLogLevel is the tfmigrate log level that includes the migration files being run,
+// from which the operations performed are determined.
+const tfmigrateLogLevel = "INFO"
+
+// MigrationOperation is a single state operation from a tfmigrate migration file, such as
+// "mv aws_s3_bucket.a aws_s3_bucket.b".
+type MigrationOperation struct {
+
+	// File is the name of the migration file containing the operation.
+	File string `json:"file"`
+
+	// Action is the tfmigrate action, such as "mv", "rm", or "import" with its arguments.
+	Action string `json:"action"`
+}
+
+// Command returns the tfmigrate command of the operation, such as "mv" or "xmv".
+func (o MigrationOperation) Command() string {
+	command, _, _ := strings.Cut(o.Action, " ")
+	return command
+}
+
+// executedOperations determines the operations tfmigrate ran within a workspace directory. tfmigrate
+// logs the path of each migration file it plans or applies, such as "dragondrop/tfmigrate/1.hcl", so
+// the actions of every migration file whose path appears as a whole word in its output are
+// returned, in file order.
+func executedOperations(workspaceDirectory string, tfmigrateOutput string) ([]MigrationOperation, error) {
+	migrationDir, err := readMigrationDir(filepath.Join(workspaceDirectory, tfmigrateConfigPath))
+	if err != nil {
+		return nil, fmt.Errorf("[readMigrationDir] %w", err)
+	}
+
+	fileNames, err := filepath.Glob(filepath.Join(workspaceDirectory, migrationDir, "*.hcl"))
+	if err != nil {
+		return nil, fmt.Errorf("[filepath.Glob] %w", err)
+	}
+	sort.Strings(fileNames)
+
+	loggedWords := outputWords(tfmigrateOutput)
+
+	var operations []MigrationOperation
+
+	for _, fileName := range fileNames {
+		if !isLoggedMigrationFile(loggedWords, migrationDir, fileName) {
+			continue
+		}
+		baseName := filepath.Base(fileName)
+
+		actions, err := readMigrationActions(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("[readMigrationActions] %w", err)
+		}
+
+		for _, action := range actions {
+			operations = append(operations, MigrationOperation{File: baseName, Action: action})
+		}
+	}
+
+	return operations, nil
+}
+
+// outputWords returns the whitespace-separated words of tfmigrate's output.
+func outputWords(tfmigrateOutput string) map[string]bool {
+	words := map[string]bool{}
+	for _, word := range strings.Fields(tfmigrateOutput) {
+		words[word] = true
+	}
+
+	return words
+}
+
+// isLoggedMigrationFile reports whether the path of a migration file within migrationDir, such as
+// "dragondrop/tfmigrate/1.hcl", is one of words, so that "1.hcl" is not mistaken for "11.hcl".
+// The tfmigrate config file is never a migration file.
+func isLoggedMigrationFile(words map[string]bool, migrationDir string, fileName string) bool {
+	baseName := filepath.Base(fileName)
+	if baseName == filepath.Base(tfmigrateConfigPath) {
+		return false
+	}
+
+	return words[filepath.Join(migrationDir, baseName)]
+}
+
+// readMigrationActions parses a tfmigrate migration file, returning its actions.
+func readMigrationActions(fileName string) ([]string, error) {
+	file, diags := hclparse.NewParser().ParseHCLFile(fileName)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("%v", diags.Error())
+	}
+
+	content, diags := file.Body.Content(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "migration", LabelNames: []string{"type", "name"}}},
+	})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("%v", diags.Error())
+	}
+
+	var actions []string
+
+	for _, block := range content.Blocks {
+		attributes, diags := block.Body.JustAttributes()
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("%v", diags.Error())
+		}
+
+		attribute, ok := attributes["actions"]
+		if !ok {
+			continue
+		}
+
+		value, diags := attribute.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("%v", diags.Error())
+		}
+
+		if !value.CanIterateElements() {
+			return nil, fmt.Errorf("%v: actions must be a list of strings", fileName)
+		}
+
+		for _, element := range value.AsValueSlice() {
+			if element.Type() != cty.String || element.IsNull() {
+				return nil, fmt.Errorf("%v: actions must be a list of strings", fileName)
+			}
+			actions = append(actions, element.AsString())
+		}
+	}
+
+	return actions, nil
+}
@@ -0,0 +1,86 @@
+package statemigration
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadMigrationActions(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "1_split.hcl")
+	err := os.WriteFile(fileName, []byte(`
+migration "multi_state" "split" {
+  from_dir = "./a"
+  to_dir   = "./b"
+  actions = [
+    "mv aws_s3_bucket.a aws_s3_bucket.a",
+    "mv aws_s3_bucket.b aws_s3_bucket.b",
+  ]
+}
+`), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output, err := readMigrationActions(fileName)
+	if err != nil {
+		t.Errorf("unexpected error in readMigrationActions: %v", err)
+	}
+
+	expectedOutput := []string{"mv aws_s3_bucket.a aws_s3_bucket.a", "mv aws_s3_bucket.b aws_s3_bucket.b"}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+}
+
+func TestExecutedOperations(t *testing.T) {
+	workspaceDirectory := t.TempDir()
+	migrationDir := filepath.Join(workspaceDirectory, "dragondrop", "tfmigrate")
+	err := os.MkdirAll(migrationDir, 0755)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files := map[string]string{
+		filepath.Join(migrationDir, ".tfmigrate.hcl"): `tfmigrate {
+  migration_dir = "./dragondrop/tfmigrate"
+}`,
+		filepath.Join(migrationDir, "a.hcl"):    `migration "state" "a" { actions = ["rm aws_s3_bucket.a"] }`,
+		filepath.Join(migrationDir, "data.hcl"): `migration "state" "data" { actions = ["rm aws_s3_bucket.data"] }`,
+		filepath.Join(migrationDir, "mv_a.hcl"): `migration "state" "mv_a" { actions = ["mv aws_s3_bucket.a aws_s3_bucket.b"] }`,
+	}
+	for fileName, content := range files {
+		err = os.WriteFile(fileName, []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	inputOutput := "[INFO] [runner] unapplied migration files: [data.hcl mv_a.hcl]\n" +
+		"[INFO] [runner] load migration file: dragondrop/tfmigrate/data.hcl\n" +
+		"[INFO] [runner] load migration file: dragondrop/tfmigrate/mv_a.hcl\n"
+
+	output, err := executedOperations(workspaceDirectory, inputOutput)
+	if err != nil {
+		t.Errorf("unexpected error in executedOperations: %v", err)
+	}
+
+	expectedOutput := []MigrationOperation{
+		{File: "data.hcl", Action: "rm aws_s3_bucket.data"},
+		{File: "mv_a.hcl", Action: "mv aws_s3_bucket.a aws_s3_bucket.b"},
+	}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+}
+
+func TestMigrationOperationCommand(t *testing.T) {
+	operation := MigrationOperation{File: "1.hcl", Action: "import aws_s3_bucket.a my-bucket"}
+
+	if operation.Command() != "import" {
+		t.Errorf("got %v, expected %v", operation.Command(), "import")
+	}
+}
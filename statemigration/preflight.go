@@ -0,0 +1,106 @@
+package statemigration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/logging"
+)
+
+// planPermissions are the workspace permissions needed to pull variables and run tfmigrate plan.
+var planPermissions = []string{"can-read-variables", "can-read-state-versions"}
+
+// applyPermissions are the workspace permissions needed to discard runs, run tfmigrate apply,
+// and queue the refresh-only run afterwards.
+var applyPermissions = append(append([]string{}, planPermissions...), "can-lock", "can-queue-run")
+
+// preflight checks that the token authenticates, that every workspace exists, and that the token
+// has the permissions the job needs on each, so that the job fails before any workspace is
+// migrated rather than part way through. Every problem found is returned together.
+func (sm *stateMigrator) preflight(ctx context.Context) error {
+	if sm.config.TerraformCloudToken == "null" {
+		slog.Warn("Job kicked off in test-mode (TerraformCloudToken == 'null').")
+		return nil
+	}
+
+	logging.StartGroup("Preflight checks")
+	defer logging.EndGroup()
+
+	username, err := sm.getAccountUsername(ctx)
+	if err != nil {
+		// Every other check would fail in the same way.
+		return fmt.Errorf("[sm.getAccountUsername] %w", err)
+	}
+	slog.Info("Authenticated with Terraform Cloud.", "username", username)
+
+	requiredPermissions := planPermissions
+	if sm.config.IsApply {
+		requiredPermissions = applyPermissions
+	}
+
+	var problems []error
+
+	for _, workspace := range sm.workspaces() {
+		err = sm.checkWorkspacePermissions(ctx, workspace, requiredPermissions)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("workspace %v: %w", workspace, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("preflight checks found %v problems:\n%w", len(problems), errors.Join(problems...))
+	}
+
+	slog.Info("Preflight checks passed.", "workspaces", len(sm.workspaces()))
+	return nil
+}
+
+// checkWorkspacePermissions checks that the workspace exists and that the token has each of the
+// required permissions on it.
+func (sm *stateMigrator) checkWorkspacePermissions(ctx context.Context, workspace string, requiredPermissions []string) error {
+	jsonResponseBytes, err := sm.getWorkspace(ctx, workspace)
+	if err != nil {
+		return fmt.Errorf("[sm.getWorkspace] %w", err)
+	}
+
+	permissions, err := extractWorkspacePermissions(jsonResponseBytes)
+	if err != nil {
+		return fmt.Errorf("[extractWorkspacePermissions] %w", err)
+	}
+
+	var missingPermissions []string
+	for _, permission := range requiredPermissions {
+		if !permissions[permission] {
+			missingPermissions = append(missingPermissions, permission)
+		}
+	}
+
+	if len(missingPermissions) > 0 {
+		return &PermissionError{Workspace: workspace, Permissions: missingPermissions}
+	}
+
+	return nil
+}
+
+// extractWorkspacePermissions is a helper function that uses the gabs library to pull out the
+// permissions block from a Terraform Cloud workspace response.
+func extractWorkspacePermissions(jsonBytes []byte) (map[string]bool, error) {
+	jsonParsed, err := gabs.ParseJSON(jsonBytes)
+	if err != nil {
+		return nil, fmt.Errorf("[extractWorkspacePermissions] error in parsing bytes array to json via 'gabs': %w", err)
+	}
+
+	if !jsonParsed.Exists("data", "attributes", "permissions") {
+		return nil, fmt.Errorf("[extractWorkspacePermissions] unable to find workspace permissions")
+	}
+
+	permissions := map[string]bool{}
+	for permission, value := range jsonParsed.Search("data", "attributes", "permissions").ChildrenMap() {
+		permissions[permission], _ = value.Data().(bool)
+	}
+
+	return permissions, nil
+}
@@ -0,0 +1,182 @@
+package statemigration
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestPreflightMux creates a mux serving account details and the given workspace responses.
+func newTestPreflightMux(accountStatus int, workspaces map[string]string) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/account/details", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(accountStatus)
+		_, _ = w.Write([]byte(`{"data": {"attributes": {"username": "api-team_123"}}}`))
+	})
+	mux.HandleFunc("/api/v2/organizations/dragondrop-cloud/workspaces/", func(w http.ResponseWriter, r *http.Request) {
+		workspace := strings.TrimPrefix(r.URL.Path, "/api/v2/organizations/dragondrop-cloud/workspaces/")
+		body, ok := workspaces[workspace]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors": [{"status": "404", "title": "not found"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	})
+	return mux
+}
+
+func TestExtractWorkspacePermissions(t *testing.T) {
+	jsonBytes := []byte(`{"data": {"id": "ws-123", "attributes": {"permissions": {
+		"can-read-variables": true, "can-read-state-versions": true, "can-lock": false, "can-update": "yes"
+	}}}}`)
+
+	output, err := extractWorkspacePermissions(jsonBytes)
+	if err != nil {
+		t.Errorf("unexpected error in extractWorkspacePermissions: %v", err)
+	}
+
+	expectedOutput := map[string]bool{
+		"can-read-variables":      true,
+		"can-read-state-versions": true,
+		"can-lock":                false,
+		"can-update":              false,
+	}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+
+	_, err = extractWorkspacePermissions([]byte(`{"data": {"id": "ws-123", "attributes": {}}}`))
+	if err == nil {
+		t.Errorf("expected an error for a workspace without a permissions block")
+	}
+}
+
+func TestExtractAccountUsername(t *testing.T) {
+	output, err := extractAccountUsername([]byte(`{"data": {"id": "user-123", "attributes": {"username": "api-team_123"}}}`))
+	if err != nil {
+		t.Errorf("unexpected error in extractAccountUsername: %v", err)
+	}
+
+	if output != "api-team_123" {
+		t.Errorf("got %v, expected %v", output, "api-team_123")
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	allPermissions := `{"data": {"id": "ws-1", "attributes": {"permissions": {"can-read-variables": true,
+		"can-read-state-versions": true, "can-lock": true, "can-queue-run": true}}}}`
+	readOnlyPermissions := `{"data": {"id": "ws-2", "attributes": {"permissions": {"can-read-variables": true,
+		"can-read-state-versions": true, "can-lock": false, "can-queue-run": false}}}}`
+
+	testCases := []struct {
+		name           string
+		isApply        bool
+		workspaces     map[string]string
+		expectedErrors []string
+	}{
+		{
+			name:    "plan only needs read permissions",
+			isApply: false,
+			workspaces: map[string]string{
+				"workspace_1": allPermissions,
+				"workspace_2": readOnlyPermissions,
+				"workspace_3": allPermissions,
+			},
+		},
+		{
+			name:    "apply needs lock and queue-run, and every problem is reported",
+			isApply: true,
+			workspaces: map[string]string{
+				"workspace_1": allPermissions,
+				"workspace_2": readOnlyPermissions,
+			},
+			expectedErrors: []string{
+				"workspace workspace_2: the token lacks can-lock, can-queue-run on workspace workspace_2",
+				"workspace workspace_3: [sm.getWorkspace] [getWorkspace] GET",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, httpClient := newTestTFCServer(t, newTestPreflightMux(http.StatusOK, testCase.workspaces))
+
+			sm := stateMigrator{
+				config: &Config{
+					TerraformCloudOrganization: "dragondrop-cloud",
+					TerraformCloudToken:        "example_token",
+					IsApply:                    testCase.isApply,
+					WorkspaceToDirectory: map[string]string{
+						"workspace_1": "/workspace_1/",
+						"workspace_2": "/workspace_2/",
+						"workspace_3": "/workspace_3/",
+					},
+				},
+				httpClient: httpClient,
+			}
+
+			err := sm.preflight(context.Background())
+
+			if len(testCase.expectedErrors) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error in preflight: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected preflight to fail")
+			}
+			for _, expectedError := range testCase.expectedErrors {
+				if !strings.Contains(err.Error(), expectedError) {
+					t.Errorf("got %v, expected it to contain %v", err, expectedError)
+				}
+			}
+			if !errors.Is(err, ErrMissingPermission) || !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected %v to be both ErrMissingPermission and ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestPreflightUnauthorized(t *testing.T) {
+	_, httpClient := newTestTFCServer(t, newTestPreflightMux(http.StatusUnauthorized, nil))
+
+	sm := stateMigrator{
+		config: &Config{
+			TerraformCloudOrganization: "dragondrop-cloud",
+			TerraformCloudToken:        "example_token",
+			WorkspaceToDirectory:       map[string]string{"workspace_1": "/workspace_1/"},
+		},
+		httpClient: httpClient,
+	}
+
+	err := sm.preflight(context.Background())
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("got %v, expected %v", err, ErrUnauthorized)
+	}
+}
+
+func TestPreflightTestMode(t *testing.T) {
+	// In test mode, Terraform Cloud is never contacted, so every request would fail.
+	_, httpClient := newTestTFCServer(t, newTestPreflightMux(http.StatusUnauthorized, nil))
+
+	sm := stateMigrator{
+		config: &Config{
+			TerraformCloudOrganization: "dragondrop-cloud",
+			TerraformCloudToken:        "null",
+			WorkspaceToDirectory:       map[string]string{"workspace_1": "/workspace_1/"},
+		},
+		httpClient: httpClient,
+	}
+
+	err := sm.preflight(context.Background())
+	if err != nil {
+		t.Errorf("unexpected error in test mode: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package statemigration
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+)
+
+// RecordingCommandRunner implements the CommandRunner interface by recording each command
+// instead of executing it, for use in tests.
+type RecordingCommandRunner struct {
+
+	// mutex guards Commands.
+	mutex sync.Mutex
+
+	// Commands are the commands run so far, in order.
+	Commands []Command
+
+	// Errors maps an executable's base name, such as "tfmigrate", to the error returned
+	// when it is run.
+	Errors map[string]error
+
+	// Outputs maps an executable's base name to the output returned when it is run.
+	Outputs map[string]string
+}
+
+// Run records the command, returning the error configured for its executable if any.
+func (r *RecordingCommandRunner) Run(ctx context.Context, command Command) (CommandResult, error) {
+	r.mutex.Lock()
+	r.Commands = append(r.Commands, command)
+	r.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return CommandResult{ExitCode: -1}, err
+	}
+
+	output := r.Outputs[filepath.Base(command.Name)]
+
+	if err, ok := r.Errors[filepath.Base(command.Name)]; ok {
+		return CommandResult{ExitCode: 1, Output: output}, err
+	}
+
+	return CommandResult{Output: output}, nil
+}
+
+// CommandStrings renders each recorded command, with executable paths reduced to their base name.
+func (r *RecordingCommandRunner) CommandStrings() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var output []string
+	for _, command := range r.Commands {
+		command.Name = filepath.Base(command.Name)
+		output = append(output, command.String())
+	}
+
+	return output
+}
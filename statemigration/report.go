@@ -0,0 +1,145 @@
+package statemigration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/logging"
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/tfvars"
+)
+
+// redactedValue replaces secrets within a RunReport.
+const redactedValue = "[REDACTED]"
+
+// RunReport is a machine-readable record of a migration job, covering every workspace attempted.
+type RunReport struct {
+
+	// StartedAt is when the job began.
+	StartedAt time.Time `json:"started_at"`
+
+	// FinishedAt is when the job ended.
+	FinishedAt time.Time `json:"finished_at"`
+
+	// Success is whether every workspace was migrated without error.
+	Success bool `json:"success"`
+
+	// Error is the error message if the job failed.
+	Error string `json:"error,omitempty"`
+
+	// Config is the resolved configuration of the job, with secrets redacted.
+	Config ReportConfig `json:"config"`
+
+	// Variables are the names of the variables sourced for each workspace.
+	Variables map[string]tfvars.SourcedVariables `json:"variables"`
+
+	// Workspaces are the results of each workspace attempted.
+	Workspaces []WorkspaceResult `json:"workspaces"`
+}
+
+// ReportConfig is the configuration of a job as included in a RunReport, with secrets redacted.
+type ReportConfig struct {
+
+	// TerraformCloudOrganization is the name of the terraform cloud organization.
+	TerraformCloudOrganization string `json:"terraform_cloud_organization"`
+
+	// TerraformCloudToken is redactedValue when a token is set.
+	TerraformCloudToken string `json:"terraform_cloud_token"`
+
+	// Engine is the binary used to run migrations.
+	Engine Engine `json:"engine"`
+
+	// TerraformVersion is the version requested, before being resolved for each workspace.
+	TerraformVersion Version `json:"terraform_version"`
+
+	// IsApply is whether tfmigrate apply was run rather than tfmigrate plan.
+	IsApply bool `json:"is_apply"`
+
+	// WorkspaceToDirectory is a map between workspace name and the relative directory for a workspace's configuration.
+	WorkspaceToDirectory map[string]string `json:"workspace_to_directory"`
+
+	// RootDirectory is the directory that workspace directories are relative to.
+	RootDirectory string `json:"root_directory"`
+
+	// CommandTimeout is the maximum duration of each command.
+	CommandTimeout string `json:"command_timeout"`
+
+	// ShutdownGracePeriod is how long a command is given to exit after SIGTERM.
+	ShutdownGracePeriod string `json:"shutdown_grace_period"`
+}
+
+// StoppedRun is a Terraform Cloud run that was discarded or cancelled so that migrations could be applied.
+type StoppedRun struct {
+
+	// ID is the Terraform Cloud ID of the run.
+	ID string `json:"id"`
+
+	// Status is the status of the run before it was stopped.
+	Status string `json:"status"`
+
+	// Action is how the run was stopped, either "discarded" or "cancelled".
+	Action string `json:"action"`
+}
+
+// CommandRecord describes a command run for a workspace.
+type CommandRecord struct {
+
+	// Command is the command as it would be typed into a shell.
+	Command string `json:"command"`
+
+	// ExitCode is the exit code of the command, or -1 if it did not exit normally.
+	ExitCode int `json:"exit_code"`
+
+	// DurationSeconds is how long the command ran for.
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// newRunReport instantiates a RunReport for a job starting now.
+func (sm *stateMigrator) newRunReport() RunReport {
+	token := ""
+	if sm.config.TerraformCloudToken != "" {
+		token = redactedValue
+	}
+
+	return RunReport{
+		StartedAt: time.Now().UTC(),
+		Config: ReportConfig{
+			TerraformCloudOrganization: sm.config.TerraformCloudOrganization,
+			TerraformCloudToken:        token,
+			Engine:                     sm.config.Engine,
+			TerraformVersion:           sm.config.TerraformVersion,
+			IsApply:                    sm.config.IsApply,
+			WorkspaceToDirectory:       sm.config.WorkspaceToDirectory,
+			RootDirectory:              sm.config.RootDirectory,
+			CommandTimeout:             sm.config.CommandTimeout.String(),
+			ShutdownGracePeriod:        sm.config.ShutdownGracePeriod.String(),
+		},
+		Variables:  map[string]tfvars.SourcedVariables{},
+		Workspaces: []WorkspaceResult{},
+	}
+}
+
+// finish records the end of the job and its outcome.
+func (r *RunReport) finish(err error) {
+	r.FinishedAt = time.Now().UTC()
+	r.Success = err == nil
+	if err != nil {
+		r.Error = logging.Redact(err.Error())
+	}
+}
+
+// WriteFile writes the report as indented JSON, readable only by the current user.
+func (r *RunReport) WriteFile(fileName string) error {
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("[json.MarshalIndent] %w", err)
+	}
+
+	err = os.WriteFile(fileName, append(content, '\n'), 0600)
+	if err != nil {
+		return fmt.Errorf("[os.WriteFile] %w", err)
+	}
+
+	return nil
+}
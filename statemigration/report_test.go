@@ -0,0 +1,97 @@
+package statemigration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewRunReportRedactsToken(t *testing.T) {
+	sm := stateMigrator{
+		config: &Config{
+			TerraformCloudOrganization: "dragondrop-cloud",
+			TerraformCloudToken:        "secret-token",
+			Engine:                     EngineTerraform,
+			CommandTimeout:             30 * time.Minute,
+			ShutdownGracePeriod:        5 * time.Second,
+		},
+	}
+
+	report := sm.newRunReport()
+	report.finish(fmt.Errorf("migration failed"))
+
+	if report.Config.TerraformCloudToken != redactedValue {
+		t.Errorf("got %v, expected %v", report.Config.TerraformCloudToken, redactedValue)
+	}
+
+	if report.Config.CommandTimeout != "30m0s" {
+		t.Errorf("got %v, expected %v", report.Config.CommandTimeout, "30m0s")
+	}
+
+	if report.Success || report.Error != "migration failed" {
+		t.Errorf("got success %v and error %v, expected a failed report", report.Success, report.Error)
+	}
+}
+
+func TestRunReportWriteFile(t *testing.T) {
+	sm := stateMigrator{
+		config: &Config{
+			TerraformCloudOrganization: "dragondrop-cloud",
+			TerraformCloudToken:        "secret-token",
+		},
+	}
+
+	report := sm.newRunReport()
+	report.Workspaces = append(report.Workspaces, WorkspaceResult{
+		Workspace: "workspace_1",
+		Directory: "directory_1",
+		Mode:      "plan",
+		Success:   true,
+		StoppedRuns: stoppedRunsFromStatuses([]RunStatus{
+			{isDiscardable: true, runID: "run-1", status: "planned"},
+			{isCancelable: true, runID: "run-2", status: "planning"},
+		}),
+		Commands:   []CommandRecord{{Command: "terraform init", DurationSeconds: 1.5}},
+		Operations: []MigrationOperation{},
+	})
+	report.finish(nil)
+
+	fileName := filepath.Join(t.TempDir(), "report.json")
+	err := report.WriteFile(fileName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Contains(string(content), "secret-token") {
+		t.Errorf("expected the token to be redacted from %v", string(content))
+	}
+
+	var output RunReport
+	err = json.Unmarshal(content, &output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedStoppedRuns := []StoppedRun{
+		{ID: "run-1", Status: "planned", Action: "discarded"},
+		{ID: "run-2", Status: "planning", Action: "cancelled"},
+	}
+
+	if !reflect.DeepEqual(output.Workspaces[0].StoppedRuns, expectedStoppedRuns) {
+		t.Errorf("got %v, expected %v", output.Workspaces[0].StoppedRuns, expectedStoppedRuns)
+	}
+
+	if !output.Success || !output.FinishedAt.Equal(report.FinishedAt) {
+		t.Errorf("got %+v, expected %+v", output, report)
+	}
+}
@@ -0,0 +1,69 @@
+package statemigration
+
+import "fmt"
+
+// WorkspaceResult describes the outcome of migrating a single workspace.
+type WorkspaceResult struct {
+
+	// Workspace is the name of the Terraform Cloud workspace.
+	Workspace string `json:"workspace"`
+
+	// Directory is the workspace's directory, relative to the root directory.
+	Directory string `json:"directory"`
+
+	// Mode is the tfmigrate command that was run, either "plan" or "apply".
+	Mode string `json:"mode"`
+
+	// Success is whether the workspace was migrated without error.
+	Success bool `json:"success"`
+
+	// Error is the error message if the migration failed.
+	Error string `json:"error,omitempty"`
+
+	// ErrorFile is the file that caused the failure, relative to the root directory, if known.
+	ErrorFile string `json:"error_file,omitempty"`
+
+	// ErrorLine is the line within ErrorFile that caused the failure, if known.
+	ErrorLine int `json:"error_line,omitempty"`
+
+	// TerraformVersion is the Terraform or OpenTofu version used for the workspace.
+	TerraformVersion string `json:"terraform_version,omitempty"`
+
+	// StoppedRuns are the runs discarded or cancelled before migrations were applied.
+	StoppedRuns []StoppedRun `json:"stopped_runs"`
+
+	// Commands are the commands run for the workspace, in order.
+	Commands []CommandRecord `json:"commands"`
+
+	// Operations are the tfmigrate operations that were planned or applied.
+	Operations []MigrationOperation `json:"operations"`
+
+	// RefreshRunID is the ID of the refresh-only run created after an apply.
+	RefreshRunID string `json:"refresh_run_id,omitempty"`
+
+	// RefreshRunURL is the Terraform Cloud URL of the refresh-only run created after an apply.
+	RefreshRunURL string `json:"refresh_run_url,omitempty"`
+}
+
+// runURL returns the Terraform Cloud URL at which a workspace's run can be viewed.
+func (sm *stateMigrator) runURL(workspace string, runID string) string {
+	return fmt.Sprintf(
+		"https://%v/app/%v/workspaces/%v/runs/%v",
+		terraformCloudHostname, sm.config.TerraformCloudOrganization, workspace, runID,
+	)
+}
+
+// stoppedRunsFromStatuses describes the runs that discardActiveRunsUnlockState stopped.
+func stoppedRunsFromStatuses(runStatuses []RunStatus) []StoppedRun {
+	stoppedRuns := []StoppedRun{}
+	for _, runStatus := range runStatuses {
+		action := "cancelled"
+		if runStatus.isDiscardable {
+			action = "discarded"
+		}
+
+		stoppedRuns = append(stoppedRuns, StoppedRun{ID: runStatus.runID, Status: runStatus.status, Action: action})
+	}
+
+	return stoppedRuns
+}
@@ -0,0 +1,133 @@
+package statemigration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+)
+
+// unwindTimeout bounds the Terraform Cloud requests made while unwinding an interrupted workspace.
+const unwindTimeout = 30 * time.Second
+
+// WorkspaceLock describes which, if anything, holds a Terraform Cloud workspace's state lock.
+type WorkspaceLock struct {
+
+	// locked is whether the workspace is currently locked.
+	locked bool
+
+	// lockedByType is the type of resource holding the lock, such as "runs" or "users".
+	lockedByType string
+
+	// lockedByUsername is the username of the user holding the lock, if the lock is held by a user.
+	lockedByUsername string
+}
+
+// unwindInterruptedWorkspace restores a workspace after tfmigrate apply was interrupted or timed
+// out. tfmigrate is not given a chance to release its state lock, so a lock held by the token's own
+// account is force-unlocked, while a lock held by anyone else is left in place. Only user accounts
+// can be matched against the token's own, so a lock held by a team or organization, or one checked
+// with a token without account details, is left in place with a warning naming the workspace. Runs
+// discarded before the migration cannot be restored, so their IDs are logged for follow up.
+func (sm *stateMigrator) unwindInterruptedWorkspace(workspace string, stoppedRuns []RunStatus) {
+	ctx, cancel := context.WithTimeout(context.Background(), unwindTimeout)
+	defer cancel()
+
+	slog.Warn("Migration was interrupted, cleaning up.", "workspace", workspace)
+
+	if len(stoppedRuns) > 0 {
+		var runIDs []string
+		for _, run := range stoppedRuns {
+			runIDs = append(runIDs, run.runID)
+		}
+		slog.Warn("Runs were discarded or cancelled before the migration and may need to be re-queued.", "workspace", workspace, "runs", strings.Join(runIDs, ", "))
+	}
+
+	workspaceID, lock, err := sm.getWorkspaceLock(ctx, workspace)
+	if err != nil {
+		slog.Error("Unable to check the state lock.", "workspace", workspace, "error", err)
+		return
+	}
+
+	if !lock.locked || lock.lockedByType == "runs" {
+		return
+	}
+
+	if lock.lockedByType != "users" {
+		slog.Warn("The state lock is not held by a user account and cannot be matched to this token, leaving it in place. Unlock it manually if it was taken by this job.", "workspace", workspace, "lockedByType", lock.lockedByType)
+		return
+	}
+
+	username, err := sm.getAccountUsername(ctx)
+	if err != nil {
+		slog.Warn("Unable to check whether the state lock is held by this token, leaving it in place. Unlock it manually if it was taken by this job.", "workspace", workspace, "error", err)
+		return
+	}
+
+	if lock.lockedByUsername != username {
+		slog.Warn("The state lock is held by another user, leaving it in place.", "workspace", workspace, "lockedBy", lock.lockedByUsername)
+		return
+	}
+
+	err = sm.forceUnlockWorkspace(ctx, workspaceID)
+	if err != nil {
+		slog.Error("Unable to release the state lock, it must be unlocked manually.", "workspace", workspace, "error", err)
+		return
+	}
+
+	slog.Info("Released the state lock.", "workspace", workspace)
+}
+
+// extractWorkspaceLock is a helper function that uses the gabs library to pull out the lock
+// status of a workspace from a Terraform Cloud API response.
+func extractWorkspaceLock(jsonBytes []byte) (WorkspaceLock, error) {
+	jsonParsed, err := gabs.ParseJSON(jsonBytes)
+	if err != nil {
+		return WorkspaceLock{}, fmt.Errorf("[extractWorkspaceLock] error in parsing bytes array to json via 'gabs': %w", err)
+	}
+
+	locked, ok := jsonParsed.Path("data.attributes.locked").Data().(bool)
+	if !ok {
+		return WorkspaceLock{}, fmt.Errorf("[extractWorkspaceLock] unable to find workspace lock status")
+	}
+
+	lockedByType, _ := jsonParsed.Path("data.relationships.locked-by.data.type").Data().(string)
+	lockedByID, _ := jsonParsed.Path("data.relationships.locked-by.data.id").Data().(string)
+
+	var lockedByUsername string
+	if lockedByType == "users" {
+		for _, included := range jsonParsed.Path("included").Children() {
+			if included.Path("type").Data() == "users" && included.Path("id").Data() == lockedByID {
+				lockedByUsername, _ = included.Path("attributes.username").Data().(string)
+			}
+		}
+	}
+
+	return WorkspaceLock{
+		locked:           locked,
+		lockedByType:     lockedByType,
+		lockedByUsername: lockedByUsername,
+	}, nil
+}
+
+// forceUnlockWorkspace releases a workspace's state lock regardless of who holds it.
+func (sm *stateMigrator) forceUnlockWorkspace(ctx context.Context, workspaceID string) error {
+	requestName := "forceUnlockWorkspace"
+	requestPath := fmt.Sprintf("https://app.terraform.io/api/v2/workspaces/%v/actions/force-unlock", workspaceID)
+
+	request, err := sm.buildTFCloudHTTPRequest(ctx, requestName, "POST", requestPath, nil)
+
+	if err != nil {
+		return fmt.Errorf("[%v] error in newRequest: %w", requestName, err)
+	}
+
+	_, err = sm.terraformCloudRequest(request, requestName)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
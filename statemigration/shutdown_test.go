@@ -0,0 +1,127 @@
+package statemigration
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractWorkspaceLock(t *testing.T) {
+	inputJSON := []byte(`{
+  "data": {
+    "id": "ws-123",
+    "attributes": {"locked": true},
+    "relationships": {"locked-by": {"data": {"id": "run-456", "type": "runs"}}}
+  }
+}`)
+
+	output, err := extractWorkspaceLock(inputJSON)
+	if err != nil {
+		t.Errorf("unexpected error in extractWorkspaceLock: %v", err)
+	}
+
+	expectedOutput := WorkspaceLock{locked: true, lockedByType: "runs"}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+
+	output, err = extractWorkspaceLock([]byte(`{"data": {"id": "ws-123", "attributes": {"locked": false}}}`))
+	if err != nil {
+		t.Errorf("unexpected error in extractWorkspaceLock: %v", err)
+	}
+
+	expectedOutput = WorkspaceLock{locked: false}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+
+	output, err = extractWorkspaceLock([]byte(`{
+  "data": {
+    "id": "ws-123",
+    "attributes": {"locked": true},
+    "relationships": {"locked-by": {"data": {"id": "user-789", "type": "users"}}}
+  },
+  "included": [
+    {"id": "user-111", "type": "users", "attributes": {"username": "someone-else"}},
+    {"id": "user-789", "type": "users", "attributes": {"username": "api-team_123"}}
+  ]
+}`))
+	if err != nil {
+		t.Errorf("unexpected error in extractWorkspaceLock: %v", err)
+	}
+
+	expectedOutput = WorkspaceLock{locked: true, lockedByType: "users", lockedByUsername: "api-team_123"}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+
+	_, err = extractWorkspaceLock([]byte(`{"data": {"id": "ws-123"}}`))
+	if err == nil {
+		t.Errorf("expected an error when the lock status is missing")
+	}
+}
+
+func TestUnwindInterruptedWorkspaceUnmatchedLock(t *testing.T) {
+	defaultLogger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	lockHolders := map[string]struct {
+		lockedBy       string
+		accountDetails int
+	}{
+		"team":               {lockedBy: `{"id": "team-1", "type": "teams"}`, accountDetails: http.StatusOK},
+		"organization token": {lockedBy: `{"id": "user-1", "type": "users"}`, accountDetails: http.StatusNotFound},
+	}
+
+	for name, lockHolder := range lockHolders {
+		var requests []string
+		mux := http.NewServeMux()
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+
+			switch {
+			case strings.HasSuffix(r.URL.Path, "/workspaces/workspace_1"):
+				_, _ = w.Write([]byte(`{
+  "data": {"id": "ws-123", "attributes": {"locked": true}, "relationships": {"locked-by": {"data": ` + lockHolder.lockedBy + `}}},
+  "included": [{"id": "user-1", "type": "users", "attributes": {"username": "api-org-123"}}]
+}`))
+			case strings.HasSuffix(r.URL.Path, "/account/details"):
+				w.WriteHeader(lockHolder.accountDetails)
+				_, _ = w.Write([]byte(`{"data": {"id": "user-2", "attributes": {"username": "api-team_123"}}}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+		_, httpClient := newTestTFCServer(t, mux)
+
+		var output bytes.Buffer
+		slog.SetDefault(slog.New(slog.NewTextHandler(&output, nil)))
+
+		sm := stateMigrator{
+			config:     &Config{TerraformCloudOrganization: "dragondrop-cloud"},
+			httpClient: httpClient,
+		}
+
+		sm.unwindInterruptedWorkspace("workspace_1", nil)
+
+		for _, request := range requests {
+			if strings.HasSuffix(request, "/actions/force-unlock") {
+				t.Errorf("%v: expected the state lock to be left in place, got %v", name, requests)
+			}
+		}
+
+		if !strings.Contains(output.String(), "level=WARN") || !strings.Contains(output.String(), "leaving it in place") {
+			t.Errorf("%v: expected a warning that the state lock is left in place, got %v", name, output.String())
+		}
+
+		if !strings.Contains(output.String(), "workspace=workspace_1") {
+			t.Errorf("%v: expected the warning to name the workspace, got %v", name, output.String())
+		}
+	}
+}
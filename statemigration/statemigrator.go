@@ -1,9 +1,11 @@
 package statemigration
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/installer"
 	"github.com/dragondrop-cloud/github-action-tfstate-migration/tfvars"
 )
 
@@ -14,10 +16,18 @@ type WorkspaceDirectory string
 type StateMigrator interface {
 
 	// MigrateAllWorkspaces runs migrations for all workspaces by coordinating calls to MigrateWorkspace.
-	MigrateAllWorkspaces() error
+	// Cancelling ctx stops the current command and unwinds the current workspace before returning.
+	// A report of the job, covering each workspace attempted, is returned even when an error occurs.
+	MigrateAllWorkspaces(ctx context.Context) (RunReport, error)
 
 	// MigrateWorkspace runs migrations for the workspace specified.
-	MigrateWorkspace(workspace string, directory WorkspaceDirectory) error
+	MigrateWorkspace(ctx context.Context, workspace string, directory WorkspaceDirectory) (WorkspaceResult, error)
+
+	// WorkspaceStatuses gets the state lock and active runs of every workspace.
+	WorkspaceStatuses(ctx context.Context) ([]WorkspaceStatus, error)
+
+	// UnlockWorkspaces force-unlocks the state of every workspace that is currently locked.
+	UnlockWorkspaces(ctx context.Context) ([]WorkspaceUnlock, error)
 }
 
 // stateMigrator implements the StateMigrator interface.
@@ -31,23 +41,40 @@ type stateMigrator struct {
 
 	// tfVar is a struct which can extract the remote variables needed to run migration statements.
 	tfVar tfvars.TFVars
+
+	// runner executes the engine and tfmigrate commands.
+	runner CommandRunner
+
+	// installer installs the Terraform or OpenTofu binary used for each workspace.
+	installer installer.Installer
+
+	// workspaceEnv are the environment variables of each workspace within Terraform Cloud, which
+	// are set only for that workspace's commands.
+	workspaceEnv map[string]tfvars.VariableMap
 }
 
 // NewStateMigrator instantiates a new implementation of the StateMigrator interface.
 func NewStateMigrator() (StateMigrator, error) {
 	conf, err := NewConfig()
 	if err != nil {
-		return nil, fmt.Errorf("[NewConfig] %v", err)
+		return nil, fmt.Errorf("[NewConfig] %w", err)
 	}
 
 	tfVar, err := tfvars.NewTFVars()
 	if err != nil {
-		return nil, fmt.Errorf("[NewTFVars] %v", err)
+		return nil, fmt.Errorf("[NewTFVars] %w", err)
+	}
+
+	terraformInstaller, err := installer.NewInstaller(string(conf.Engine))
+	if err != nil {
+		return nil, fmt.Errorf("[NewInstaller] %w", err)
 	}
 
 	return &stateMigrator{
 		config:     conf,
 		httpClient: http.Client{},
 		tfVar:      tfVar,
+		runner:     NewCommandRunner(conf.ShutdownGracePeriod),
+		installer:  terraformInstaller,
 	}, nil
 }
@@ -0,0 +1,123 @@
+package statemigration
+
+import (
+	"context"
+	"fmt"
+)
+
+// WorkspaceStatus describes the state lock and active runs of a Terraform Cloud workspace.
+type WorkspaceStatus struct {
+
+	// Workspace is the name of the Terraform Cloud workspace.
+	Workspace string `json:"workspace"`
+
+	// Locked is whether the workspace's state is currently locked.
+	Locked bool `json:"locked"`
+
+	// LockedBy is the type of resource holding the lock, such as "runs" or "users".
+	LockedBy string `json:"locked_by,omitempty"`
+
+	// ActiveRuns are the workspace's runs that have not reached a terminal state.
+	ActiveRuns []ActiveRun `json:"active_runs"`
+}
+
+// ActiveRun describes a Terraform Cloud run that has not reached a terminal state.
+type ActiveRun struct {
+
+	// ID is the Terraform Cloud ID of the run.
+	ID string `json:"id"`
+
+	// Status is the current status of the run, such as "planning".
+	Status string `json:"status"`
+}
+
+// WorkspaceUnlock describes the outcome of unlocking a Terraform Cloud workspace.
+type WorkspaceUnlock struct {
+
+	// Workspace is the name of the Terraform Cloud workspace.
+	Workspace string `json:"workspace"`
+
+	// WasLocked is whether the workspace was locked before being unlocked.
+	WasLocked bool `json:"was_locked"`
+
+	// LockedBy is the type of resource that held the lock, such as "runs" or "users".
+	LockedBy string `json:"locked_by,omitempty"`
+}
+
+// WorkspaceStatuses gets the state lock and active runs of every workspace.
+func (sm *stateMigrator) WorkspaceStatuses(ctx context.Context) ([]WorkspaceStatus, error) {
+	var statuses []WorkspaceStatus
+
+	for _, workspace := range sm.workspaces() {
+		workspaceID, lock, err := sm.getWorkspaceLock(ctx, workspace)
+		if err != nil {
+			return statuses, fmt.Errorf("[sm.getWorkspaceLock] %v: %w", workspace, err)
+		}
+
+		runStatuses, err := sm.getActiveRuns(ctx, workspaceID)
+		if err != nil {
+			return statuses, fmt.Errorf("[sm.getActiveRuns] %v: %w", workspace, err)
+		}
+
+		activeRuns := []ActiveRun{}
+		for _, runStatus := range runStatuses {
+			activeRuns = append(activeRuns, ActiveRun{ID: runStatus.runID, Status: runStatus.status})
+		}
+
+		statuses = append(statuses, WorkspaceStatus{
+			Workspace:  workspace,
+			Locked:     lock.locked,
+			LockedBy:   lock.lockedByType,
+			ActiveRuns: activeRuns,
+		})
+	}
+
+	return statuses, nil
+}
+
+// UnlockWorkspaces force-unlocks the state of every workspace that is currently locked.
+func (sm *stateMigrator) UnlockWorkspaces(ctx context.Context) ([]WorkspaceUnlock, error) {
+	var unlocks []WorkspaceUnlock
+
+	for _, workspace := range sm.workspaces() {
+		workspaceID, lock, err := sm.getWorkspaceLock(ctx, workspace)
+		if err != nil {
+			return unlocks, fmt.Errorf("[sm.getWorkspaceLock] %v: %w", workspace, err)
+		}
+
+		if lock.locked {
+			err = sm.forceUnlockWorkspace(ctx, workspaceID)
+			if err != nil {
+				return unlocks, fmt.Errorf("[sm.forceUnlockWorkspace] %v: %w", workspace, err)
+			}
+		}
+
+		unlocks = append(unlocks, WorkspaceUnlock{
+			Workspace: workspace,
+			WasLocked: lock.locked,
+			LockedBy:  lock.lockedByType,
+		})
+	}
+
+	return unlocks, nil
+}
+
+// getWorkspaceLock gets the ID and state lock of the corresponding workspace name.
+func (sm *stateMigrator) getWorkspaceLock(ctx context.Context, workspace string) (string, WorkspaceLock, error) {
+	jsonResponseBytes, err := sm.getWorkspace(ctx, workspace)
+	if err != nil {
+		return "", WorkspaceLock{}, err
+	}
+
+	workspaceID, err := extractWorkspaceID(jsonResponseBytes)
+	if err != nil {
+		return "", WorkspaceLock{}, err
+	}
+
+	lock, err := extractWorkspaceLock(jsonResponseBytes)
+	if err != nil {
+		return "", WorkspaceLock{}, err
+	}
+
+	return workspaceID, lock, nil
+}
@@ -0,0 +1,83 @@
+package statemigration
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaceStatusesAndUnlock(t *testing.T) {
+	var requests []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/workspaces/workspace_1"):
+			_, _ = w.Write([]byte(`{"data": {"id": "ws-1", "attributes": {"locked": true}, "relationships": {"locked-by": {"data": {"id": "user-1", "type": "users"}}}}}`))
+		case strings.HasSuffix(r.URL.Path, "/workspaces/workspace_2"):
+			_, _ = w.Write([]byte(`{"data": {"id": "ws-2", "attributes": {"locked": false}}}`))
+		case r.URL.Path == "/api/v2/workspaces/ws-1/runs":
+			_, _ = w.Write([]byte(`{"data": [
+				{"id": "run-1", "attributes": {"status": "planning", "actions": {"is-cancelable": true, "is-discardable": false}}},
+				{"id": "run-0", "attributes": {"status": "applied", "actions": {"is-cancelable": false, "is-discardable": false}}}
+			]}`))
+		case r.URL.Path == "/api/v2/workspaces/ws-2/runs":
+			_, _ = w.Write([]byte(`{"data": []}`))
+		case strings.HasSuffix(r.URL.Path, "/actions/force-unlock"):
+			_, _ = w.Write([]byte(`{"data": {}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	_, httpClient := newTestTFCServer(t, mux)
+
+	sm := stateMigrator{
+		config: &Config{
+			TerraformCloudOrganization: "dragondrop-cloud",
+			WorkspaceToDirectory:       map[string]string{"workspace_2": "/directory_2/", "workspace_1": "/directory_1/", "workspace_3": "null"},
+		},
+		httpClient: httpClient,
+	}
+
+	statuses, err := sm.WorkspaceStatuses(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedStatuses := []WorkspaceStatus{
+		{Workspace: "workspace_1", Locked: true, LockedBy: "users", ActiveRuns: []ActiveRun{{ID: "run-1", Status: "planning"}}},
+		{Workspace: "workspace_2", ActiveRuns: []ActiveRun{}},
+	}
+
+	if !reflect.DeepEqual(statuses, expectedStatuses) {
+		t.Errorf("got %v, expected %v", statuses, expectedStatuses)
+	}
+
+	requests = nil
+	unlocks, err := sm.UnlockWorkspaces(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedUnlocks := []WorkspaceUnlock{
+		{Workspace: "workspace_1", WasLocked: true, LockedBy: "users"},
+		{Workspace: "workspace_2"},
+	}
+
+	if !reflect.DeepEqual(unlocks, expectedUnlocks) {
+		t.Errorf("got %v, expected %v", unlocks, expectedUnlocks)
+	}
+
+	expectedRequests := []string{
+		"GET /api/v2/organizations/dragondrop-cloud/workspaces/workspace_1",
+		"POST /api/v2/workspaces/ws-1/actions/force-unlock",
+		"GET /api/v2/organizations/dragondrop-cloud/workspaces/workspace_2",
+	}
+
+	if !reflect.DeepEqual(requests, expectedRequests) {
+		t.Errorf("got %v, expected %v", requests, expectedRequests)
+	}
+}
@@ -5,12 +5,17 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strconv"
 
 	"github.com/Jeffail/gabs/v2"
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/internal/tfcapi"
 )
 
+// terraformCloudHostname is the hostname of Terraform Cloud.
+const terraformCloudHostname = "app.terraform.io"
+
 // RunStatus is a struct containing information on a workspace run as is required to determine whether
 // to cancel or discard a run if possible.
 type RunStatus struct {
@@ -26,27 +31,35 @@ type RunStatus struct {
 
 	// runID is the Terraform Cloud ID for a workspace run.
 	runID string
+
+	// status is the current status of the run, such as "planning".
+	status string
 }
 
 // getWorkspaceID gets the workspace ID for the corresponding workspace name
 // from the Terraform Cloud API.
 func (sm *stateMigrator) getWorkspaceID(ctx context.Context, workspace string) (string, error) {
-	requestName := "getWorkspaceID"
-	requestPath := fmt.Sprintf("https://app.terraform.io/api/v2/organizations/%v/workspaces/%v", sm.config.TerraformCloudOrganization, workspace)
-
-	request, err := sm.buildTFCloudHTTPRequest(ctx, requestName, "GET", requestPath, nil)
+	jsonResponseBytes, err := sm.getWorkspace(ctx, workspace)
 
 	if err != nil {
-		return "", fmt.Errorf("[%v] error in newRequest: %v", requestName, err)
+		return "", err
 	}
 
-	jsonResponseBytes, err := sm.terraformCloudRequest(request, requestName)
+	return extractWorkspaceID(jsonResponseBytes)
+}
+
+// getWorkspace gets the details of the corresponding workspace name from the Terraform Cloud API.
+func (sm *stateMigrator) getWorkspace(ctx context.Context, workspace string) ([]byte, error) {
+	requestName := "getWorkspace"
+	requestPath := fmt.Sprintf("https://app.terraform.io/api/v2/organizations/%v/workspaces/%v?include=locked_by", sm.config.TerraformCloudOrganization, workspace)
+
+	request, err := sm.buildTFCloudHTTPRequest(ctx, requestName, "GET", requestPath, nil)
 
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("[%v] error in newRequest: %w", requestName, err)
 	}
 
-	return extractWorkspaceID(jsonResponseBytes)
+	return sm.terraformCloudRequest(request, requestName)
 }
 
 // extractWorkspaceID is a helper function that uses the gabs library to pull out the workspace ID
@@ -54,7 +67,7 @@ func (sm *stateMigrator) getWorkspaceID(ctx context.Context, workspace string) (
 func extractWorkspaceID(jsonBytes []byte) (string, error) {
 	jsonParsed, err := gabs.ParseJSON(jsonBytes)
 	if err != nil {
-		return "", fmt.Errorf("[getWorkspaceID] error in parsing bytes array to json via 'gabs': %v", err)
+		return "", fmt.Errorf("[getWorkspaceID] error in parsing bytes array to json via 'gabs': %w", err)
 	}
 
 	value, ok := jsonParsed.Path("data.id").Data().(string)
@@ -65,53 +78,104 @@ func extractWorkspaceID(jsonBytes []byte) (string, error) {
 	return value, nil
 }
 
-// discardActiveRunsUnlockState identifies pending/active Terraform Cloud runs and discards
-// them so that tfmigrate apply can itself apply a state lock and run migrations.
-func (sm *stateMigrator) discardActiveRunsUnlockState(ctx context.Context, workspaceID string) error {
-	// Get a list of all active/pending runs
-	requestName := "getMostRecentRuns"
-	requestPath := fmt.Sprintf("https://app.terraform.io/api/v2/workspaces/%v/runs", workspaceID)
+// getAccountUsername gets the username of the account that the token belongs to, which fails if
+// the token does not authenticate.
+func (sm *stateMigrator) getAccountUsername(ctx context.Context) (string, error) {
+	requestName := "getAccountDetails"
+	requestPath := "https://app.terraform.io/api/v2/account/details"
 
 	request, err := sm.buildTFCloudHTTPRequest(ctx, requestName, "GET", requestPath, nil)
 
 	if err != nil {
-		return fmt.Errorf("[%v] error in newRequest: %v", requestName, err)
+		return "", fmt.Errorf("[%v] error in newRequest: %w", requestName, err)
 	}
 
 	jsonResponseBytes, err := sm.terraformCloudRequest(request, requestName)
 
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	runStatusSlice, err := extractRecentRunStatuses(jsonResponseBytes)
+	return extractAccountUsername(jsonResponseBytes)
+}
+
+// extractAccountUsername is a helper function that uses the gabs library to pull out the username
+// from a Terraform Cloud account details response.
+func extractAccountUsername(jsonBytes []byte) (string, error) {
+	jsonParsed, err := gabs.ParseJSON(jsonBytes)
+	if err != nil {
+		return "", fmt.Errorf("[extractAccountUsername] error in parsing bytes array to json via 'gabs': %w", err)
+	}
+
+	value, ok := jsonParsed.Path("data.attributes.username").Data().(string)
+	if !ok {
+		return "", fmt.Errorf("[extractAccountUsername] unable to find username")
+	}
+
+	return value, nil
+}
+
+// discardActiveRunsUnlockState identifies pending/active Terraform Cloud runs and discards
+// them so that tfmigrate apply can itself apply a state lock and run migrations. The runs
+// that were discarded or cancelled are returned.
+func (sm *stateMigrator) discardActiveRunsUnlockState(ctx context.Context, workspaceID string) ([]RunStatus, error) {
+	runStatusSlice, err := sm.getActiveRuns(ctx, workspaceID)
 	if err != nil {
-		return fmt.Errorf("[extractRecentRunStatuses] %v", err)
+		return nil, fmt.Errorf("[sm.getActiveRuns] %w", err)
 	}
 
 	for _, runStatus := range runStatusSlice {
 		if runStatus.isPostConfirmation {
-			fmt.Println(
-				"There is an unfinished run that is post-confirmation. We do not discard those runs, ending " +
-					"job execution.")
+			slog.Warn(
+				"There is an unfinished run that is post-confirmation. We do not discard those runs, ending "+
+					"job execution.", "run", runStatus.runID)
 		}
 	}
 
+	var stoppedRuns []RunStatus
+
 	for _, runStatus := range runStatusSlice {
 		if runStatus.isDiscardable {
 			err = sm.discardRun(ctx, runStatus.runID)
 			if err != nil {
-				return fmt.Errorf("[sm.discardRun] %v", err)
+				return stoppedRuns, fmt.Errorf("[sm.discardRun] %w", err)
 			}
+			stoppedRuns = append(stoppedRuns, runStatus)
 		} else if runStatus.isCancelable {
 			err = sm.cancelRun(ctx, runStatus.runID)
 			if err != nil {
-				return fmt.Errorf("[sm.cancelRun] %v", err)
+				return stoppedRuns, fmt.Errorf("[sm.cancelRun] %w", err)
 			}
+			stoppedRuns = append(stoppedRuns, runStatus)
 		}
 	}
 
-	return nil
+	return stoppedRuns, nil
+}
+
+// getActiveRuns gets the statuses of the workspace's recent runs that have not yet reached a terminal state.
+func (sm *stateMigrator) getActiveRuns(ctx context.Context, workspaceID string) ([]RunStatus, error) {
+	requestName := "getMostRecentRuns"
+	requestPath := fmt.Sprintf("https://app.terraform.io/api/v2/workspaces/%v/runs", workspaceID)
+
+	request, err := sm.buildTFCloudHTTPRequest(ctx, requestName, "GET", requestPath, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("[%v] error in newRequest: %w", requestName, err)
+	}
+
+	jsonResponseBytes, err := sm.terraformCloudRequest(request, requestName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	runStatusSlice, err := extractRecentRunStatuses(jsonResponseBytes)
+	if err != nil {
+		return nil, fmt.Errorf("[extractRecentRunStatuses] %w", err)
+	}
+
+	return runStatusSlice, nil
 }
 
 // TODO: Add unit test if possible
@@ -123,13 +187,13 @@ func (sm *stateMigrator) cancelRun(ctx context.Context, runID string) error {
 	request, err := sm.buildTFCloudHTTPRequest(ctx, requestName, "POST", requestPath, nil)
 
 	if err != nil {
-		return fmt.Errorf("[%v] error in newRequest: %v", requestName, err)
+		return fmt.Errorf("[%v] error in newRequest: %w", requestName, err)
 	}
 
 	_, err = sm.terraformCloudRequest(request, requestName)
 
 	if err != nil {
-		return err
+		return &RunError{RunID: runID, Action: "cancel", Err: err}
 	}
 	return nil
 }
@@ -142,13 +206,13 @@ func (sm *stateMigrator) discardRun(ctx context.Context, runID string) error {
 	request, err := sm.buildTFCloudHTTPRequest(ctx, requestName, "POST", requestPath, nil)
 
 	if err != nil {
-		return fmt.Errorf("[%v] error in newRequest: %v", requestName, err)
+		return fmt.Errorf("[%v] error in newRequest: %w", requestName, err)
 	}
 
 	_, err = sm.terraformCloudRequest(request, requestName)
 
 	if err != nil {
-		return err
+		return &RunError{RunID: runID, Action: "discard", Err: err}
 	}
 	return nil
 }
@@ -157,7 +221,7 @@ func (sm *stateMigrator) discardRun(ctx context.Context, runID string) error {
 func extractRecentRunStatuses(jsonResponseBytes []byte) ([]RunStatus, error) {
 	jsonParsed, err := gabs.ParseJSON(jsonResponseBytes)
 	if err != nil {
-		return nil, fmt.Errorf("[gabs.ParseJSON] %v", err)
+		return nil, fmt.Errorf("[gabs.ParseJSON] %w", err)
 	}
 
 	var runStatusSlice []RunStatus
@@ -187,6 +251,7 @@ func extractRecentRunStatuses(jsonResponseBytes []byte) ([]RunStatus, error) {
 			isDiscardable:      isDiscardable,
 			isPostConfirmation: isPostConfirmation,
 			runID:              runID,
+			status:             status,
 		}
 
 		runStatusSlice = append(runStatusSlice, currentRS)
@@ -227,13 +292,14 @@ func isStatusPostConfirmation(status string) bool {
 	return postConfirmationSet[status]
 }
 
-// createPlanOnlyRefreshRun kicks off a new plan-only, refresh-state run for the workspace.
-func (sm *stateMigrator) createPlanOnlyRefreshRun(ctx context.Context, workspaceID string) error {
+// createPlanOnlyRefreshRun kicks off a new plan-only, refresh-state run for the workspace,
+// returning the ID of the run.
+func (sm *stateMigrator) createPlanOnlyRefreshRun(ctx context.Context, workspaceID string) (string, error) {
 	requestPath := "https://app.terraform.io/api/v2/runs"
 
 	payload, err := generateRefreshOnlyPlanPayload(workspaceID)
 	if err != nil {
-		return fmt.Errorf("[generateRefreshOnlyPlanPayload] %v", err)
+		return "", fmt.Errorf("[generateRefreshOnlyPlanPayload] %w", err)
 	}
 
 	requestName := "createPlanOnlyRefreshRun"
@@ -241,14 +307,31 @@ func (sm *stateMigrator) createPlanOnlyRefreshRun(ctx context.Context, workspace
 	request, err := sm.buildTFCloudHTTPRequest(ctx, requestName, "POST", requestPath, bytes.NewBuffer(payload))
 
 	if err != nil {
-		return fmt.Errorf("[%v] error in newRequest: %v", requestName, err)
+		return "", fmt.Errorf("[%v] error in newRequest: %w", requestName, err)
 	}
-	_, err = sm.terraformCloudRequest(request, requestName)
+	jsonResponseBytes, err := sm.terraformCloudRequest(request, requestName)
 
 	if err != nil {
-		return err
+		return "", &RunError{Action: "create a refresh-only", Err: err}
 	}
-	return nil
+
+	return extractRunID(jsonResponseBytes)
+}
+
+// extractRunID is a helper function that uses the gabs library to pull out the run ID
+// from a Terraform Cloud API response.
+func extractRunID(jsonBytes []byte) (string, error) {
+	jsonParsed, err := gabs.ParseJSON(jsonBytes)
+	if err != nil {
+		return "", fmt.Errorf("[extractRunID] error in parsing bytes array to json via 'gabs': %w", err)
+	}
+
+	value, ok := jsonParsed.Path("data.id").Data().(string)
+	if !ok {
+		return "", fmt.Errorf("[extractRunID] unable to find run id")
+	}
+
+	return value, nil
 }
 
 // generateRefreshOnlyPlanPayload builds the JSON payload needed to
@@ -258,27 +341,27 @@ func generateRefreshOnlyPlanPayload(workspaceID string) ([]byte, error) {
 
 	_, err := jsonObj.Set("runs", "data", "type")
 	if err != nil {
-		return nil, fmt.Errorf("[data: type:]%v", err)
+		return nil, fmt.Errorf("[data: type:]%w", err)
 	}
 
 	_, err = jsonObj.Set(true, "data", "attributes", "refresh-only")
 	if err != nil {
-		return nil, fmt.Errorf("[data: attributes: refresh-only:]%v", err)
+		return nil, fmt.Errorf("[data: attributes: refresh-only:]%w", err)
 	}
 
 	_, err = jsonObj.Set(true, "data", "attributes", "plan-only")
 	if err != nil {
-		return nil, fmt.Errorf("[data: attributes: plan-only:]%v", err)
+		return nil, fmt.Errorf("[data: attributes: plan-only:]%w", err)
 	}
 
 	_, err = jsonObj.Set("workspaces", "data", "relationships", "workspace", "data", "type")
 	if err != nil {
-		return nil, fmt.Errorf("[data: relationships: workspace: type:]%v", err)
+		return nil, fmt.Errorf("[data: relationships: workspace: type:]%w", err)
 	}
 
 	_, err = jsonObj.Set(workspaceID, "data", "relationships", "workspace", "data", "id")
 	if err != nil {
-		return nil, fmt.Errorf("[data: relationship: workspace: id:]%v", err)
+		return nil, fmt.Errorf("[data: relationship: workspace: id:]%w", err)
 	}
 
 	return jsonObj.Bytes(), nil
@@ -288,7 +371,7 @@ func generateRefreshOnlyPlanPayload(workspaceID string) ([]byte, error) {
 func (sm *stateMigrator) buildTFCloudHTTPRequest(ctx context.Context, requestName string, method string, requestPath string, body io.Reader) (*http.Request, error) {
 	request, err := http.NewRequestWithContext(ctx, method, requestPath, body)
 	if err != nil {
-		return nil, fmt.Errorf("[%v] error in http request instantiation: %v", requestName, err)
+		return nil, fmt.Errorf("[%v] error in http request instantiation: %w", requestName, err)
 	}
 
 	request.Header = http.Header{
@@ -305,19 +388,27 @@ func (sm *stateMigrator) terraformCloudRequest(request *http.Request, requestNam
 	response, err := sm.httpClient.Do(request)
 
 	if err != nil {
-		return nil, fmt.Errorf("[%v] error in http GET request to Terraform cloud: %v", requestName, err)
+		return nil, fmt.Errorf("[%v] error in http GET request to Terraform cloud: %w", requestName, err)
 	}
 
 	defer response.Body.Close()
-	if !(response.StatusCode <= 299) {
-		return nil, fmt.Errorf("[%v] was unsuccessful, with the server returning: %v", requestName, response.StatusCode)
-	}
 
 	// Read in response body to bytes array.
 	outputBytes, err := io.ReadAll(response.Body)
 
 	if err != nil {
-		return nil, fmt.Errorf("[%v] error in reading response into bytes array: %v", requestName, err)
+		return nil, fmt.Errorf("[%v] error in reading response into bytes array: %w", requestName, err)
+	}
+
+	if !(response.StatusCode <= 299) {
+		return nil, &APIError{APIError: tfcapi.APIError{
+			Request:    requestName,
+			Method:     request.Method,
+			Path:       request.URL.Path,
+			StatusCode: response.StatusCode,
+			Body:       string(outputBytes),
+			Errors:     tfcapi.ExtractErrorDetails(outputBytes),
+		}}
 	}
 
 	return outputBytes, nil
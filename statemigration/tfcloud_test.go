@@ -69,7 +69,7 @@ func TestDiscardActiveRunsUnlockState(t *testing.T) {
 	ctx := context.Background()
 
 	// Very simple test, only checking that it can run end to end
-	err := sm.discardActiveRunsUnlockState(ctx, os.Getenv("TerraformCloudWorkspaceID"))
+	_, err := sm.discardActiveRunsUnlockState(ctx, os.Getenv("TerraformCloudWorkspaceID"))
 	if err != nil {
 		t.Errorf("[sm.discardActiveRunsUnlockState] %v", err)
 	}
@@ -128,12 +128,14 @@ func TestExtractRecentRunStatuses(t *testing.T) {
 			isDiscardable:      false,
 			isPostConfirmation: false,
 			runID:              "run-CZcmD7eagjhyX0vN",
+			status:             "pending",
 		},
 		{
 			isCancelable:       false,
 			isDiscardable:      true,
 			isPostConfirmation: true,
 			runID:              "run-CZcmD7eagjhyX0vN",
+			status:             "applying",
 		},
 	}
 
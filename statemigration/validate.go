@@ -0,0 +1,199 @@
+package statemigration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// tfmigrateConfigPath is the path of the tfmigrate configuration file, relative to a workspace directory.
+const tfmigrateConfigPath = "./dragondrop/tfmigrate/.tfmigrate.hcl"
+
+// migrationTypes are the migration block types supported by tfmigrate.
+var migrationTypes = map[string]bool{
+	"state":       true,
+	"multi_state": true,
+}
+
+// WorkspaceValidation describes the outcome of validating a workspace's tfmigrate configuration
+// and migration files.
+type WorkspaceValidation struct {
+
+	// Workspace is the name of the Terraform Cloud workspace.
+	Workspace string `json:"workspace"`
+
+	// Directory is the workspace's directory, relative to the root directory.
+	Directory string `json:"directory"`
+
+	// Files are the configuration and migration files that were validated.
+	Files []string `json:"files"`
+
+	// Errors are the problems found, empty if the workspace is valid.
+	Errors []string `json:"errors"`
+}
+
+// ValidateWorkspaces checks the tfmigrate configuration and migration files of every workspace
+// without contacting Terraform Cloud.
+func ValidateWorkspaces(config *ValidateConfig) []WorkspaceValidation {
+	var validations []WorkspaceValidation
+
+	for _, workspace := range sortedWorkspaces(config.WorkspaceToDirectory) {
+		directory := config.WorkspaceToDirectory[workspace]
+		validation := validateWorkspaceDirectory(filepath.Join(config.RootDirectory, directory))
+		validation.Workspace = workspace
+		validation.Directory = directory
+
+		validations = append(validations, validation)
+	}
+
+	return validations
+}
+
+// workspaces returns the names of the workspaces with a directory, sorted so that output is stable.
+func (sm *stateMigrator) workspaces() []string {
+	return sortedWorkspaces(sm.config.WorkspaceToDirectory)
+}
+
+// sortedWorkspaces returns the names of the workspaces in workspaceToDirectory with a directory,
+// sorted so that output is stable.
+func sortedWorkspaces(workspaceToDirectory map[string]string) []string {
+	var workspaces []string
+	for workspace, directory := range workspaceToDirectory {
+		if directory == "null" {
+			continue
+		}
+		workspaces = append(workspaces, workspace)
+	}
+	sort.Strings(workspaces)
+
+	return workspaces
+}
+
+// validateWorkspaceDirectory parses the tfmigrate configuration file within a workspace directory
+// and each migration file within its migration_dir.
+func validateWorkspaceDirectory(workspaceDirectory string) WorkspaceValidation {
+	validation := WorkspaceValidation{Files: []string{}, Errors: []string{}}
+
+	configPath := filepath.Join(workspaceDirectory, tfmigrateConfigPath)
+	validation.Files = append(validation.Files, configPath)
+
+	migrationDir, err := readMigrationDir(configPath)
+	if err != nil {
+		validation.Errors = append(validation.Errors, err.Error())
+		return validation
+	}
+
+	// tfmigrate resolves migration_dir relative to the directory it is run from.
+	migrationDir = filepath.Join(workspaceDirectory, migrationDir)
+
+	if _, err = os.Stat(migrationDir); err != nil {
+		validation.Errors = append(validation.Errors, fmt.Sprintf("migration_dir %v does not exist", migrationDir))
+		return validation
+	}
+
+	fileNames, err := filepath.Glob(filepath.Join(migrationDir, "*.hcl"))
+	if err != nil {
+		validation.Errors = append(validation.Errors, fmt.Sprintf("[filepath.Glob] %v", err))
+		return validation
+	}
+	sort.Strings(fileNames)
+
+	for _, fileName := range fileNames {
+		if fileName == filepath.Clean(configPath) {
+			continue
+		}
+
+		validation.Files = append(validation.Files, fileName)
+
+		err = validateMigrationFile(fileName)
+		if err != nil {
+			validation.Errors = append(validation.Errors, err.Error())
+		}
+	}
+
+	return validation
+}
+
+// readMigrationDir parses a tfmigrate configuration file, returning its migration_dir.
+func readMigrationDir(configPath string) (string, error) {
+	file, diags := hclparse.NewParser().ParseHCLFile(configPath)
+	if diags.HasErrors() {
+		return "", fmt.Errorf("%v", diags.Error())
+	}
+
+	content, diags := file.Body.Content(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "tfmigrate"}},
+	})
+	if diags.HasErrors() {
+		return "", fmt.Errorf("%v", diags.Error())
+	}
+
+	if len(content.Blocks) != 1 {
+		return "", fmt.Errorf("%v: expected exactly one tfmigrate block, found %v", configPath, len(content.Blocks))
+	}
+
+	tfmigrateContent, _, diags := content.Blocks[0].Body.PartialContent(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: "migration_dir"}},
+		Blocks:     []hcl.BlockHeaderSchema{{Type: "history"}},
+	})
+	if diags.HasErrors() {
+		return "", fmt.Errorf("%v", diags.Error())
+	}
+
+	attribute, ok := tfmigrateContent.Attributes["migration_dir"]
+	if !ok {
+		return ".", nil
+	}
+
+	value, diags := attribute.Expr.Value(nil)
+	if diags.HasErrors() {
+		return "", fmt.Errorf("%v", diags.Error())
+	}
+
+	if value.Type() != cty.String || value.IsNull() {
+		return "", fmt.Errorf("%v: migration_dir must be a string", configPath)
+	}
+
+	return value.AsString(), nil
+}
+
+// validateMigrationFile parses a tfmigrate migration file, checking that it contains a single
+// migration block of a supported type with an actions attribute.
+func validateMigrationFile(fileName string) error {
+	file, diags := hclparse.NewParser().ParseHCLFile(fileName)
+	if diags.HasErrors() {
+		return fmt.Errorf("%v", diags.Error())
+	}
+
+	content, diags := file.Body.Content(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "migration", LabelNames: []string{"type", "name"}}},
+	})
+	if diags.HasErrors() {
+		return fmt.Errorf("%v", diags.Error())
+	}
+
+	if len(content.Blocks) != 1 {
+		return fmt.Errorf("%v: expected exactly one migration block, found %v", fileName, len(content.Blocks))
+	}
+
+	block := content.Blocks[0]
+	if !migrationTypes[block.Labels[0]] {
+		return fmt.Errorf("%v: unsupported migration type %q, expected state or multi_state", fileName, block.Labels[0])
+	}
+
+	attributes, diags := block.Body.JustAttributes()
+	if diags.HasErrors() {
+		return fmt.Errorf("%v", diags.Error())
+	}
+
+	if _, ok := attributes["actions"]; !ok {
+		return fmt.Errorf("%v: migration %q has no actions", fileName, block.Labels[1])
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package statemigration
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateWorkspaceDirectory(t *testing.T) {
+	directory := t.TempDir()
+	migrationDir := filepath.Join(directory, "dragondrop", "tfmigrate")
+	err := os.MkdirAll(migrationDir, 0755)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files := map[string]string{
+		".tfmigrate.hcl": `
+tfmigrate {
+  migration_dir = "./dragondrop/tfmigrate"
+  history {
+    storage "local" {
+      path = "history.json"
+    }
+  }
+}
+`,
+		"1_move.hcl": `
+migration "state" "move" {
+  actions = ["mv aws_s3_bucket.a aws_s3_bucket.b"]
+}
+`,
+		"2_unknown_type.hcl": `
+migration "tfstate" "move" {
+  actions = []
+}
+`,
+		"3_no_actions.hcl": `
+migration "multi_state" "split" {
+  from_dir = "a"
+  to_dir   = "b"
+}
+`,
+		"4_syntax_error.hcl": `migration "state" {`,
+	}
+
+	for fileName, content := range files {
+		err = os.WriteFile(filepath.Join(migrationDir, fileName), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	output := validateWorkspaceDirectory(directory)
+
+	if len(output.Files) != 5 {
+		t.Errorf("expected the config and four migration files to be validated, got %v", output.Files)
+	}
+
+	expectedErrors := []string{"2_unknown_type.hcl", "3_no_actions.hcl", "4_syntax_error.hcl"}
+	if len(output.Errors) != len(expectedErrors) {
+		t.Fatalf("got %v, expected errors for %v", output.Errors, expectedErrors)
+	}
+
+	for i, expectedError := range expectedErrors {
+		if !strings.Contains(output.Errors[i], expectedError) {
+			t.Errorf("got %v, expected an error for %v", output.Errors[i], expectedError)
+		}
+	}
+}
+
+func TestValidateWorkspaceDirectoryMissingConfig(t *testing.T) {
+	output := validateWorkspaceDirectory(t.TempDir())
+
+	if len(output.Errors) != 1 {
+		t.Errorf("expected a single error for the missing config file, got %v", output.Errors)
+	}
+}
@@ -0,0 +1,224 @@
+package statemigration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// versionFiles maps each engine to the file tfenv, tenv, and tfswitch read a workspace's version from.
+var versionFiles = map[Engine]string{
+	EngineTerraform: ".terraform-version",
+	EngineTofu:      ".opentofu-version",
+}
+
+// latestVersion is the version string Terraform Cloud and .terraform-version files use to
+// request the newest release.
+const latestVersion = "latest"
+
+// resolveTerraformVersion determines the concrete Terraform or OpenTofu version to install for a
+// workspace, which must be of major version 0 or 1 however it was requested.
+func (sm *stateMigrator) resolveTerraformVersion(ctx context.Context, workspace string, directory string) (string, error) {
+	requestedVersion := string(sm.config.TerraformVersion)
+
+	if sm.config.TerraformVersion.IsExact() {
+		return requestedVersion, nil
+	}
+
+	if sm.config.TerraformVersion.IsAuto() {
+		var err error
+		requestedVersion, err = sm.autoTerraformVersion(ctx, workspace, directory)
+		if err != nil {
+			return "", fmt.Errorf("[sm.autoTerraformVersion] %w", err)
+		}
+	}
+
+	releases, err := sm.installer.ListVersions(ctx)
+	if err != nil {
+		return "", fmt.Errorf("[sm.installer.ListVersions] %w", err)
+	}
+
+	resolvedVersion, err := resolveVersionConstraint(requestedVersion, releases)
+	if err != nil {
+		return "", fmt.Errorf("[resolveVersionConstraint] %w", err)
+	}
+
+	err = checkMajorVersion(resolvedVersion)
+	if err != nil {
+		return "", fmt.Errorf("[checkMajorVersion] %w", err)
+	}
+
+	return resolvedVersion, nil
+}
+
+// autoTerraformVersion finds the version requested for a workspace, checking in order the
+// Terraform Cloud workspace's terraform-version attribute, the engine's version file, and
+// the required_version constraint within the workspace's configuration. The Terraform Cloud
+// attribute is a Terraform version, so it is skipped when running OpenTofu.
+func (sm *stateMigrator) autoTerraformVersion(ctx context.Context, workspace string, directory string) (string, error) {
+	if sm.config.Engine == EngineTerraform {
+		workspaceVersion, err := sm.getWorkspaceTerraformVersion(ctx, workspace)
+		if err != nil {
+			return "", fmt.Errorf("[sm.getWorkspaceTerraformVersion] %w", err)
+		}
+
+		if workspaceVersion != "" {
+			slog.Debug("Using the Terraform Cloud workspace version.", "workspace", workspace, "version", workspaceVersion)
+			return workspaceVersion, nil
+		}
+	}
+
+	versionFile := versionFiles[sm.config.Engine]
+
+	fileVersion, err := readVersionFile(directory, versionFile)
+	if err != nil {
+		return "", fmt.Errorf("[readVersionFile] %w", err)
+	}
+
+	if fileVersion != "" {
+		slog.Debug("Using the version file.", "workspace", workspace, "file", versionFile, "version", fileVersion)
+		return fileVersion, nil
+	}
+
+	requiredVersion, err := readRequiredVersion(directory)
+	if err != nil {
+		return "", fmt.Errorf("[readRequiredVersion] %w", err)
+	}
+
+	if requiredVersion != "" {
+		slog.Debug("Using required_version.", "workspace", workspace, "version", requiredVersion)
+		return requiredVersion, nil
+	}
+
+	slog.Debug("No Terraform version found, using the latest release.", "workspace", workspace)
+	return latestVersion, nil
+}
+
+// getWorkspaceTerraformVersion gets the terraform-version attribute of a Terraform Cloud workspace.
+func (sm *stateMigrator) getWorkspaceTerraformVersion(ctx context.Context, workspace string) (string, error) {
+	jsonResponseBytes, err := sm.getWorkspace(ctx, workspace)
+	if err != nil {
+		return "", err
+	}
+
+	return extractWorkspaceTerraformVersion(jsonResponseBytes)
+}
+
+// extractWorkspaceTerraformVersion pulls out the terraform-version attribute from a Terraform
+// Cloud workspace API response.
+func extractWorkspaceTerraformVersion(jsonBytes []byte) (string, error) {
+	jsonParsed, err := gabs.ParseJSON(jsonBytes)
+	if err != nil {
+		return "", fmt.Errorf("[gabs.ParseJSON] %w", err)
+	}
+
+	value, _ := jsonParsed.Search("data", "attributes", "terraform-version").Data().(string)
+
+	return strings.TrimSpace(value), nil
+}
+
+// readVersionFile reads the version within a directory's version file, such as
+// .terraform-version, returning an empty string if the file does not exist.
+func readVersionFile(directory string, versionFile string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(directory, versionFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("[os.ReadFile] %w", err)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	value := strings.TrimSpace(lines[0])
+
+	// tfenv supports "latest:<regex>", of which only the "latest" portion is honored here.
+	if strings.HasPrefix(value, latestVersion+":") {
+		value = latestVersion
+	}
+
+	return value, nil
+}
+
+// readRequiredVersion reads the required_version attribute of the terraform block within a
+// directory's .tf files, returning an empty string if none is set.
+func readRequiredVersion(directory string) (string, error) {
+	fileNames, err := filepath.Glob(filepath.Join(directory, "*.tf"))
+	if err != nil {
+		return "", fmt.Errorf("[filepath.Glob] %w", err)
+	}
+	sort.Strings(fileNames)
+
+	parser := hclparse.NewParser()
+	var constraints []string
+
+	for _, fileName := range fileNames {
+		file, diags := parser.ParseHCLFile(fileName)
+		if diags.HasErrors() {
+			return "", fmt.Errorf("[parser.ParseHCLFile] %v", diags.Error())
+		}
+
+		content, _, _ := file.Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{{Type: "terraform"}},
+		})
+
+		for _, block := range content.Blocks {
+			attributes, _ := block.Body.JustAttributes()
+			attribute, ok := attributes["required_version"]
+			if !ok {
+				continue
+			}
+
+			value, diags := attribute.Expr.Value(nil)
+			if diags.HasErrors() {
+				return "", fmt.Errorf("[attribute.Expr.Value] %v", diags.Error())
+			}
+
+			if value.IsNull() || value.Type() != cty.String {
+				return "", fmt.Errorf("%v: required_version must be a string", fileName)
+			}
+
+			constraints = append(constraints, value.AsString())
+		}
+	}
+
+	return strings.Join(constraints, ", "), nil
+}
+
+// resolveVersionConstraint returns the newest non-prerelease version within releases that
+// satisfies the constraint. Exact versions are returned as-is.
+func resolveVersionConstraint(constraint string, releases []*version.Version) (string, error) {
+	if exactVersionRegex.MatchString(constraint) {
+		return constraint, nil
+	}
+
+	var constraints version.Constraints
+	if constraint != latestVersion {
+		var err error
+		constraints, err = version.NewConstraint(constraint)
+		if err != nil {
+			return "", fmt.Errorf("[version.NewConstraint] %w", err)
+		}
+	}
+
+	for i := len(releases) - 1; i >= 0; i-- {
+		if releases[i].Prerelease() != "" {
+			continue
+		}
+
+		if constraints.Check(releases[i]) {
+			return releases[i].String(), nil
+		}
+	}
+
+	return "", fmt.Errorf("no release satisfies the version constraint %v", constraint)
+}
@@ -0,0 +1,183 @@
+package statemigration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+)
+
+func TestResolveVersionConstraint(t *testing.T) {
+	var releases []*version.Version
+	for _, v := range []string{"1.2.9", "1.3.0", "1.5.0", "1.5.7", "1.6.0-beta1", "2.0.0"} {
+		releases = append(releases, version.Must(version.NewVersion(v)))
+	}
+
+	testCases := map[string]string{
+		"~> 1.5":         "1.5.7",
+		">= 1.3, < 2.0":  "1.5.7",
+		"~> 1.2.0":       "1.2.9",
+		"1.4.2":          "1.4.2",
+		"1.6.0-beta1":    "1.6.0-beta1",
+		"latest":         "2.0.0",
+		"< 1.3.0, > 1.0": "1.2.9",
+	}
+
+	for constraint, expectedOutput := range testCases {
+		output, err := resolveVersionConstraint(constraint, releases)
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", constraint, err)
+		}
+
+		if output != expectedOutput {
+			t.Errorf("got %v, expected %v for constraint %v", output, expectedOutput, constraint)
+		}
+	}
+
+	_, err := resolveVersionConstraint("> 3.0", releases)
+	if err == nil {
+		t.Errorf("expected an error for an unsatisfiable constraint")
+	}
+}
+
+// listingInstaller is a fakeInstaller that lists the releases given.
+type listingInstaller struct {
+	fakeInstaller
+
+	// releases are the versions listed by ListVersions.
+	releases []string
+}
+
+func (li listingInstaller) ListVersions(ctx context.Context) ([]*version.Version, error) {
+	var releases []*version.Version
+	for _, v := range li.releases {
+		releases = append(releases, version.Must(version.NewVersion(v)))
+	}
+
+	return releases, nil
+}
+
+func TestResolveTerraformVersionMajorVersion(t *testing.T) {
+	directory := t.TempDir()
+	err := os.WriteFile(filepath.Join(directory, ".opentofu-version"), []byte("2.0.1\n"), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := map[Version]string{
+		">= 1.3": "",
+		"~> 1.5": "1.5.7",
+		"auto":   "",
+	}
+
+	for requestedVersion, expectedOutput := range testCases {
+		sm := stateMigrator{
+			config:    &Config{Engine: EngineTofu, TerraformVersion: requestedVersion},
+			installer: listingInstaller{releases: []string{"1.5.7", "2.0.0"}},
+		}
+
+		output, err := sm.resolveTerraformVersion(context.Background(), "workspace_1", directory)
+		if expectedOutput == "" && err == nil {
+			t.Errorf("expected an error for %v, which resolves to major version 2, got %v", requestedVersion, output)
+		}
+
+		if output != expectedOutput {
+			t.Errorf("got %v, expected %v for %v", output, expectedOutput, requestedVersion)
+		}
+	}
+}
+
+func TestExtractWorkspaceTerraformVersion(t *testing.T) {
+	jsonBytes := []byte(`{
+		"data" : {
+			"attributes": {"terraform-version": "~> 1.5.0"},
+			"id": "8675309"
+		}
+	}`)
+
+	output, err := extractWorkspaceTerraformVersion(jsonBytes)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if output != "~> 1.5.0" {
+		t.Errorf("got %v, expected '~> 1.5.0'", output)
+	}
+}
+
+func TestReadVersionFile(t *testing.T) {
+	directory := t.TempDir()
+
+	output, err := readVersionFile(directory, ".terraform-version")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if output != "" {
+		t.Errorf("got %v, expected an empty string for a missing file", output)
+	}
+
+	err = os.WriteFile(filepath.Join(directory, ".terraform-version"), []byte("1.4.6\n"), 0600)
+	if err != nil {
+		t.Fatalf("[os.WriteFile] %v", err)
+	}
+
+	output, err = readVersionFile(directory, ".terraform-version")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if output != "1.4.6" {
+		t.Errorf("got %v, expected '1.4.6'", output)
+	}
+}
+
+func TestReadRequiredVersion(t *testing.T) {
+	directory := t.TempDir()
+
+	configuration := []byte(`
+terraform {
+  required_version = ">= 1.3, < 2.0"
+
+  required_providers {
+    google = {
+      source = "hashicorp/google"
+    }
+  }
+}
+
+resource "null_resource" "example" {}
+`)
+
+	err := os.WriteFile(filepath.Join(directory, "main.tf"), configuration, 0600)
+	if err != nil {
+		t.Fatalf("[os.WriteFile] %v", err)
+	}
+
+	output, err := readRequiredVersion(directory)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if output != ">= 1.3, < 2.0" {
+		t.Errorf("got %v, expected '>= 1.3, < 2.0'", output)
+	}
+
+	for _, invalidVersion := range []string{"null", "1.5", `["1.5.7"]`} {
+		configuration = []byte(fmt.Sprintf("terraform {\n  required_version = %v\n}\n", invalidVersion))
+
+		err = os.WriteFile(filepath.Join(directory, "main.tf"), configuration, 0600)
+		if err != nil {
+			t.Fatalf("[os.WriteFile] %v", err)
+		}
+
+		_, err = readRequiredVersion(directory)
+		if err == nil || !strings.Contains(err.Error(), "main.tf") {
+			t.Errorf("got %v, expected an error naming main.tf for required_version = %v", err, invalidVersion)
+		}
+	}
+}
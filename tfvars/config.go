@@ -2,9 +2,13 @@ package tfvars
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/Jeffail/gabs/v2"
 	"github.com/kelseyhightower/envconfig"
+
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/internal/rootdir"
 )
 
 // GroupToVariables is a mapping between a group name and Variables associated with that group.
@@ -24,6 +28,17 @@ type VariableData struct {
 	category string
 }
 
+// TFVarsFormat is the format in which variables files are written.
+type TFVarsFormat string
+
+const (
+	// FormatHCL writes variables as a .tfvars file of HCL.
+	FormatHCL TFVarsFormat = "hcl"
+
+	// FormatJSON writes variables as a .tfvars.json file.
+	FormatJSON TFVarsFormat = "json"
+)
+
 // Config contains the variables needed to support the TFVars interface.
 type Config struct {
 
@@ -44,6 +59,45 @@ type Config struct {
 	// WorkspaceToDirectory is a map between workspace name and the relative directory for a workspace's
 	// configuration.
 	WorkspaceToDirectory map[string]string `required:"true"`
+
+	// RootDirectory is the directory that workspace directories are relative to. It defaults to
+	// GITHUB_WORKSPACE when set, and otherwise the current working directory.
+	RootDirectory string `required:"false"`
+
+	// SensitiveVarsFile is the path of a JSON file of sensitive variables, in the format described
+	// by SensitiveVars. No file is read when empty.
+	SensitiveVarsFile string `required:"false"`
+
+	// SensitiveVarsSOPSFile is the path of a SOPS-encrypted YAML or JSON file of sensitive
+	// variables, in the format described by SensitiveVars. No file is read when empty.
+	SensitiveVarsSOPSFile string `required:"false"`
+
+	// VaultAddress is the address of the Vault server from which sensitive variables are read.
+	// It defaults to VAULT_ADDR.
+	VaultAddress string `required:"false"`
+
+	// VaultToken is the Vault token used to read sensitive variables. It defaults to VAULT_TOKEN.
+	VaultToken string `required:"false"`
+
+	// VaultKVMount is the mount path of the KV version 2 secrets engine holding VaultSecretPath.
+	// It defaults to "secret".
+	VaultKVMount string `required:"false"`
+
+	// VaultSecretPath is the path of the Vault secret of sensitive variables, in the format
+	// described by SensitiveVars. No secret is read from Vault when empty.
+	VaultSecretPath string `required:"false"`
+
+	// IncludeUndeclaredVars is whether Terraform variables that the root module does not declare
+	// are still written to the variables file, which can help when debugging.
+	IncludeUndeclaredVars bool `required:"false" default:"false"`
+
+	// KeepVarsFiles is whether the variables files written are kept once the run ends, rather
+	// than removed.
+	KeepVarsFiles bool `required:"false" default:"false"`
+
+	// TFVarsFormat is the format in which variables files are written, either "hcl" (the default)
+	// or "json".
+	TFVarsFormat TFVarsFormat `required:"false"`
 }
 
 // NewConfig instantiates a new instance of Config
@@ -52,18 +106,74 @@ func NewConfig() (*Config, error) {
 	err := envconfig.Process("", &c)
 
 	if err != nil {
-		return nil, fmt.Errorf("[envconfig.Process] Error loading config: %v", err)
+		return nil, fmt.Errorf("[envconfig.Process] Error loading config: %w", err)
+	}
+
+	if c.RootDirectory == "" {
+		c.RootDirectory, err = rootdir.Default()
+		if err != nil {
+			return nil, fmt.Errorf("[rootdir.Default] %w", err)
+		}
+	}
+
+	if c.VaultAddress == "" {
+		c.VaultAddress = os.Getenv("VAULT_ADDR")
+	}
+
+	if c.VaultToken == "" {
+		c.VaultToken = os.Getenv("VAULT_TOKEN")
+	}
+
+	if c.VaultKVMount == "" {
+		c.VaultKVMount = "secret"
+	}
+
+	if c.TFVarsFormat == "" {
+		c.TFVarsFormat = FormatHCL
 	}
 
 	return &c, err
 }
 
+// Decode parses a string into a TFVarsFormat, defaulting to HCL when empty.
+func (f *TFVarsFormat) Decode(value string) error {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", string(FormatHCL):
+		*f = FormatHCL
+	case string(FormatJSON):
+		*f = FormatJSON
+	default:
+		return fmt.Errorf("tfvars format must be either '%v' or '%v', got %v", FormatHCL, FormatJSON, value)
+	}
+
+	return nil
+}
+
+// Merge combines the contents of two GroupToVariables. If a variable exists within the same group
+// of both, then the VariableData from other is used in the output.
+func (gtv GroupToVariables) Merge(other GroupToVariables) GroupToVariables {
+	combination := GroupToVariables{}
+
+	for _, groupToVariables := range []GroupToVariables{gtv, other} {
+		for group, variables := range groupToVariables {
+			if _, ok := combination[group]; !ok {
+				combination[group] = Variables{}
+			}
+			for varKey, variableData := range variables {
+				combination[group][varKey] = variableData
+			}
+		}
+	}
+
+	return combination
+}
+
 // Decode parses a string variable into the format needed for a GroupToVariables
 // object.
 func (gtv *GroupToVariables) Decode(value string) error {
 	parsedJSON, err := gabs.ParseJSON([]byte(value))
 	if err != nil {
-		return fmt.Errorf("[gabs.ParseJSON] Error parsing JSON: %v", err)
+		return fmt.Errorf("[gabs.ParseJSON] Error parsing JSON: %w", err)
 	}
 
 	groupToVars := GroupToVariables{}
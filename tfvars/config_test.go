@@ -94,3 +94,63 @@ func TestGroupToVariablesDecode(t *testing.T) {
 	}
 
 }
+
+func TestGroupToVariablesMerge(t *testing.T) {
+	input := GroupToVariables{
+		"group_1": {"key_1": {value: "val_1", category: "terraform"}},
+		"group_2": {"key_2": {value: "val_2", category: "env"}},
+	}
+
+	other := GroupToVariables{
+		"group_1": {
+			"key_1": {value: "val_new_1", category: "terraform"},
+			"key_3": {value: "val_3", category: "terraform"},
+		},
+		"group_3": {"key_4": {value: "val_4", category: "env"}},
+	}
+
+	expectedOutput := GroupToVariables{
+		"group_1": {
+			"key_1": {value: "val_new_1", category: "terraform"},
+			"key_3": {value: "val_3", category: "terraform"},
+		},
+		"group_2": {"key_2": {value: "val_2", category: "env"}},
+		"group_3": {"key_4": {value: "val_4", category: "env"}},
+	}
+
+	output := input.Merge(other)
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+
+	if input["group_1"]["key_1"].value != "val_1" {
+		t.Errorf("expected Merge not to modify its receiver")
+	}
+}
+
+func TestTFVarsFormatDecode(t *testing.T) {
+	inputToExpected := map[string]TFVarsFormat{
+		"":       FormatHCL,
+		"hcl":    FormatHCL,
+		" JSON ": FormatJSON,
+	}
+
+	for input, expected := range inputToExpected {
+		var format TFVarsFormat
+
+		err := format.Decode(input)
+		if err != nil {
+			t.Errorf("unexpected error decoding %q: %v", input, err)
+		}
+
+		if format != expected {
+			t.Errorf("got %v, expected %v", format, expected)
+		}
+	}
+
+	var format TFVarsFormat
+	if err := format.Decode("yaml"); err == nil {
+		t.Errorf("expected an error decoding an unknown format")
+	}
+}
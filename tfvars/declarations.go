@@ -0,0 +1,110 @@
+package tfvars
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+// variableBlockSchema matches the variable blocks of a Terraform configuration file.
+var variableBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{{Type: "variable", LabelNames: []string{"name"}}},
+}
+
+// variableDefaultSchema matches the default of a variable block.
+var variableDefaultSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "default"}},
+}
+
+// readDeclaredVariables reads the variables declared by the root module within directory, mapped
+// to whether each is required, having no default. false is returned if the directory has no
+// Terraform configuration files.
+func readDeclaredVariables(directory string) (map[string]bool, bool, error) {
+	var fileNames []string
+	for _, pattern := range []string{"*.tf", "*.tf.json"} {
+		matches, err := filepath.Glob(filepath.Join(directory, pattern))
+		if err != nil {
+			return nil, false, fmt.Errorf("[filepath.Glob] %w", err)
+		}
+		fileNames = append(fileNames, matches...)
+	}
+	sort.Strings(fileNames)
+
+	if len(fileNames) == 0 {
+		return nil, false, nil
+	}
+
+	parser := hclparse.NewParser()
+	declaredVariables := map[string]bool{}
+
+	for _, fileName := range fileNames {
+		var file *hcl.File
+		var diags hcl.Diagnostics
+
+		if strings.HasSuffix(fileName, ".json") {
+			file, diags = parser.ParseJSONFile(fileName)
+		} else {
+			file, diags = parser.ParseHCLFile(fileName)
+		}
+		if diags.HasErrors() {
+			return nil, false, fmt.Errorf("[parser.ParseHCLFile] %w", diags)
+		}
+
+		content, _, diags := file.Body.PartialContent(variableBlockSchema)
+		if diags.HasErrors() {
+			return nil, false, fmt.Errorf("[file.Body.PartialContent] %w", diags)
+		}
+
+		for _, block := range content.Blocks {
+			variableContent, _, diags := block.Body.PartialContent(variableDefaultSchema)
+			if diags.HasErrors() {
+				return nil, false, fmt.Errorf("[block.Body.PartialContent] %w", diags)
+			}
+
+			// An override file may add a default to a variable declared elsewhere.
+			_, hasDefault := variableContent.Attributes["default"]
+			required, declared := declaredVariables[block.Labels[0]]
+			declaredVariables[block.Labels[0]] = !hasDefault && (required || !declared)
+		}
+	}
+
+	return declaredVariables, true, nil
+}
+
+// removeUndeclaredVariables removes the variables that the root module does not declare, returning
+// the names of those removed, sorted alphabetically.
+func removeUndeclaredVariables(
+	variables VariableMap, variableSources map[string]string, declaredVariables map[string]bool,
+) []string {
+	var undeclared []string
+
+	for varKey := range variableSources {
+		if _, ok := declaredVariables[varKey]; !ok {
+			undeclared = append(undeclared, varKey)
+			delete(variables, varKey)
+			delete(variableSources, varKey)
+		}
+	}
+	sort.Strings(undeclared)
+
+	return undeclared
+}
+
+// findUnsetRequiredVariables returns the names of the required variables that no source sets,
+// sorted alphabetically. Withheld sensitive variables have a source and are reported separately.
+func findUnsetRequiredVariables(variableSources map[string]string, declaredVariables map[string]bool) []string {
+	var unset []string
+
+	for varKey, required := range declaredVariables {
+		if _, ok := variableSources[varKey]; required && !ok {
+			unset = append(unset, varKey)
+		}
+	}
+	sort.Strings(unset)
+
+	return unset
+}
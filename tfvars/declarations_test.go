@@ -0,0 +1,131 @@
+package tfvars
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadDeclaredVariables(t *testing.T) {
+	directory := t.TempDir()
+
+	files := map[string]string{
+		"variables.tf": `
+variable "region" {
+  type = string
+}
+
+variable "tier" {
+  type    = string
+  default = "small"
+}
+
+variable "zone" {}
+
+resource "null_resource" "example" {}
+`,
+		"variables.tf.json": `{"variable": {"owner": {"type": "string"}, "size": {"default": 1}}}`,
+		"zone_override.tf": `
+variable "zone" {
+  default = "a"
+}
+`,
+		"notes.txt": `variable "ignored" {}`,
+	}
+
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(directory, name), []byte(content), 0600)
+		if err != nil {
+			t.Fatalf("unexpected error writing %v: %v", name, err)
+		}
+	}
+
+	expectedDeclared := map[string]bool{
+		"owner":  true,
+		"region": true,
+		"size":   false,
+		"tier":   false,
+		"zone":   false,
+	}
+
+	outputDeclared, found, err := readDeclaredVariables(directory)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !found {
+		t.Errorf("got %v, expected %v", found, true)
+	}
+
+	if !reflect.DeepEqual(outputDeclared, expectedDeclared) {
+		t.Errorf("got %v, expected %v", outputDeclared, expectedDeclared)
+	}
+}
+
+func TestReadDeclaredVariablesNoConfiguration(t *testing.T) {
+	outputDeclared, found, err := readDeclaredVariables(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if found || outputDeclared != nil {
+		t.Errorf("got %v and %v, expected no declared variables", outputDeclared, found)
+	}
+}
+
+func TestReadDeclaredVariablesInvalid(t *testing.T) {
+	directory := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(directory, "main.tf"), []byte(`variable "region" {`), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error writing main.tf: %v", err)
+	}
+
+	_, _, err = readDeclaredVariables(directory)
+	if err == nil {
+		t.Errorf("expected an error for an invalid configuration file")
+	}
+}
+
+func TestRemoveUndeclaredVariables(t *testing.T) {
+	inputVariables := VariableMap{"region": `"us-east1"`, "shared": `"value"`, "tier": `"small"`}
+	inputSources := map[string]string{
+		"region":   "workspace",
+		"shared":   "varset:var_set_1",
+		"tier":     "varset:var_set_2",
+		"withheld": "varset:var_set_1",
+	}
+	declaredVariables := map[string]bool{"region": true, "tier": false}
+
+	expectedVariables := VariableMap{"region": `"us-east1"`, "tier": `"small"`}
+	expectedSources := map[string]string{"region": "workspace", "tier": "varset:var_set_2"}
+	expectedUndeclared := []string{"shared", "withheld"}
+
+	outputUndeclared := removeUndeclaredVariables(inputVariables, inputSources, declaredVariables)
+
+	if !reflect.DeepEqual(outputUndeclared, expectedUndeclared) {
+		t.Errorf("got %v, expected %v", outputUndeclared, expectedUndeclared)
+	}
+
+	if !reflect.DeepEqual(inputVariables, expectedVariables) {
+		t.Errorf("got %v, expected %v", inputVariables, expectedVariables)
+	}
+
+	if !reflect.DeepEqual(inputSources, expectedSources) {
+		t.Errorf("got %v, expected %v", inputSources, expectedSources)
+	}
+}
+
+func TestFindUnsetRequiredVariables(t *testing.T) {
+	inputSources := map[string]string{"region": "workspace", "password": "varset:var_set_1"}
+	declaredVariables := map[string]bool{"owner": true, "password": true, "region": true, "tier": false, "zone": true}
+
+	expectedUnset := []string{"owner", "zone"}
+
+	outputUnset := findUnsetRequiredVariables(inputSources, declaredVariables)
+
+	if !reflect.DeepEqual(outputUnset, expectedUnset) {
+		t.Errorf("got %v, expected %v", outputUnset, expectedUnset)
+	}
+}
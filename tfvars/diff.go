@@ -0,0 +1,249 @@
+package tfvars
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// VariableChange is how a variable differs between two sets of variables.
+type VariableChange string
+
+const (
+	// ChangeAdded variables are only within the second set of variables.
+	ChangeAdded VariableChange = "added"
+
+	// ChangeRemoved variables are only within the first set of variables.
+	ChangeRemoved VariableChange = "removed"
+
+	// ChangeChanged variables are within both sets of variables, with different values.
+	ChangeChanged VariableChange = "changed"
+)
+
+// VariableDifference is a variable that differs between two sets of variables.
+type VariableDifference struct {
+
+	// Key is the name of the variable.
+	Key string `json:"key"`
+
+	// Category is either "terraform" or "env".
+	Category string `json:"category"`
+
+	// Change is how the variable differs.
+	Change VariableChange `json:"change"`
+
+	// From is the variable within the first set of variables, nil if it was added.
+	From *ExportedVariable `json:"from"`
+
+	// To is the variable within the second set of variables, nil if it was removed.
+	To *ExportedVariable `json:"to"`
+}
+
+// Sensitive is whether the variable is sensitive within either set of variables.
+func (vd VariableDifference) Sensitive() bool {
+	return (vd.From != nil && vd.From.Sensitive) || (vd.To != nil && vd.To.Sensitive)
+}
+
+// DiffVariables returns the variables that were added, removed, or changed between from and to,
+// with Terraform variables before environment variables, each sorted by key. Values are compared
+// as Terraform reads them, so a number within a .tfvars file matches the same number held as a
+// string by Terraform Cloud. As the values of withheld variables are unknown, a variable withheld
+// on only one side is reported as changed.
+func DiffVariables(from []ExportedVariable, to []ExportedVariable) []VariableDifference {
+	type variableID struct {
+		category string
+		key      string
+	}
+
+	fromVariables := map[variableID]ExportedVariable{}
+	for _, variable := range from {
+		fromVariables[variableID{variable.Category, variable.Key}] = variable
+	}
+
+	toVariables := map[variableID]ExportedVariable{}
+	for _, variable := range to {
+		toVariables[variableID{variable.Category, variable.Key}] = variable
+	}
+
+	differences := []VariableDifference{}
+
+	for id, fromVariable := range fromVariables {
+		fromVariable := fromVariable
+
+		toVariable, ok := toVariables[id]
+		switch {
+		case !ok:
+			differences = append(differences, VariableDifference{
+				Key: id.key, Category: id.category, Change: ChangeRemoved, From: &fromVariable,
+			})
+		case !sameValue(fromVariable, toVariable):
+			differences = append(differences, VariableDifference{
+				Key: id.key, Category: id.category, Change: ChangeChanged, From: &fromVariable, To: &toVariable,
+			})
+		}
+	}
+
+	for id, toVariable := range toVariables {
+		toVariable := toVariable
+
+		if _, ok := fromVariables[id]; !ok {
+			differences = append(differences, VariableDifference{
+				Key: id.key, Category: id.category, Change: ChangeAdded, To: &toVariable,
+			})
+		}
+	}
+
+	sort.Slice(differences, func(i, j int) bool {
+		if differences[i].Category != differences[j].Category {
+			return differences[i].Category == "terraform"
+		}
+		return differences[i].Key < differences[j].Key
+	})
+
+	return differences
+}
+
+// sameValue reports whether two variables hold the same value.
+func sameValue(a ExportedVariable, b ExportedVariable) bool {
+	if a.Withheld || b.Withheld {
+		return a.Withheld && b.Withheld
+	}
+
+	return canonicalValue(a) == canonicalValue(b)
+}
+
+// canonicalValue returns the value of a variable such that equal values are written alike: strings,
+// numbers, and booleans as written within a string, and all other values as JSON.
+func canonicalValue(variable ExportedVariable) string {
+	if !variable.HCL {
+		return variable.Value
+	}
+
+	jsonValue, err := variableJSONValue(variable.Key, variable.Value, variable.HCL)
+	if err != nil {
+		return variable.Value
+	}
+
+	switch value := jsonValue.(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	default:
+		jsonBytes, err := encodeJSON(value)
+		if err != nil {
+			return variable.Value
+		}
+		return string(jsonBytes)
+	}
+}
+
+// MaskSensitiveDifferences returns a copy of the differences in which the values of variables that
+// are sensitive on either side are masked.
+func MaskSensitiveDifferences(differences []VariableDifference) []VariableDifference {
+	masked := make([]VariableDifference, 0, len(differences))
+
+	for _, difference := range differences {
+		if difference.Sensitive() {
+			difference.From = maskedVariable(difference.From)
+			difference.To = maskedVariable(difference.To)
+		}
+		masked = append(masked, difference)
+	}
+
+	return masked
+}
+
+// maskedVariable returns a copy of the variable with its value masked, unless it has no value.
+func maskedVariable(variable *ExportedVariable) *ExportedVariable {
+	if variable == nil {
+		return nil
+	}
+
+	masked := *variable
+	if !masked.Withheld {
+		masked.Value = maskedValue
+		masked.Masked = true
+	}
+
+	return &masked
+}
+
+// DisplayValue writes the value of a variable as it is shown within a diff: strings quoted, HCL
+// expressions as written, and withheld values as (withheld).
+func (ev ExportedVariable) DisplayValue() string {
+	switch {
+	case ev.Withheld:
+		return "(withheld)"
+	case ev.Masked:
+		return ev.Value
+	case ev.HCL:
+		return strings.Join(strings.Fields(ev.Value), " ")
+	default:
+		return strconv.Quote(ev.Value)
+	}
+}
+
+// ReadTFVarsFile reads the Terraform variables of a .tfvars or .tfvars.json file, whose values must
+// be literal values. Strings, numbers, and booleans are read as strings, and all other values as
+// HCL expressions, as they are held within Terraform Cloud.
+func ReadTFVarsFile(path string) ([]ExportedVariable, error) {
+	parser := hclparse.NewParser()
+
+	var file *hcl.File
+	var diags hcl.Diagnostics
+
+	if strings.HasSuffix(path, ".json") {
+		file, diags = parser.ParseJSONFile(path)
+	} else {
+		file, diags = parser.ParseHCLFile(path)
+	}
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("[parser.ParseHCLFile] %w", diags)
+	}
+
+	attributes, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("[file.Body.JustAttributes] %w", diags)
+	}
+
+	variables := make([]ExportedVariable, 0, len(attributes))
+	for varKey, attribute := range attributes {
+		value, diags := attribute.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("the variable %v must only contain literal values: %w", varKey, diags)
+		}
+
+		variable := ExportedVariable{Key: varKey, Category: "terraform", Source: "file:" + path}
+
+		switch {
+		case value.IsNull():
+			variable.Value, variable.HCL = "null", true
+		case value.Type() == cty.String:
+			variable.Value = value.AsString()
+		case value.Type() == cty.Number:
+			variable.Value = value.AsBigFloat().Text('f', -1)
+		case value.Type() == cty.Bool:
+			variable.Value = strconv.FormatBool(value.True())
+		default:
+			variable.Value, variable.HCL = string(hclwrite.TokensForValue(value).Bytes()), true
+		}
+
+		variables = append(variables, variable)
+	}
+
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].Key < variables[j].Key
+	})
+
+	return variables, nil
+}
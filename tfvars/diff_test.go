@@ -0,0 +1,160 @@
+package tfvars
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiffVariables(t *testing.T) {
+	inputFrom := []ExportedVariable{
+		{Key: "region", Category: "terraform", Value: "us-east1", Source: "varset:global"},
+		{Key: "count", Category: "terraform", Value: "3", Source: "workspace"},
+		{Key: "tags", Category: "terraform", Value: `{ team = "platform", tier = 1 }`, Source: "workspace", HCL: true},
+		{Key: "db_password", Category: "terraform", Source: "workspace", Sensitive: true, Withheld: true},
+		{Key: "api_key", Category: "terraform", Source: "workspace", Sensitive: true, Withheld: true},
+		{Key: "TOKEN", Category: "env", Value: "abc", Source: "workspace", Sensitive: true},
+		{Key: "region", Category: "env", Value: "us-east1", Source: "workspace"},
+	}
+
+	inputTo := []ExportedVariable{
+		{Key: "region", Category: "terraform", Value: "us-west1", Source: "workspace"},
+		{Key: "count", Category: "terraform", Value: "3", Source: "file:prod.tfvars", HCL: true},
+		{Key: "tags", Category: "terraform", Value: "{\n  tier = 1\n  team = \"platform\"\n}", Source: "workspace", HCL: true},
+		{Key: "db_password", Category: "terraform", Source: "workspace", Sensitive: true, Withheld: true},
+		{Key: "api_key", Category: "terraform", Value: "xyz", Source: "workspace", Sensitive: true},
+		{Key: "zone", Category: "terraform", Value: "b", Source: "varset:global"},
+		{Key: "region", Category: "env", Value: "us-east1", Source: "workspace"},
+	}
+
+	expectedOutput := []VariableDifference{
+		{Key: "api_key", Category: "terraform", Change: ChangeChanged, From: &inputFrom[4], To: &inputTo[4]},
+		{Key: "region", Category: "terraform", Change: ChangeChanged, From: &inputFrom[0], To: &inputTo[0]},
+		{Key: "zone", Category: "terraform", Change: ChangeAdded, To: &inputTo[5]},
+		{Key: "TOKEN", Category: "env", Change: ChangeRemoved, From: &inputFrom[5]},
+	}
+
+	output := DiffVariables(inputFrom, inputTo)
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+}
+
+func TestMaskSensitiveDifferences(t *testing.T) {
+	from := ExportedVariable{Key: "api_key", Category: "terraform", Value: "abc", Source: "workspace"}
+	to := ExportedVariable{Key: "api_key", Category: "terraform", Value: "xyz", Source: "workspace", Sensitive: true}
+	withheld := ExportedVariable{Key: "db_password", Category: "terraform", Source: "workspace", Sensitive: true, Withheld: true}
+	region := ExportedVariable{Key: "region", Category: "terraform", Value: "us-east1", Source: "workspace"}
+
+	input := []VariableDifference{
+		{Key: "api_key", Category: "terraform", Change: ChangeChanged, From: &from, To: &to},
+		{Key: "db_password", Category: "terraform", Change: ChangeRemoved, From: &withheld},
+		{Key: "region", Category: "terraform", Change: ChangeAdded, To: &region},
+	}
+
+	expectedFrom := ExportedVariable{Key: "api_key", Category: "terraform", Value: "***", Source: "workspace", Masked: true}
+	expectedTo := ExportedVariable{
+		Key: "api_key", Category: "terraform", Value: "***", Source: "workspace", Sensitive: true, Masked: true,
+	}
+
+	expectedOutput := []VariableDifference{
+		{Key: "api_key", Category: "terraform", Change: ChangeChanged, From: &expectedFrom, To: &expectedTo},
+		{Key: "db_password", Category: "terraform", Change: ChangeRemoved, From: &withheld},
+		{Key: "region", Category: "terraform", Change: ChangeAdded, To: &region},
+	}
+
+	output := MaskSensitiveDifferences(input)
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+
+	if from.Value != "abc" || to.Value != "xyz" {
+		t.Errorf("expected the input not to be modified, got %v and %v", from.Value, to.Value)
+	}
+}
+
+func TestExportedVariableDisplayValue(t *testing.T) {
+	inputToExpected := map[string]ExportedVariable{
+		`"us-east1"`:               {Value: "us-east1"},
+		`{ team = "platform" }`:    {Value: "{\n  team = \"platform\"\n}", HCL: true},
+		"***":                      {Value: "***", Sensitive: true, Masked: true},
+		"(withheld)":               {Sensitive: true, Withheld: true},
+		`"line one\nline \"two\""`: {Value: "line one\nline \"two\""},
+	}
+
+	for expected, input := range inputToExpected {
+		if output := input.DisplayValue(); output != expected {
+			t.Errorf("got %v, expected %v", output, expected)
+		}
+	}
+}
+
+func TestReadTFVarsFile(t *testing.T) {
+	directory := t.TempDir()
+
+	hclPath := filepath.Join(directory, "prod.tfvars")
+	err := os.WriteFile(hclPath, []byte(`
+region  = "us-west1"
+count   = 3
+enabled = true
+ratio   = 0.5
+zones   = ["a", "b"]
+owner   = null
+`), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error writing %v: %v", hclPath, err)
+	}
+
+	source := "file:" + hclPath
+	expectedOutput := []ExportedVariable{
+		{Key: "count", Category: "terraform", Value: "3", Source: source},
+		{Key: "enabled", Category: "terraform", Value: "true", Source: source},
+		{Key: "owner", Category: "terraform", Value: "null", Source: source, HCL: true},
+		{Key: "ratio", Category: "terraform", Value: "0.5", Source: source},
+		{Key: "region", Category: "terraform", Value: "us-west1", Source: source},
+		{Key: "zones", Category: "terraform", Value: `["a", "b"]`, Source: source, HCL: true},
+	}
+
+	output, err := ReadTFVarsFile(hclPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+
+	jsonPath := filepath.Join(directory, "prod.tfvars.json")
+	err = os.WriteFile(jsonPath, []byte(`{"region": "us-west1", "tags": {"team": "platform"}}`), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error writing %v: %v", jsonPath, err)
+	}
+
+	output, err = ReadTFVarsFile(jsonPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The object from JSON matches the same object held as HCL by Terraform Cloud.
+	differences := DiffVariables(output, []ExportedVariable{
+		{Key: "region", Category: "terraform", Value: "us-west1", Source: "workspace"},
+		{Key: "tags", Category: "terraform", Value: `{ team = "platform" }`, Source: "workspace", HCL: true},
+	})
+	if len(differences) != 0 {
+		t.Errorf("got %v, expected no differences", differences)
+	}
+
+	invalidPath := filepath.Join(directory, "invalid.tfvars")
+	err = os.WriteFile(invalidPath, []byte(`region = var.default_region`), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error writing %v: %v", invalidPath, err)
+	}
+
+	_, err = ReadTFVarsFile(invalidPath)
+	if err == nil {
+		t.Errorf("expected an error for a variable that references another variable")
+	}
+}
@@ -0,0 +1,131 @@
+package tfvars
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/internal/tfcapi"
+)
+
+// Sentinel errors identifying why variables could not be pulled, for use with errors.Is.
+var (
+	// ErrUnauthorized is returned when Terraform Cloud rejects the token as invalid or expired.
+	ErrUnauthorized = errors.New("the Terraform Cloud token is invalid or expired")
+
+	// ErrForbidden is returned when the token is valid but lacks permission for a request.
+	ErrForbidden = errors.New("the Terraform Cloud token lacks permission")
+
+	// ErrNotFound is returned when a workspace or variable set does not exist, or the token cannot see it.
+	ErrNotFound = errors.New("not found in Terraform Cloud")
+
+	// ErrMissingSensitiveVariables is returned when Terraform Cloud withholds the values of sensitive
+	// variables that have not been supplied.
+	ErrMissingSensitiveVariables = errors.New("sensitive variables have not been supplied")
+)
+
+// MissingSensitiveVariable is a sensitive variable whose value Terraform Cloud withholds and that
+// has not been supplied.
+type MissingSensitiveVariable struct {
+
+	// Workspace is the name of the workspace that the variable applies to.
+	Workspace string
+
+	// Key is the name of the variable.
+	Key string
+
+	// Category is the category of the variable, either "terraform" or "env".
+	Category string
+
+	// Source is where the variable is defined, either "workspace" or "varset:<name>".
+	Source string
+}
+
+// ConfigPath is where the variable should be supplied within the sensitive variables config.
+func (v MissingSensitiveVariable) ConfigPath() string {
+	if varSet, ok := strings.CutPrefix(v.Source, "varset:"); ok {
+		return fmt.Sprintf("terraform-var-set-sensitive-vars[%q][%q]", varSet, v.Key)
+	}
+
+	return fmt.Sprintf("terraform-workspace-sensitive-vars[%q][%q]", v.Workspace, v.Key)
+}
+
+// MissingSensitiveVariablesError is returned when Terraform Cloud withholds the values of sensitive
+// variables that have not been supplied, listing every such variable.
+type MissingSensitiveVariablesError struct {
+
+	// Variables are the missing variables, in the order in which they were found.
+	Variables []MissingSensitiveVariable
+}
+
+// Error implements the error interface.
+func (e *MissingSensitiveVariablesError) Error() string {
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "%v sensitive variables are withheld by Terraform Cloud and have not been supplied:", len(e.Variables))
+	for _, variable := range e.Variables {
+		fmt.Fprintf(
+			&builder, "\n  workspace %v: %v (%v, from %v), supply it as %v",
+			variable.Workspace, variable.Key, variable.Category, variable.Source, variable.ConfigPath(),
+		)
+	}
+	builder.WriteString("\nVariables may also be supplied under \"workspaces\" or \"var_sets\" of a sensitive variables file or Vault secret.")
+
+	return builder.String()
+}
+
+// Unwrap returns ErrMissingSensitiveVariables.
+func (e *MissingSensitiveVariablesError) Unwrap() error {
+	return ErrMissingSensitiveVariables
+}
+
+// APIError is returned when the Terraform Cloud API responds with an unsuccessful status code.
+type APIError struct {
+
+	// APIError is the unsuccessful request and the response to it.
+	tfcapi.APIError
+}
+
+// apiErrorHints explain the likely cause of an unsuccessful status code for a request.
+var apiErrorHints = map[string]map[int]string{
+	"getWorkspaceID": {
+		http.StatusNotFound: "check that the workspace exists in the organization and that the token can access it",
+	},
+	"getWorkspaceVars": {
+		http.StatusForbidden: "the token needs read-variables access to the workspace",
+		http.StatusNotFound:  "the token needs read-variables access to the workspace",
+	},
+	"getAllVarSetIds": {
+		http.StatusForbidden: "the token needs permission to read the organization's variable sets",
+		http.StatusNotFound:  "the token needs permission to read the organization's variable sets",
+	},
+	"getVarSetVars": {
+		http.StatusForbidden: "the token needs read-variables access to the variable set",
+		http.StatusNotFound:  "the token needs read-variables access to the variable set",
+	},
+	"getWorkspaceVarSets": {
+		http.StatusForbidden: "the token needs read-variables access to the workspace",
+		http.StatusNotFound:  "the token needs read-variables access to the workspace",
+	},
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	return e.Message(apiErrorHints)
+}
+
+// Unwrap returns the sentinel error for the status code, so that errors.Is(err, ErrNotFound) and
+// similar identify the failure.
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	default:
+		return nil
+	}
+}
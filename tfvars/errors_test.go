@@ -0,0 +1,73 @@
+package tfvars
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/internal/tfcapi"
+)
+
+func TestAPIErrorSentinels(t *testing.T) {
+	testCases := []struct {
+		statusCode int
+		expected   error
+	}{
+		{statusCode: http.StatusUnauthorized, expected: ErrUnauthorized},
+		{statusCode: http.StatusForbidden, expected: ErrForbidden},
+		{statusCode: http.StatusNotFound, expected: ErrNotFound},
+	}
+
+	for _, testCase := range testCases {
+		err := fmt.Errorf("[tfc.getWorkspaceVariables] %w", &APIError{APIError: tfcapi.APIError{Request: "getWorkspaceVars", StatusCode: testCase.statusCode}})
+
+		if !errors.Is(err, testCase.expected) {
+			t.Errorf("expected status code %v to be %v", testCase.statusCode, testCase.expected)
+		}
+	}
+
+	var apiErr *APIError
+	err := fmt.Errorf("[tfc.getVarSetVars] %w", &APIError{APIError: tfcapi.APIError{Request: "getVarSetVars", StatusCode: http.StatusBadGateway, Body: "bad gateway"}})
+	if !errors.As(err, &apiErr) || apiErr.Body != "bad gateway" {
+		t.Errorf("expected errors.As to find the APIError, got %v", apiErr)
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	err := &APIError{APIError: tfcapi.APIError{
+		Request:    "getVarSetVars",
+		Method:     "GET",
+		Path:       "/api/v2/varsets/varset-123/relationships/vars",
+		StatusCode: http.StatusNotFound,
+		Errors:     []tfcapi.ErrorDetail{{Title: "not found"}},
+	}}
+
+	expectedMessage := "[getVarSetVars] GET /api/v2/varsets/varset-123/relationships/vars was unsuccessful, with " +
+		"the server returning: 404: not found (hint: the token needs read-variables access to the variable set)"
+	if err.Error() != expectedMessage {
+		t.Errorf("got %v, expected %v", err.Error(), expectedMessage)
+	}
+}
+
+func TestMissingSensitiveVariablesError(t *testing.T) {
+	err := fmt.Errorf("[tfc.CreateAllWorkspaceVarsFiles] %w", &MissingSensitiveVariablesError{
+		Variables: []MissingSensitiveVariable{
+			{Workspace: "workspace_1", Key: "db_password", Category: "terraform", Source: "workspace"},
+			{Workspace: "workspace_1", Key: "AWS_SECRET_ACCESS_KEY", Category: "env", Source: "varset:shared"},
+		},
+	})
+
+	if !errors.Is(err, ErrMissingSensitiveVariables) {
+		t.Errorf("expected %v to be %v", err, ErrMissingSensitiveVariables)
+	}
+
+	expectedMessage := "[tfc.CreateAllWorkspaceVarsFiles] 2 sensitive variables are withheld by Terraform Cloud and have not been supplied:\n" +
+		"  workspace workspace_1: db_password (terraform, from workspace), supply it as terraform-workspace-sensitive-vars[\"workspace_1\"][\"db_password\"]\n" +
+		"  workspace workspace_1: AWS_SECRET_ACCESS_KEY (env, from varset:shared), supply it as terraform-var-set-sensitive-vars[\"shared\"][\"AWS_SECRET_ACCESS_KEY\"]\n" +
+		"Variables may also be supplied under \"workspaces\" or \"var_sets\" of a sensitive variables file or Vault secret."
+
+	if err.Error() != expectedMessage {
+		t.Errorf("got %v, expected %v", err.Error(), expectedMessage)
+	}
+}
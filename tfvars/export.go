@@ -0,0 +1,337 @@
+package tfvars
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ExportFormat is the format in which exported variables are written.
+type ExportFormat string
+
+const (
+	// ExportTFVars writes the Terraform variables of each workspace as HCL, as in a .tfvars file.
+	ExportTFVars ExportFormat = "tfvars"
+
+	// ExportJSON writes the Terraform and environment variables of each workspace as JSON.
+	ExportJSON ExportFormat = "json"
+
+	// ExportDotenv writes every variable as a line of a .env file, with Terraform variables
+	// prefixed by TF_VAR_.
+	ExportDotenv ExportFormat = "dotenv"
+
+	// ExportShell writes every variable as a shell export statement, with Terraform variables
+	// prefixed by TF_VAR_.
+	ExportShell ExportFormat = "shell"
+)
+
+// maskedValue replaces the values of sensitive variables that are not revealed.
+const maskedValue = "***"
+
+// Decode parses a string into an ExportFormat, defaulting to tfvars when empty.
+func (f *ExportFormat) Decode(value string) error {
+	switch format := ExportFormat(strings.ToLower(strings.TrimSpace(value))); format {
+	case "":
+		*f = ExportTFVars
+	case ExportTFVars, ExportJSON, ExportDotenv, ExportShell:
+		*f = format
+	default:
+		return fmt.Errorf(
+			"export format must be one of %v, %v, %v, or %v, got %v",
+			ExportTFVars, ExportJSON, ExportDotenv, ExportShell, value,
+		)
+	}
+
+	return nil
+}
+
+// ExportedVariable is a variable as it applies to a workspace, once the precedence of its sources
+// is resolved.
+type ExportedVariable struct {
+
+	// Key is the name of the variable.
+	Key string `json:"key"`
+
+	// Category is either "terraform" or "env".
+	Category string `json:"category"`
+
+	// Value is the value of the variable, empty if it is withheld.
+	Value string `json:"value"`
+
+	// Source is the source the value was taken from, either "workspace" or "varset:<name>".
+	Source string `json:"source"`
+
+	// Sensitive is whether the variable is sensitive within Terraform Cloud or was supplied as such.
+	Sensitive bool `json:"sensitive"`
+
+	// HCL is whether the value is an HCL expression rather than a string.
+	HCL bool `json:"hcl"`
+
+	// Masked is whether the value has been replaced so that it is not revealed.
+	Masked bool `json:"masked"`
+
+	// Withheld is whether the variable is sensitive and its value has not been supplied.
+	Withheld bool `json:"withheld"`
+}
+
+// ExportWorkspaceVariables resolves the variables of each workspace, as CreateAllWorkspaceVarsFiles
+// does, without writing them anywhere. Every configured workspace is exported when workspaces is
+// empty. Withheld sensitive variables are exported without a value rather than failing.
+func (tfc *tfCloud) ExportWorkspaceVariables(
+	ctx context.Context, workspaces []string,
+) (map[string][]ExportedVariable, error) {
+	workspaceToVariables := map[string][]ExportedVariable{}
+
+	if tfc.config.TerraformCloudToken == "null" {
+		slog.Warn("Job kicked off in test-mode (TerraformCloudToken == 'null').")
+		return workspaceToVariables, nil
+	}
+
+	if len(workspaces) == 0 {
+		workspaces = tfc.workspaces()
+	}
+
+	err := tfc.loadSecretSources(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("[tfc.loadSecretSources] %w", err)
+	}
+
+	workspaceToVarSetSources, err := tfc.getWorkspaceToVarSetSources(ctx, workspaces)
+	if err != nil {
+		return nil, fmt.Errorf("[tfc.getWorkspaceToVarSetSources] %w", err)
+	}
+
+	for _, workspace := range workspaces {
+		resolved, err := tfc.resolveWorkspaceVariables(ctx, workspace, workspaceToVarSetSources[workspace])
+		if err != nil {
+			return nil, fmt.Errorf("[tfc.resolveWorkspaceVariables] Error in workspace %v: %w", workspace, err)
+		}
+
+		workspaceToVariables[workspace] = resolved.exportedVariables()
+	}
+
+	return workspaceToVariables, nil
+}
+
+// exportedVariables lists the Terraform variables and then the environment variables, each
+// sorted by key.
+func (rv resolvedVariables) exportedVariables() []ExportedVariable {
+	variables := []ExportedVariable{}
+
+	variables = append(variables, exportedVariablesOf(
+		"terraform", rv.terraform, rv.terraformSources, rv.terraformHCL, rv.terraformSensitive,
+	)...)
+	variables = append(variables, exportedVariablesOf(
+		"env", rv.env, rv.envSources, map[string]bool{}, rv.envSensitive,
+	)...)
+
+	return variables
+}
+
+// exportedVariablesOf lists the variables of a category, sorted by key. Every variable has a
+// source, including withheld variables, which have no value.
+func exportedVariablesOf(
+	category string,
+	variables VariableMap,
+	variableSources map[string]string,
+	hclVariables map[string]bool,
+	sensitiveVariables map[string]bool,
+) []ExportedVariable {
+	keys := make([]string, 0, len(variableSources))
+	for varKey := range variableSources {
+		keys = append(keys, varKey)
+	}
+	sort.Strings(keys)
+
+	exported := make([]ExportedVariable, 0, len(keys))
+	for _, varKey := range keys {
+		value, ok := variables[varKey]
+
+		exported = append(exported, ExportedVariable{
+			Key:       varKey,
+			Category:  category,
+			Value:     value,
+			Source:    variableSources[varKey],
+			Sensitive: sensitiveVariables[varKey],
+			HCL:       hclVariables[varKey],
+			Withheld:  !ok,
+		})
+	}
+
+	return exported
+}
+
+// MaskSensitiveValues returns a copy of the variables of each workspace in which the values of
+// sensitive variables are masked.
+func MaskSensitiveValues(workspaceToVariables map[string][]ExportedVariable) map[string][]ExportedVariable {
+	masked := map[string][]ExportedVariable{}
+
+	for workspace, variables := range workspaceToVariables {
+		maskedVariables := make([]ExportedVariable, 0, len(variables))
+
+		for _, variable := range variables {
+			if variable.Sensitive && !variable.Withheld {
+				variable.Value = maskedValue
+				variable.Masked = true
+			}
+			maskedVariables = append(maskedVariables, variable)
+		}
+
+		masked[workspace] = maskedVariables
+	}
+
+	return masked
+}
+
+// FormatExportedVariables writes the variables of each workspace in the format given, with
+// workspaces sorted by name. Every format other than JSON records the source of each variable
+// within a comment.
+func FormatExportedVariables(
+	workspaceToVariables map[string][]ExportedVariable, format ExportFormat,
+) ([]byte, error) {
+	if format == ExportJSON {
+		return formatExportedJSON(workspaceToVariables)
+	}
+
+	workspaces := make([]string, 0, len(workspaceToVariables))
+	for workspace := range workspaceToVariables {
+		workspaces = append(workspaces, workspace)
+	}
+	sort.Strings(workspaces)
+
+	var buffer bytes.Buffer
+
+	for i, workspace := range workspaces {
+		if i > 0 {
+			buffer.WriteString("\n")
+		}
+		fmt.Fprintf(&buffer, "# workspace: %v\n", workspace)
+
+		for _, variable := range workspaceToVariables[workspace] {
+			if format == ExportTFVars && variable.Category != "terraform" {
+				continue
+			}
+
+			if variable.Withheld {
+				fmt.Fprintf(&buffer, "# %v: %v, sensitive, not supplied\n", variable.Key, exportComment(variable))
+				continue
+			}
+			fmt.Fprintf(&buffer, "# %v\n", exportComment(variable))
+
+			line, err := formatExportedVariable(variable, format)
+			if err != nil {
+				return nil, fmt.Errorf("[formatExportedVariable] %w", err)
+			}
+			buffer.Write(line)
+		}
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// exportComment describes where a variable's value was taken from.
+func exportComment(variable ExportedVariable) string {
+	if variable.Sensitive && !variable.Withheld {
+		return variable.Source + ", sensitive"
+	}
+
+	return variable.Source
+}
+
+// formatExportedVariable writes a single variable, ending with a newline, in a format other than JSON.
+func formatExportedVariable(variable ExportedVariable, format ExportFormat) ([]byte, error) {
+	name := variable.Key
+	if variable.Category == "terraform" {
+		name = "TF_VAR_" + variable.Key
+	}
+
+	switch format {
+	case ExportTFVars:
+		value := cty.StringVal(variable.Value)
+		if !variable.Masked {
+			var err error
+			value, err = variableValue(variable.Key, variable.Value, variable.HCL)
+			if err != nil {
+				return nil, fmt.Errorf("[variableValue] %w", err)
+			}
+		}
+
+		f := hclwrite.NewEmptyFile()
+		f.Body().SetAttributeValue(variable.Key, value)
+		return f.Bytes(), nil
+	case ExportDotenv:
+		escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, `$`, `\$`).Replace(variable.Value)
+		return []byte(fmt.Sprintf("%v=\"%v\"\n", name, escaped)), nil
+	case ExportShell:
+		escaped := strings.ReplaceAll(variable.Value, `'`, `'\''`)
+		return []byte(fmt.Sprintf("export %v='%v'\n", name, escaped)), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %v", format)
+	}
+}
+
+// exportedJSONVariable is a variable as it is written in the JSON export format.
+type exportedJSONVariable struct {
+
+	// Value is the value of the variable, as Terraform reads it unless masked, and null if withheld.
+	Value interface{} `json:"value"`
+
+	// Source is the source the value was taken from.
+	Source string `json:"source"`
+
+	// Sensitive is whether the variable is sensitive.
+	Sensitive bool `json:"sensitive"`
+
+	// Withheld is whether the variable is sensitive and its value has not been supplied.
+	Withheld bool `json:"withheld,omitempty"`
+}
+
+// formatExportedJSON writes the Terraform and environment variables of each workspace as a JSON
+// object of workspaces, to categories, to variables.
+func formatExportedJSON(workspaceToVariables map[string][]ExportedVariable) ([]byte, error) {
+	document := map[string]map[string]map[string]exportedJSONVariable{}
+
+	for workspace, variables := range workspaceToVariables {
+		categories := map[string]map[string]exportedJSONVariable{
+			"terraform": {},
+			"env":       {},
+		}
+
+		for _, variable := range variables {
+			jsonVariable := exportedJSONVariable{
+				Source:    variable.Source,
+				Sensitive: variable.Sensitive,
+				Withheld:  variable.Withheld,
+			}
+
+			switch {
+			case variable.Withheld:
+			case variable.Masked || variable.Category != "terraform":
+				jsonVariable.Value = variable.Value
+			default:
+				value, err := variableJSONValue(variable.Key, variable.Value, variable.HCL)
+				if err != nil {
+					return nil, fmt.Errorf("[variableJSONValue] %w", err)
+				}
+				jsonVariable.Value = value
+			}
+
+			categories[variable.Category][variable.Key] = jsonVariable
+		}
+
+		document[workspace] = categories
+	}
+
+	jsonBytes, err := encodeJSON(document)
+	if err != nil {
+		return nil, fmt.Errorf("[encodeJSON] %w", err)
+	}
+
+	return jsonBytes, nil
+}
@@ -0,0 +1,197 @@
+package tfvars
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestExportFormatDecode(t *testing.T) {
+	inputToExpected := map[string]ExportFormat{
+		"":         ExportTFVars,
+		"tfvars":   ExportTFVars,
+		"JSON":     ExportJSON,
+		" dotenv ": ExportDotenv,
+		"shell":    ExportShell,
+	}
+
+	for input, expected := range inputToExpected {
+		var format ExportFormat
+
+		err := format.Decode(input)
+		if err != nil {
+			t.Errorf("unexpected error decoding %q: %v", input, err)
+		}
+
+		if format != expected {
+			t.Errorf("got %v, expected %v", format, expected)
+		}
+	}
+
+	var format ExportFormat
+	if err := format.Decode("yaml"); err == nil {
+		t.Errorf("expected an error decoding an unknown format")
+	}
+}
+
+func TestResolvedVariablesExportedVariables(t *testing.T) {
+	resolved := resolvedVariables{
+		terraform:          VariableMap{"region": "us-east1", "tags": "{}"},
+		terraformSources:   map[string]string{"region": "varset:global", "tags": "workspace", "db_password": "workspace"},
+		terraformHCL:       map[string]bool{"tags": true},
+		terraformSensitive: map[string]bool{"db_password": true},
+		env:                VariableMap{"AWS_SECRET_ACCESS_KEY": "secret"},
+		envSources:         map[string]string{"AWS_SECRET_ACCESS_KEY": "varset:aws"},
+		envSensitive:       map[string]bool{"AWS_SECRET_ACCESS_KEY": true},
+	}
+
+	expectedOutput := []ExportedVariable{
+		{Key: "db_password", Category: "terraform", Source: "workspace", Sensitive: true, Withheld: true},
+		{Key: "region", Category: "terraform", Value: "us-east1", Source: "varset:global"},
+		{Key: "tags", Category: "terraform", Value: "{}", Source: "workspace", HCL: true},
+		{Key: "AWS_SECRET_ACCESS_KEY", Category: "env", Value: "secret", Source: "varset:aws", Sensitive: true},
+	}
+
+	output := resolved.exportedVariables()
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+}
+
+func TestMaskSensitiveValues(t *testing.T) {
+	input := map[string][]ExportedVariable{
+		"workspace_1": {
+			{Key: "db_password", Category: "terraform", Source: "workspace", Sensitive: true, Withheld: true},
+			{Key: "region", Category: "terraform", Value: "us-east1", Source: "varset:global"},
+			{Key: "token", Category: "env", Value: "secret", Source: "workspace", Sensitive: true},
+		},
+	}
+
+	expectedOutput := map[string][]ExportedVariable{
+		"workspace_1": {
+			{Key: "db_password", Category: "terraform", Source: "workspace", Sensitive: true, Withheld: true},
+			{Key: "region", Category: "terraform", Value: "us-east1", Source: "varset:global"},
+			{Key: "token", Category: "env", Value: "***", Source: "workspace", Sensitive: true, Masked: true},
+		},
+	}
+
+	output := MaskSensitiveValues(input)
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+
+	if input["workspace_1"][2].Value != "secret" {
+		t.Errorf("expected the input not to be modified, got %v", input["workspace_1"][2].Value)
+	}
+}
+
+func TestFormatExportedVariables(t *testing.T) {
+	input := map[string][]ExportedVariable{
+		"workspace_b": {
+			{Key: "region", Category: "terraform", Value: "us-east1", Source: "workspace"},
+		},
+		"workspace_a": {
+			{Key: "db_password", Category: "terraform", Source: "workspace", Sensitive: true, Withheld: true},
+			{Key: "name", Category: "terraform", Value: `it's "$HOME"`, Source: "varset:global"},
+			{Key: "tags", Category: "terraform", Value: `{ team = "platform" }`, Source: "workspace", HCL: true},
+			{Key: "token", Category: "env", Value: "***", Source: "workspace", Sensitive: true, Masked: true},
+		},
+	}
+
+	expectedOutputs := map[ExportFormat]string{
+		ExportTFVars: `# workspace: workspace_a
+# db_password: workspace, sensitive, not supplied
+# varset:global
+name = "it's \"$HOME\""
+# workspace
+tags = {
+  team = "platform"
+}
+
+# workspace: workspace_b
+# workspace
+region = "us-east1"
+`,
+		ExportDotenv: `# workspace: workspace_a
+# db_password: workspace, sensitive, not supplied
+# varset:global
+TF_VAR_name="it's \"\$HOME\""
+# workspace
+TF_VAR_tags="{ team = \"platform\" }"
+# workspace, sensitive
+token="***"
+
+# workspace: workspace_b
+# workspace
+TF_VAR_region="us-east1"
+`,
+		ExportShell: `# workspace: workspace_a
+# db_password: workspace, sensitive, not supplied
+# varset:global
+export TF_VAR_name='it'\''s "$HOME"'
+# workspace
+export TF_VAR_tags='{ team = "platform" }'
+# workspace, sensitive
+export token='***'
+
+# workspace: workspace_b
+# workspace
+export TF_VAR_region='us-east1'
+`,
+		ExportJSON: `{
+  "workspace_a": {
+    "env": {
+      "token": {
+        "value": "***",
+        "source": "workspace",
+        "sensitive": true
+      }
+    },
+    "terraform": {
+      "db_password": {
+        "value": null,
+        "source": "workspace",
+        "sensitive": true,
+        "withheld": true
+      },
+      "name": {
+        "value": "it's \"$HOME\"",
+        "source": "varset:global",
+        "sensitive": false
+      },
+      "tags": {
+        "value": {
+          "team": "platform"
+        },
+        "source": "workspace",
+        "sensitive": false
+      }
+    }
+  },
+  "workspace_b": {
+    "env": {},
+    "terraform": {
+      "region": {
+        "value": "us-east1",
+        "source": "workspace",
+        "sensitive": false
+      }
+    }
+  }
+}
+`,
+	}
+
+	for format, expectedOutput := range expectedOutputs {
+		output, err := FormatExportedVariables(input, format)
+		if err != nil {
+			t.Errorf("unexpected error formatting %v: %v", format, err)
+		}
+
+		if string(output) != expectedOutput {
+			t.Errorf("%v: got:\n%v\nexpected:\n%v", format, strconv.Quote(string(output)), strconv.Quote(expectedOutput))
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package tfvars
+
+import "sort"
+
+// VarSetScope is what a variable set is applied to, which determines the precedence of its variables.
+type VarSetScope int
+
+const (
+	// ScopeGlobal variable sets apply to every workspace in the organization.
+	ScopeGlobal VarSetScope = iota
+
+	// ScopeProject variable sets apply to every workspace within the projects they are attached to.
+	ScopeProject
+
+	// ScopeWorkspace variable sets are attached to the workspace directly.
+	ScopeWorkspace
+)
+
+// VarSet describes a Terraform Cloud variable set and what it is applied to.
+type VarSet struct {
+
+	// Name is the name of the variable set.
+	Name string
+
+	// Global is whether the variable set applies to every workspace in the organization.
+	Global bool
+
+	// Priority is whether the variable set overrides the variables of the workspaces it applies to.
+	Priority bool
+
+	// WorkspaceIDs are the IDs of the workspaces the variable set is attached to.
+	WorkspaceIDs map[string]bool
+
+	// ProjectIDs are the IDs of the projects the variable set is attached to.
+	ProjectIDs map[string]bool
+}
+
+// scopeFor returns the scope with which the variable set applies to a workspace, and false if it
+// does not apply to it. attached is whether Terraform Cloud lists the variable set amongst those of
+// the workspace. A variable set that is both attached to the workspace and to its project takes the
+// precedence of the workspace.
+func (vs VarSet) scopeFor(workspaceID string, projectID string, attached bool) (VarSetScope, bool) {
+	switch {
+	case vs.Global:
+		return ScopeGlobal, true
+	case vs.WorkspaceIDs[workspaceID]:
+		return ScopeWorkspace, true
+	case projectID != "" && vs.ProjectIDs[projectID]:
+		return ScopeProject, true
+	case attached:
+		return ScopeWorkspace, true
+	default:
+		return ScopeWorkspace, false
+	}
+}
+
+// VariableSource is a set of variables that applies to a workspace: either the workspace's own
+// variables, or those of a variable set.
+type VariableSource struct {
+
+	// VarSet is the name of the variable set, empty for the workspace's own variables.
+	VarSet string
+
+	// Scope is what the variable set is applied to. It is ignored for the workspace's own variables.
+	Scope VarSetScope
+
+	// Priority is whether the variable set overrides the workspace's own variables.
+	Priority bool
+
+	// Variables are the variables of the source.
+	Variables VariableMap
+
+	// Env are the environment variables of a variable set, until createWorkspaceVariableSources
+	// separates them into a source of their own, whose Variables they become.
+	Env VariableMap
+
+	// Withheld are the sensitive variables of the source, mapped to their category, whose values
+	// Terraform Cloud withholds and that have not been supplied.
+	Withheld map[string]string
+
+	// HCL are the variables of the source whose values are HCL expressions rather than strings.
+	HCL map[string]bool
+
+	// Sensitive are the variables of the source that are sensitive, whether withheld or supplied.
+	Sensitive map[string]bool
+}
+
+// String names the source as it is reported, either "workspace" or "varset:<name>".
+func (vs VariableSource) String() string {
+	if vs.VarSet == "" {
+		return "workspace"
+	}
+
+	return "varset:" + vs.VarSet
+}
+
+// rank orders sources from the lowest precedence to the highest, matching Terraform Cloud: global
+// variable sets, project-scoped variable sets, variable sets attached to the workspace, the
+// workspace's own variables, and finally priority variable sets, which are ranked by scope in the
+// same way amongst themselves.
+func (vs VariableSource) rank() int {
+	switch {
+	case vs.VarSet == "":
+		return int(ScopeWorkspace) + 1
+	case vs.Priority:
+		return int(ScopeWorkspace) + 2 + int(vs.Scope)
+	default:
+		return int(vs.Scope)
+	}
+}
+
+// resolveVariables combines the variables of each source, returning the value of each variable
+// alongside the source it was taken from. When sources of the same rank define the same variable,
+// the variable set whose name is lexically first wins, as it does in Terraform Cloud. A withheld
+// variable that takes precedence has a source but no value.
+func resolveVariables(sources []VariableSource) (VariableMap, map[string]string) {
+	ordered := append([]VariableSource{}, sources...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].rank() != ordered[j].rank() {
+			return ordered[i].rank() < ordered[j].rank()
+		}
+
+		// Later sources override earlier ones, so the lexically first name is placed last.
+		return ordered[i].VarSet > ordered[j].VarSet
+	})
+
+	variables := VariableMap{}
+	variableSources := map[string]string{}
+
+	for _, source := range ordered {
+		for k, v := range source.Variables {
+			variables[k] = v
+			variableSources[k] = source.String()
+		}
+
+		for k := range source.Withheld {
+			delete(variables, k)
+			variableSources[k] = source.String()
+		}
+	}
+
+	return variables, variableSources
+}
+
+// resolveHCLVariables returns the variables whose values, as resolved by resolveVariables, are
+// HCL expressions.
+func resolveHCLVariables(sources []VariableSource, variableSources map[string]string) map[string]bool {
+	return resolveFlaggedVariables(sources, variableSources, func(source VariableSource) map[string]bool {
+		return source.HCL
+	})
+}
+
+// resolveSensitiveVariables returns the variables that, as resolved by resolveVariables, are sensitive.
+func resolveSensitiveVariables(sources []VariableSource, variableSources map[string]string) map[string]bool {
+	return resolveFlaggedVariables(sources, variableSources, func(source VariableSource) map[string]bool {
+		return source.Sensitive
+	})
+}
+
+// resolveFlaggedVariables returns the variables flagged by the source their value was taken from.
+func resolveFlaggedVariables(
+	sources []VariableSource, variableSources map[string]string, flagged func(VariableSource) map[string]bool,
+) map[string]bool {
+	flaggedVariables := map[string]bool{}
+
+	for _, source := range sources {
+		for k := range flagged(source) {
+			if variableSources[k] == source.String() {
+				flaggedVariables[k] = true
+			}
+		}
+	}
+
+	return flaggedVariables
+}
@@ -0,0 +1,101 @@
+package tfvars
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveVariables(t *testing.T) {
+	inputSources := []VariableSource{
+		{VarSet: "priority_b", Scope: ScopeGlobal, Priority: true, Variables: VariableMap{"region": "priority_b"}},
+		{VarSet: "global", Scope: ScopeGlobal, Variables: VariableMap{"region": "global", "tier": "global", "zone": "global"}},
+		{Variables: VariableMap{"region": "workspace", "tier": "workspace", "owner": "workspace"}},
+		{VarSet: "project", Scope: ScopeProject, Variables: VariableMap{"tier": "project", "zone": "project", "size": "project"}},
+		{VarSet: "attached_b", Scope: ScopeWorkspace, Variables: VariableMap{"size": "attached_b", "zone": "attached_b"}},
+		{VarSet: "attached_a", Scope: ScopeWorkspace, Variables: VariableMap{"size": "attached_a"}},
+		{VarSet: "priority_a", Scope: ScopeGlobal, Priority: true, Variables: VariableMap{"region": "priority_a"}},
+	}
+
+	expectedVariables := VariableMap{
+		"owner":  "workspace",
+		"region": "priority_a",
+		"size":   "attached_a",
+		"tier":   "workspace",
+		"zone":   "attached_b",
+	}
+
+	expectedSources := map[string]string{
+		"owner":  "workspace",
+		"region": "varset:priority_a",
+		"size":   "varset:attached_a",
+		"tier":   "workspace",
+		"zone":   "varset:attached_b",
+	}
+
+	outputVariables, outputSources := resolveVariables(inputSources)
+
+	if !reflect.DeepEqual(outputVariables, expectedVariables) {
+		t.Errorf("got %v, expected %v", outputVariables, expectedVariables)
+	}
+
+	if !reflect.DeepEqual(outputSources, expectedSources) {
+		t.Errorf("got %v, expected %v", outputSources, expectedSources)
+	}
+}
+
+func TestVariableSourceRank(t *testing.T) {
+	// Sources from the lowest precedence to the highest.
+	sources := []VariableSource{
+		{VarSet: "global", Scope: ScopeGlobal},
+		{VarSet: "project", Scope: ScopeProject},
+		{VarSet: "attached", Scope: ScopeWorkspace},
+		{},
+		{VarSet: "priority_global", Scope: ScopeGlobal, Priority: true},
+		{VarSet: "priority_project", Scope: ScopeProject, Priority: true},
+		{VarSet: "priority_attached", Scope: ScopeWorkspace, Priority: true},
+	}
+
+	for i := 1; i < len(sources); i++ {
+		if sources[i-1].rank() >= sources[i].rank() {
+			t.Errorf("expected %v to take precedence over %v", sources[i], sources[i-1])
+		}
+	}
+}
+
+func TestResolveVariablesWithheld(t *testing.T) {
+	inputSources := []VariableSource{
+		{VarSet: "global", Scope: ScopeGlobal, Withheld: map[string]string{"token": "terraform", "password": "terraform"}},
+		{Variables: VariableMap{"token": "abc"}},
+	}
+
+	outputVariables, outputSources := resolveVariables(inputSources)
+
+	expectedVariables := VariableMap{"token": "abc"}
+	if !reflect.DeepEqual(outputVariables, expectedVariables) {
+		t.Errorf("got %v, expected %v", outputVariables, expectedVariables)
+	}
+
+	expectedSources := map[string]string{"token": "workspace", "password": "varset:global"}
+	if !reflect.DeepEqual(outputSources, expectedSources) {
+		t.Errorf("got %v, expected %v", outputSources, expectedSources)
+	}
+}
+
+func TestResolveHCLVariables(t *testing.T) {
+	inputSources := []VariableSource{
+		{VarSet: "global", Scope: ScopeGlobal, Variables: VariableMap{"tags": "{}", "zones": "[]"}, HCL: map[string]bool{"tags": true, "zones": true}},
+		{Variables: VariableMap{"tags": `{ team = "platform" }`, "name": "app"}, HCL: map[string]bool{"tags": true}},
+		{VarSet: "priority", Scope: ScopeGlobal, Priority: true, Variables: VariableMap{"zones": "a,b"}},
+	}
+
+	_, variableSources := resolveVariables(inputSources)
+
+	// zones is overridden by a variable set where it is a string.
+	expectedHCLVariables := map[string]bool{"tags": true}
+
+	outputHCLVariables := resolveHCLVariables(inputSources, variableSources)
+
+	if !reflect.DeepEqual(outputHCLVariables, expectedHCLVariables) {
+		t.Errorf("got %v, expected %v", outputHCLVariables, expectedHCLVariables)
+	}
+}
@@ -0,0 +1,276 @@
+package tfvars
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/logging"
+)
+
+// SecretSource supplies the values of sensitive variables from outside of Terraform Cloud, which
+// never returns them.
+type SecretSource interface {
+
+	// Name describes the source within logs and errors.
+	Name() string
+
+	// Load reads the sensitive variables of each workspace and variable set.
+	Load(ctx context.Context) (SensitiveVars, error)
+}
+
+// SensitiveVars are the sensitive variables of each workspace and variable set, as read from a
+// SecretSource. Every source holds a JSON document of the form:
+//
+//	{
+//	    "workspaces": {"my_workspace": {"db_password": {"value": "...", "category": "terraform"}}},
+//	    "var_sets": {"my_var_set": {"AWS_SECRET_ACCESS_KEY": {"value": "...", "category": "env"}}}
+//	}
+type SensitiveVars struct {
+
+	// Workspaces are the sensitive variables of each workspace, as in TerraformWorkspaceSensitiveVars.
+	Workspaces GroupToVariables
+
+	// VarSets are the sensitive variables of each variable set, as in TerraformVarSetSensitiveVars.
+	VarSets GroupToVariables
+}
+
+// newSecretSources creates a SecretSource for each source configured, in the order in which they
+// take precedence, lowest first.
+func newSecretSources(config *Config, httpClient http.Client) []SecretSource {
+	var sources []SecretSource
+
+	if config.SensitiveVarsFile != "" {
+		sources = append(sources, &fileSource{path: config.SensitiveVarsFile})
+	}
+
+	if config.SensitiveVarsSOPSFile != "" {
+		sources = append(sources, &sopsSource{path: config.SensitiveVarsSOPSFile, command: "sops"})
+	}
+
+	if config.VaultSecretPath != "" {
+		sources = append(sources, &vaultSource{
+			address:    config.VaultAddress,
+			token:      config.VaultToken,
+			mount:      config.VaultKVMount,
+			path:       config.VaultSecretPath,
+			httpClient: httpClient,
+		})
+	}
+
+	return sources
+}
+
+// parseSensitiveVars parses the JSON document held by every SecretSource.
+func parseSensitiveVars(jsonBytes []byte) (SensitiveVars, error) {
+	container, err := gabs.ParseJSON(jsonBytes)
+	if err != nil {
+		return SensitiveVars{}, fmt.Errorf("[gabs.ParseJSON] %w", err)
+	}
+
+	for key := range container.ChildrenMap() {
+		if key != "workspaces" && key != "var_sets" {
+			return SensitiveVars{}, fmt.Errorf("unexpected key %v, expected only 'workspaces' and 'var_sets'", key)
+		}
+	}
+
+	sensitiveVars := SensitiveVars{Workspaces: GroupToVariables{}, VarSets: GroupToVariables{}}
+
+	if container.Exists("workspaces") {
+		err = sensitiveVars.Workspaces.Decode(container.Search("workspaces").String())
+		if err != nil {
+			return SensitiveVars{}, fmt.Errorf("[GroupToVariables.Decode] workspaces: %w", err)
+		}
+	}
+
+	if container.Exists("var_sets") {
+		err = sensitiveVars.VarSets.Decode(container.Search("var_sets").String())
+		if err != nil {
+			return SensitiveVars{}, fmt.Errorf("[GroupToVariables.Decode] var_sets: %w", err)
+		}
+	}
+
+	return sensitiveVars, nil
+}
+
+// values returns the value of every sensitive variable.
+func (sv SensitiveVars) values() []string {
+	var values []string
+
+	for _, groupToVariables := range []GroupToVariables{sv.Workspaces, sv.VarSets} {
+		for _, variables := range groupToVariables {
+			for _, variableData := range variables {
+				values = append(values, variableData.value)
+			}
+		}
+	}
+
+	return values
+}
+
+// fileSource reads sensitive variables from a local JSON file.
+type fileSource struct {
+
+	// path is the path of the file.
+	path string
+}
+
+// Name describes the source within logs and errors.
+func (fs *fileSource) Name() string {
+	return fmt.Sprintf("file %v", fs.path)
+}
+
+// Load reads the sensitive variables of each workspace and variable set.
+func (fs *fileSource) Load(_ context.Context) (SensitiveVars, error) {
+	jsonBytes, err := os.ReadFile(fs.path)
+	if err != nil {
+		return SensitiveVars{}, fmt.Errorf("[os.ReadFile] %w", err)
+	}
+
+	sensitiveVars, err := parseSensitiveVars(jsonBytes)
+	if err != nil {
+		return SensitiveVars{}, fmt.Errorf("[parseSensitiveVars] %w", err)
+	}
+
+	return sensitiveVars, nil
+}
+
+// sopsSource reads sensitive variables from a SOPS-encrypted YAML or JSON file, decrypting it with
+// the sops binary. sops finds its keys as it usually does, such as an age key within
+// SOPS_AGE_KEY_FILE or SOPS_AGE_KEY.
+type sopsSource struct {
+
+	// path is the path of the encrypted file.
+	path string
+
+	// command is the sops binary that decrypts the file.
+	command string
+}
+
+// Name describes the source within logs and errors.
+func (ss *sopsSource) Name() string {
+	return fmt.Sprintf("SOPS file %v", ss.path)
+}
+
+// Load reads the sensitive variables of each workspace and variable set.
+func (ss *sopsSource) Load(ctx context.Context) (SensitiveVars, error) {
+	var stdout, stderr bytes.Buffer
+
+	// Decrypting to JSON allows YAML and JSON files to be parsed alike.
+	command := exec.CommandContext(ctx, ss.command, "--decrypt", "--output-type", "json", ss.path)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+
+	err := command.Run()
+	if err != nil {
+		return SensitiveVars{}, fmt.Errorf("[%v --decrypt] %w: %v", ss.command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	sensitiveVars, err := parseSensitiveVars(stdout.Bytes())
+	if err != nil {
+		return SensitiveVars{}, fmt.Errorf("[parseSensitiveVars] %w", err)
+	}
+
+	return sensitiveVars, nil
+}
+
+// vaultSource reads sensitive variables from a secret within a HashiCorp Vault KV version 2
+// secrets engine, whose data is the JSON document described by SensitiveVars.
+type vaultSource struct {
+
+	// address is the address of the Vault server, such as https://vault.example.com:8200.
+	address string
+
+	// token is the Vault token used to read the secret.
+	token string
+
+	// mount is the mount path of the KV secrets engine, such as "secret".
+	mount string
+
+	// path is the path of the secret within the mount.
+	path string
+
+	// httpClient contains an http.Client struct
+	httpClient http.Client
+}
+
+// Name describes the source within logs and errors.
+func (vs *vaultSource) Name() string {
+	return fmt.Sprintf("Vault secret %v/%v", vs.mount, vs.path)
+}
+
+// Load reads the sensitive variables of each workspace and variable set.
+func (vs *vaultSource) Load(ctx context.Context) (SensitiveVars, error) {
+	requestURL, err := url.JoinPath(vs.address, "v1", vs.mount, "data", vs.path)
+	if err != nil {
+		return SensitiveVars{}, fmt.Errorf("[url.JoinPath] %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		return SensitiveVars{}, fmt.Errorf("[http.NewRequestWithContext] %w", err)
+	}
+	request.Header.Set("X-Vault-Token", vs.token)
+
+	// The token may have come from VAULT_TOKEN rather than the configuration masked at startup.
+	logging.AddSecrets(vs.token)
+
+	response, err := vs.httpClient.Do(request)
+	if err != nil {
+		return SensitiveVars{}, fmt.Errorf("[vs.httpClient.Do] %w", err)
+	}
+	defer response.Body.Close()
+
+	responseBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		return SensitiveVars{}, fmt.Errorf("[io.ReadAll] %w", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return SensitiveVars{}, fmt.Errorf(
+			"GET %v was unsuccessful, with the server returning: %v: %v",
+			request.URL.Path, response.StatusCode, extractVaultErrors(responseBytes),
+		)
+	}
+
+	container, err := gabs.ParseJSON(responseBytes)
+	if err != nil {
+		return SensitiveVars{}, fmt.Errorf("[gabs.ParseJSON] %w", err)
+	}
+
+	if !container.Exists("data", "data") {
+		return SensitiveVars{}, fmt.Errorf("unable to find the secret's data, check that %v is a KV version 2 mount", vs.mount)
+	}
+
+	sensitiveVars, err := parseSensitiveVars(container.Search("data", "data").Bytes())
+	if err != nil {
+		return SensitiveVars{}, fmt.Errorf("[parseSensitiveVars] %w", err)
+	}
+
+	return sensitiveVars, nil
+}
+
+// extractVaultErrors is a helper function that uses the gabs library to pull out the errors array
+// from a Vault API response.
+func extractVaultErrors(jsonBytes []byte) string {
+	container, err := gabs.ParseJSON(jsonBytes)
+	if err != nil {
+		return strings.TrimSpace(string(jsonBytes))
+	}
+
+	var messages []string
+	for _, child := range container.Search("errors").Children() {
+		if message, ok := child.Data().(string); ok {
+			messages = append(messages, message)
+		}
+	}
+
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,248 @@
+package tfvars
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSensitiveVarsJSON = `{
+	"workspaces": {
+		"workspace_1": {"db_password": {"value": "hunter2", "category": "terraform"}}
+	},
+	"var_sets": {
+		"var_set_1": {"AWS_SECRET_ACCESS_KEY": {"value": "abc123", "category": "env"}}
+	}
+}`
+
+var testSensitiveVars = SensitiveVars{
+	Workspaces: GroupToVariables{
+		"workspace_1": {"db_password": {value: "hunter2", category: "terraform"}},
+	},
+	VarSets: GroupToVariables{
+		"var_set_1": {"AWS_SECRET_ACCESS_KEY": {value: "abc123", category: "env"}},
+	},
+}
+
+func TestParseSensitiveVars(t *testing.T) {
+	output, err := parseSensitiveVars([]byte(testSensitiveVarsJSON))
+	if err != nil {
+		t.Errorf("unexpected error in parseSensitiveVars: %v", err)
+	}
+
+	if !reflect.DeepEqual(output, testSensitiveVars) {
+		t.Errorf("got %v, expected %v", output, testSensitiveVars)
+	}
+
+	output, err = parseSensitiveVars([]byte(`{"workspaces": {}}`))
+	if err != nil {
+		t.Errorf("unexpected error in parseSensitiveVars: %v", err)
+	}
+
+	expectedOutput := SensitiveVars{Workspaces: GroupToVariables{}, VarSets: GroupToVariables{}}
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+
+	_, err = parseSensitiveVars([]byte(`{"workspace": {}}`))
+	if err == nil {
+		t.Errorf("expected an error for an unexpected key")
+	}
+
+	_, err = parseSensitiveVars([]byte(`{"workspaces": {"workspace_1": {"db_password": {"value": "x"}}}}`))
+	if err == nil {
+		t.Errorf("expected an error for a variable without a category")
+	}
+}
+
+func TestFileSourceLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sensitive-vars.json")
+	err := os.WriteFile(path, []byte(testSensitiveVarsJSON), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error writing the file: %v", err)
+	}
+
+	output, err := (&fileSource{path: path}).Load(context.Background())
+	if err != nil {
+		t.Errorf("unexpected error in fileSource.Load: %v", err)
+	}
+
+	if !reflect.DeepEqual(output, testSensitiveVars) {
+		t.Errorf("got %v, expected %v", output, testSensitiveVars)
+	}
+}
+
+func TestSOPSSourceLoad(t *testing.T) {
+	directory := t.TempDir()
+	decryptedPath := filepath.Join(directory, "decrypted.json")
+	err := os.WriteFile(decryptedPath, []byte(testSensitiveVarsJSON), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error writing the file: %v", err)
+	}
+
+	// The stand-in for sops prints the decrypted file when called as sops would be.
+	command := filepath.Join(directory, "sops")
+	script := "#!/bin/sh\n" +
+		"[ \"$*\" = \"--decrypt --output-type json secrets.enc.yaml\" ] || { echo \"unexpected arguments: $*\" >&2; exit 1; }\n" +
+		"cat " + decryptedPath + "\n"
+	err = os.WriteFile(command, []byte(script), 0700)
+	if err != nil {
+		t.Fatalf("unexpected error writing the script: %v", err)
+	}
+
+	output, err := (&sopsSource{path: "secrets.enc.yaml", command: command}).Load(context.Background())
+	if err != nil {
+		t.Errorf("unexpected error in sopsSource.Load: %v", err)
+	}
+
+	if !reflect.DeepEqual(output, testSensitiveVars) {
+		t.Errorf("got %v, expected %v", output, testSensitiveVars)
+	}
+
+	_, err = (&sopsSource{path: "other.enc.yaml", command: command}).Load(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "unexpected arguments") {
+		t.Errorf("got %v, expected an error including the output of sops", err)
+	}
+}
+
+// newTestVaultServer starts a stand-in for a dev-mode Vault server with a root token of "root",
+// serving a single KV version 2 secret at secret/tfstate-migration.
+func newTestVaultServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+
+		if r.Method != "GET" || r.URL.Path != "/v1/secret/data/tfstate-migration" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors": []}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{
+			"request_id": "8675309",
+			"data": {
+				"data": ` + testSensitiveVarsJSON + `,
+				"metadata": {"version": 1, "destroyed": false}
+			}
+		}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestVaultSourceLoad(t *testing.T) {
+	server := newTestVaultServer(t)
+
+	testCases := []struct {
+		token         string
+		path          string
+		expectedError string
+	}{
+		{token: "root", path: "tfstate-migration"},
+		{token: "wrong", path: "tfstate-migration", expectedError: "403: permission denied"},
+		{token: "root", path: "missing", expectedError: "GET /v1/secret/data/missing was unsuccessful"},
+	}
+
+	for _, testCase := range testCases {
+		source := &vaultSource{
+			address:    server.URL,
+			token:      testCase.token,
+			mount:      "secret",
+			path:       testCase.path,
+			httpClient: http.Client{},
+		}
+
+		output, err := source.Load(context.Background())
+
+		if testCase.expectedError != "" {
+			if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+				t.Errorf("got %v, expected an error containing %v", err, testCase.expectedError)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("unexpected error in vaultSource.Load: %v", err)
+		}
+
+		if !reflect.DeepEqual(output, testSensitiveVars) {
+			t.Errorf("got %v, expected %v", output, testSensitiveVars)
+		}
+	}
+}
+
+func TestLoadSecretSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sensitive-vars.json")
+	err := os.WriteFile(path, []byte(`{
+		"workspaces": {"workspace_1": {"db_password": {"value": "from_file", "category": "terraform"}}}
+	}`), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error writing the file: %v", err)
+	}
+
+	server := newTestVaultServer(t)
+
+	tfc := tfCloud{
+		config: &Config{
+			TerraformWorkspaceSensitiveVars: GroupToVariables{
+				"workspace_1": {
+					"db_password": {value: "from_config", category: "terraform"},
+					"db_user":     {value: "admin", category: "terraform"},
+				},
+			},
+		},
+		secretSources: []SecretSource{
+			&fileSource{path: path},
+			&vaultSource{address: server.URL, token: "root", mount: "secret", path: "tfstate-migration"},
+		},
+	}
+
+	err = tfc.loadSecretSources(context.Background())
+	if err != nil {
+		t.Errorf("unexpected error in tfc.loadSecretSources: %v", err)
+	}
+
+	// Vault is the last source, so its value takes precedence over the file and the config.
+	expectedWorkspaceSensitiveVars := GroupToVariables{
+		"workspace_1": {
+			"db_password": {value: "hunter2", category: "terraform"},
+			"db_user":     {value: "admin", category: "terraform"},
+		},
+	}
+
+	if !reflect.DeepEqual(tfc.config.TerraformWorkspaceSensitiveVars, expectedWorkspaceSensitiveVars) {
+		t.Errorf("got %v, expected %v", tfc.config.TerraformWorkspaceSensitiveVars, expectedWorkspaceSensitiveVars)
+	}
+
+	if !reflect.DeepEqual(tfc.config.TerraformVarSetSensitiveVars, testSensitiveVars.VarSets) {
+		t.Errorf("got %v, expected %v", tfc.config.TerraformVarSetSensitiveVars, testSensitiveVars.VarSets)
+	}
+}
+
+func TestNewSecretSources(t *testing.T) {
+	config := &Config{
+		SensitiveVarsSOPSFile: "secrets.enc.yaml",
+		VaultAddress:          "http://127.0.0.1:8200",
+		VaultKVMount:          "secret",
+		VaultSecretPath:       "tfstate-migration",
+	}
+
+	var names []string
+	for _, source := range newSecretSources(config, http.Client{}) {
+		names = append(names, source.Name())
+	}
+
+	expectedNames := []string{"SOPS file secrets.enc.yaml", "Vault secret secret/tfstate-migration"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("got %v, expected %v", names, expectedNames)
+	}
+}
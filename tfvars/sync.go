@@ -0,0 +1,594 @@
+package tfvars
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+
+	"github.com/Jeffail/gabs/v2"
+)
+
+// SyncFile declares the variables of workspaces and variable sets within Terraform Cloud. Every
+// workspace or variable set listed is managed in full: its variables that are not declared are
+// deleted. Those that are not listed are left untouched.
+type SyncFile struct {
+
+	// Workspaces maps the names of workspaces to their variables.
+	Workspaces map[string][]SyncVariable `json:"workspaces"`
+
+	// VarSets maps the names of variable sets to their variables.
+	VarSets map[string][]SyncVariable `json:"var_sets"`
+}
+
+// SyncVariable is a variable as declared within a SyncFile. Variables are identified by their key
+// and category, and attributes that are omitted keep their current values within Terraform Cloud,
+// or take Terraform Cloud's defaults when the variable is created.
+type SyncVariable struct {
+
+	// Key is the name of the variable.
+	Key string `json:"key"`
+
+	// Category is either "terraform" or "env", defaulting to "terraform".
+	Category string `json:"category"`
+
+	// Value is the value of the variable, written as Terraform Cloud holds it. It may be omitted for
+	// an existing sensitive variable, whose value Terraform Cloud withholds, to keep that value.
+	Value *string `json:"value"`
+
+	// HCL is whether the value is an HCL expression rather than a string.
+	HCL *bool `json:"hcl"`
+
+	// Sensitive is whether the value is withheld by Terraform Cloud once written.
+	Sensitive *bool `json:"sensitive"`
+
+	// Description describes the variable within Terraform Cloud.
+	Description *string `json:"description"`
+}
+
+// SyncOperation is a change made to a variable within Terraform Cloud.
+type SyncOperation string
+
+const (
+	// SyncCreate variables are declared but do not exist within Terraform Cloud.
+	SyncCreate SyncOperation = "create"
+
+	// SyncUpdate variables exist within Terraform Cloud with different attributes than declared.
+	SyncUpdate SyncOperation = "update"
+
+	// SyncDelete variables exist within Terraform Cloud but are not declared.
+	SyncDelete SyncOperation = "delete"
+)
+
+// SyncAttributes are the attributes of a variable within Terraform Cloud.
+type SyncAttributes struct {
+
+	// Value is the value of the variable, empty if it is withheld.
+	Value string `json:"value"`
+
+	// HCL is whether the value is an HCL expression rather than a string.
+	HCL bool `json:"hcl"`
+
+	// Sensitive is whether the value is withheld by Terraform Cloud once written.
+	Sensitive bool `json:"sensitive"`
+
+	// Description describes the variable within Terraform Cloud.
+	Description string `json:"description"`
+
+	// Masked is whether the value has been replaced so that it is not revealed.
+	Masked bool `json:"masked"`
+
+	// Withheld is whether the variable is sensitive and its value is unknown, either as Terraform
+	// Cloud withholds it or, after a change, as it is kept.
+	Withheld bool `json:"withheld"`
+}
+
+// DisplayValue writes the value of the variable as it is shown within a plan.
+func (sa SyncAttributes) DisplayValue() string {
+	return ExportedVariable{Value: sa.Value, HCL: sa.HCL, Masked: sa.Masked, Withheld: sa.Withheld}.DisplayValue()
+}
+
+// SyncChange is a change that makes a variable within Terraform Cloud match its declaration.
+type SyncChange struct {
+
+	// Target is the workspace or variable set of the variable, either "workspace:<name>" or
+	// "varset:<name>".
+	Target string `json:"target"`
+
+	// Operation is the change made to the variable.
+	Operation SyncOperation `json:"operation"`
+
+	// Key is the name of the variable.
+	Key string `json:"key"`
+
+	// Category is either "terraform" or "env".
+	Category string `json:"category"`
+
+	// Before are the attributes of the variable within Terraform Cloud, nil if it is created.
+	Before *SyncAttributes `json:"before"`
+
+	// After are the attributes of the variable once changed, nil if it is deleted.
+	After *SyncAttributes `json:"after"`
+
+	// Changed are the names of the attributes that are updated, in the order value, hcl, sensitive,
+	// and description.
+	Changed []string `json:"changed,omitempty"`
+
+	// varsPath is the Terraform Cloud API path of the variables of the workspace or variable set.
+	varsPath string
+
+	// variableID is the Terraform Cloud ID of the variable, empty if it is created.
+	variableID string
+}
+
+// remoteVariable is a variable as it exists within Terraform Cloud.
+type remoteVariable struct {
+
+	// id is the Terraform Cloud ID of the variable.
+	id string
+
+	// key is the name of the variable.
+	key string
+
+	// category is either "terraform" or "env".
+	category string
+
+	// attributes are the attributes of the variable.
+	attributes SyncAttributes
+}
+
+// ReadSyncFile reads and validates a SyncFile written as JSON, defaulting the category of each
+// variable to "terraform".
+func ReadSyncFile(path string) (SyncFile, error) {
+	var syncFile SyncFile
+
+	fileBytes, err := os.ReadFile(path)
+	if err != nil {
+		return syncFile, fmt.Errorf("[os.ReadFile] %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(fileBytes))
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(&syncFile)
+	if err != nil {
+		return syncFile, fmt.Errorf("[decoder.Decode] error in parsing %v: %w", path, err)
+	}
+
+	for workspace, variables := range syncFile.Workspaces {
+		err = validateSyncVariables(variables)
+		if err != nil {
+			return syncFile, fmt.Errorf("[validateSyncVariables] workspace %v: %w", workspace, err)
+		}
+	}
+
+	for varSet, variables := range syncFile.VarSets {
+		err = validateSyncVariables(variables)
+		if err != nil {
+			return syncFile, fmt.Errorf("[validateSyncVariables] variable set %v: %w", varSet, err)
+		}
+	}
+
+	return syncFile, nil
+}
+
+// validateSyncVariables checks that each variable has a key and a valid category, and is declared
+// once, defaulting categories to "terraform".
+func validateSyncVariables(variables []SyncVariable) error {
+	type variableID struct {
+		category string
+		key      string
+	}
+
+	declared := map[variableID]bool{}
+
+	for i := range variables {
+		if variables[i].Key == "" {
+			return fmt.Errorf("variable %v has no key", i)
+		}
+
+		if variables[i].Category == "" {
+			variables[i].Category = "terraform"
+		}
+		if variables[i].Category != "terraform" && variables[i].Category != "env" {
+			return fmt.Errorf(
+				"variable %v has category %v, which must be either terraform or env", variables[i].Key, variables[i].Category,
+			)
+		}
+
+		id := variableID{variables[i].Category, variables[i].Key}
+		if declared[id] {
+			return fmt.Errorf("%v variable %v is declared more than once", id.category, id.key)
+		}
+		declared[id] = true
+	}
+
+	return nil
+}
+
+// PlanVariableSync compares the variables declared by a SyncFile with those within Terraform
+// Cloud, returning the changes that make them match, workspaces before variable sets.
+func (tfc *tfCloud) PlanVariableSync(ctx context.Context, syncFile SyncFile) ([]SyncChange, error) {
+	changes := []SyncChange{}
+
+	if tfc.config.TerraformCloudToken == "null" {
+		slog.Warn("Job kicked off in test-mode (TerraformCloudToken == 'null').")
+		return changes, nil
+	}
+
+	for _, workspace := range sortedSyncTargets(syncFile.Workspaces) {
+		workspaceID, err := tfc.getWorkspaceID(ctx, workspace)
+		if err != nil {
+			return nil, fmt.Errorf("[tfc.getWorkspaceID] workspace %v: %w", workspace, err)
+		}
+
+		response, err := tfc.getWorkspaceVariables(ctx, workspaceID)
+		if err != nil {
+			return nil, fmt.Errorf("[tfc.getWorkspaceVariables] workspace %v: %w", workspace, err)
+		}
+
+		groupChanges, err := planSyncChanges(
+			"workspace:"+workspace,
+			fmt.Sprintf("https://app.terraform.io/api/v2/workspaces/%v/vars", workspaceID),
+			syncFile.Workspaces[workspace],
+			response,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("[planSyncChanges] workspace %v: %w", workspace, err)
+		}
+		changes = append(changes, groupChanges...)
+	}
+
+	if len(syncFile.VarSets) == 0 {
+		return changes, nil
+	}
+
+	varSets, err := tfc.getVarSetsForOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("[tfc.getVarSetsForOrg] %w", err)
+	}
+
+	varSetNameToID := map[string]string{}
+	for varSetID, varSet := range varSets {
+		varSetNameToID[varSet.Name] = varSetID
+	}
+
+	for _, varSet := range sortedSyncTargets(syncFile.VarSets) {
+		varSetID, ok := varSetNameToID[varSet]
+		if !ok {
+			return nil, fmt.Errorf("variable set %v: %w", varSet, ErrNotFound)
+		}
+
+		response, err := tfc.getVarSetVariables(ctx, varSetID)
+		if err != nil {
+			return nil, fmt.Errorf("[tfc.getVarSetVariables] variable set %v: %w", varSet, err)
+		}
+
+		groupChanges, err := planSyncChanges(
+			"varset:"+varSet,
+			fmt.Sprintf("https://app.terraform.io/api/v2/varsets/%v/relationships/vars", varSetID),
+			syncFile.VarSets[varSet],
+			response,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("[planSyncChanges] variable set %v: %w", varSet, err)
+		}
+		changes = append(changes, groupChanges...)
+	}
+
+	return changes, nil
+}
+
+// sortedSyncTargets returns the names of the workspaces or variable sets of a SyncFile, sorted.
+func sortedSyncTargets(groups map[string][]SyncVariable) []string {
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// planSyncChanges compares the declared variables of a workspace or variable set with those within
+// a response from the Terraform Cloud endpoint, returning the changes that make them match, with
+// Terraform variables before environment variables, each sorted by key.
+func planSyncChanges(target string, varsPath string, declared []SyncVariable, response []byte) ([]SyncChange, error) {
+	remoteVariables, err := extractRemoteVariables(response)
+	if err != nil {
+		return nil, fmt.Errorf("[extractRemoteVariables] %w", err)
+	}
+
+	type variableID struct {
+		category string
+		key      string
+	}
+
+	remote := map[variableID]remoteVariable{}
+	for _, variable := range remoteVariables {
+		remote[variableID{variable.category, variable.key}] = variable
+	}
+
+	changes := []SyncChange{}
+	isDeclared := map[variableID]bool{}
+
+	for _, variable := range declared {
+		id := variableID{variable.Category, variable.Key}
+		isDeclared[id] = true
+
+		change := SyncChange{Target: target, Key: variable.Key, Category: variable.Category, varsPath: varsPath}
+
+		existing, ok := remote[id]
+		if !ok {
+			if variable.Value == nil {
+				return nil, fmt.Errorf("%v variable %v does not exist and has no value to create it with", id.category, id.key)
+			}
+
+			after := declaredAttributes(SyncAttributes{}, variable)
+			change.Operation, change.After = SyncCreate, &after
+			changes = append(changes, change)
+			continue
+		}
+
+		before := existing.attributes
+		after := declaredAttributes(before, variable)
+
+		if before.Sensitive && !after.Sensitive {
+			return nil, fmt.Errorf(
+				"%v variable %v is sensitive and cannot be made non-sensitive, delete it and declare it again instead",
+				id.category, id.key,
+			)
+		}
+
+		changed := changedAttributes(before, after, variable.Value != nil)
+		if len(changed) == 0 {
+			continue
+		}
+
+		change.Operation, change.Before, change.After = SyncUpdate, &before, &after
+		change.Changed, change.variableID = changed, existing.id
+		changes = append(changes, change)
+	}
+
+	for id, existing := range remote {
+		if isDeclared[id] {
+			continue
+		}
+
+		before := existing.attributes
+		changes = append(changes, SyncChange{
+			Target:     target,
+			Operation:  SyncDelete,
+			Key:        id.key,
+			Category:   id.category,
+			Before:     &before,
+			varsPath:   varsPath,
+			variableID: existing.id,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Category != changes[j].Category {
+			return changes[i].Category == "terraform"
+		}
+		return changes[i].Key < changes[j].Key
+	})
+
+	return changes, nil
+}
+
+// declaredAttributes returns the attributes of a variable once the attributes declared for it are
+// applied to its current attributes.
+func declaredAttributes(current SyncAttributes, variable SyncVariable) SyncAttributes {
+	attributes := current
+
+	if variable.Value != nil {
+		attributes.Value, attributes.Withheld = *variable.Value, false
+	}
+	if variable.HCL != nil {
+		attributes.HCL = *variable.HCL
+	}
+	if variable.Sensitive != nil {
+		attributes.Sensitive = *variable.Sensitive
+	}
+	if variable.Description != nil {
+		attributes.Description = *variable.Description
+	}
+
+	return attributes
+}
+
+// changedAttributes returns the names of the attributes that differ between before and after. A
+// value supplied for a variable whose value Terraform Cloud withholds cannot be compared, so it is
+// always changed.
+func changedAttributes(before SyncAttributes, after SyncAttributes, valueSupplied bool) []string {
+	var changed []string
+
+	if valueSupplied && (before.Withheld || before.Value != after.Value) {
+		changed = append(changed, "value")
+	}
+	if before.HCL != after.HCL {
+		changed = append(changed, "hcl")
+	}
+	if before.Sensitive != after.Sensitive {
+		changed = append(changed, "sensitive")
+	}
+	if before.Description != after.Description {
+		changed = append(changed, "description")
+	}
+
+	return changed
+}
+
+// extractRemoteVariables extracts the variables, with their IDs and attributes, from a []byte from
+// the Terraform Cloud endpoint.
+func extractRemoteVariables(response []byte) ([]remoteVariable, error) {
+	container, err := gabs.ParseJSON(response)
+	if err != nil {
+		return nil, fmt.Errorf("[gabs.ParseJSON] %w", err)
+	}
+
+	var variables []remoteVariable
+
+	for _, variable := range container.Search("data").Children() {
+		id, ok := variable.Search("id").Data().(string)
+		if !ok {
+			return nil, fmt.Errorf("unable to find variable id")
+		}
+
+		varKey, ok := variable.Search("attributes", "key").Data().(string)
+		if !ok {
+			return nil, fmt.Errorf("unable to find the key of variable %v", id)
+		}
+
+		category, ok := variable.Search("attributes", "category").Data().(string)
+		if !ok {
+			category = "terraform"
+		}
+
+		value, hasValue := variable.Search("attributes", "value").Data().(string)
+		isHCL, _ := variable.Search("attributes", "hcl").Data().(bool)
+		sensitive, _ := variable.Search("attributes", "sensitive").Data().(bool)
+		description, _ := variable.Search("attributes", "description").Data().(string)
+
+		variables = append(variables, remoteVariable{
+			id:       id,
+			key:      varKey,
+			category: category,
+			attributes: SyncAttributes{
+				Value:       value,
+				HCL:         isHCL,
+				Sensitive:   sensitive,
+				Description: description,
+				Withheld:    sensitive && !hasValue,
+			},
+		})
+	}
+
+	return variables, nil
+}
+
+// MaskSensitiveChanges returns a copy of the changes in which the values of variables that are
+// sensitive before or after the change are masked.
+func MaskSensitiveChanges(changes []SyncChange) []SyncChange {
+	masked := make([]SyncChange, 0, len(changes))
+
+	for _, change := range changes {
+		if (change.Before != nil && change.Before.Sensitive) || (change.After != nil && change.After.Sensitive) {
+			change.Before = maskedAttributes(change.Before)
+			change.After = maskedAttributes(change.After)
+		}
+		masked = append(masked, change)
+	}
+
+	return masked
+}
+
+// maskedAttributes returns a copy of the attributes with the value masked, unless it is withheld.
+func maskedAttributes(attributes *SyncAttributes) *SyncAttributes {
+	if attributes == nil {
+		return nil
+	}
+
+	masked := *attributes
+	if !masked.Withheld {
+		masked.Value = maskedValue
+		masked.Masked = true
+	}
+
+	return &masked
+}
+
+// ApplyVariableSync makes each change within Terraform Cloud in turn, stopping at the first that
+// fails, and returns the number of changes made.
+func (tfc *tfCloud) ApplyVariableSync(ctx context.Context, changes []SyncChange) (int, error) {
+	for i, change := range changes {
+		err := tfc.applySyncChange(ctx, change)
+		if err != nil {
+			return i, fmt.Errorf(
+				"[tfc.applySyncChange] Error in %v %v variable %v of %v: %w",
+				change.Operation, change.Category, change.Key, change.Target, err,
+			)
+		}
+
+		slog.Info(fmt.Sprintf("%v %v variable %v of %v", change.Operation, change.Category, change.Key, change.Target))
+	}
+
+	return len(changes), nil
+}
+
+// applySyncChange makes a single change within Terraform Cloud.
+func (tfc *tfCloud) applySyncChange(ctx context.Context, change SyncChange) error {
+	requestName := string(change.Operation) + "Variable"
+	method, requestPath := "POST", change.varsPath
+
+	switch change.Operation {
+	case SyncUpdate:
+		method, requestPath = "PATCH", change.varsPath+"/"+change.variableID
+	case SyncDelete:
+		method, requestPath = "DELETE", change.varsPath+"/"+change.variableID
+	}
+
+	var body io.Reader
+	if change.Operation != SyncDelete {
+		payload, err := generateVariablePayload(change)
+		if err != nil {
+			return fmt.Errorf("[generateVariablePayload] %w", err)
+		}
+		body = bytes.NewBuffer(payload)
+	}
+
+	request, err := tfc.buildTFCloudHTTPRequest(ctx, requestName, method, requestPath, body)
+	if err != nil {
+		return fmt.Errorf("[tfc.buildTFCloudHTTPRequest] %w", err)
+	}
+
+	_, err = tfc.terraformCloudRequest(request, requestName)
+	if err != nil {
+		return fmt.Errorf("[tfc.terraformCloudRequest] %w", err)
+	}
+
+	return nil
+}
+
+// generateVariablePayload builds the JSON payload needed to create or update a variable within
+// Terraform Cloud. The value is left out when it is withheld, so that Terraform Cloud keeps it.
+func generateVariablePayload(change SyncChange) ([]byte, error) {
+	jsonObj := gabs.New()
+
+	_, err := jsonObj.Set("vars", "data", "type")
+	if err != nil {
+		return nil, fmt.Errorf("[data: type:] %w", err)
+	}
+
+	if change.variableID != "" {
+		_, err = jsonObj.Set(change.variableID, "data", "id")
+		if err != nil {
+			return nil, fmt.Errorf("[data: id:] %w", err)
+		}
+	}
+
+	attributes := map[string]interface{}{
+		"key":         change.Key,
+		"category":    change.Category,
+		"hcl":         change.After.HCL,
+		"sensitive":   change.After.Sensitive,
+		"description": change.After.Description,
+	}
+	if !change.After.Withheld {
+		attributes["value"] = change.After.Value
+	}
+
+	for name, value := range attributes {
+		_, err = jsonObj.Set(value, "data", "attributes", name)
+		if err != nil {
+			return nil, fmt.Errorf("[data: attributes: %v:] %w", name, err)
+		}
+	}
+
+	return jsonObj.Bytes(), nil
+}
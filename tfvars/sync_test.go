@@ -0,0 +1,279 @@
+package tfvars
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadSyncFile(t *testing.T) {
+	directory := t.TempDir()
+
+	path := filepath.Join(directory, "variables.json")
+	err := os.WriteFile(path, []byte(`{
+  "workspaces": {
+    "workspace_1": [
+      {"key": "region", "value": "us-east1", "description": "Region to deploy to"},
+      {"key": "region", "category": "env", "value": "us-east1"}
+    ]
+  },
+  "var_sets": {
+    "global": [
+      {"key": "db_password", "sensitive": true}
+    ]
+  }
+}`), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error writing %v: %v", path, err)
+	}
+
+	region, description, sensitive := "us-east1", "Region to deploy to", true
+	expectedOutput := SyncFile{
+		Workspaces: map[string][]SyncVariable{
+			"workspace_1": {
+				{Key: "region", Category: "terraform", Value: &region, Description: &description},
+				{Key: "region", Category: "env", Value: &region},
+			},
+		},
+		VarSets: map[string][]SyncVariable{
+			"global": {
+				{Key: "db_password", Category: "terraform", Sensitive: &sensitive},
+			},
+		},
+	}
+
+	output, err := ReadSyncFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %+v, expected %+v", output, expectedOutput)
+	}
+
+	invalidInputs := []string{
+		`{"workspaces": {"workspace_1": [{"key": "region"}, {"key": "region", "category": "terraform"}]}}`,
+		`{"workspaces": {"workspace_1": [{"key": "region", "category": "secret"}]}}`,
+		`{"var_sets": {"global": [{"value": "us-east1"}]}}`,
+		`{"workspace": {}}`,
+	}
+
+	for _, invalidInput := range invalidInputs {
+		err = os.WriteFile(path, []byte(invalidInput), 0600)
+		if err != nil {
+			t.Fatalf("unexpected error writing %v: %v", path, err)
+		}
+
+		_, err = ReadSyncFile(path)
+		if err == nil {
+			t.Errorf("expected an error reading %v", invalidInput)
+		}
+	}
+}
+
+func TestPlanSyncChanges(t *testing.T) {
+	inputResponse := []byte(`{"data": [
+		{"id": "var-1", "attributes": {"key": "region", "value": "us-east1", "category": "terraform", "hcl": false, "sensitive": false, "description": ""}},
+		{"id": "var-2", "attributes": {"key": "tags", "value": "{}", "category": "terraform", "hcl": true, "sensitive": false, "description": "Tags"}},
+		{"id": "var-3", "attributes": {"key": "db_password", "value": null, "category": "terraform", "hcl": false, "sensitive": true, "description": ""}},
+		{"id": "var-4", "attributes": {"key": "api_key", "value": null, "category": "terraform", "hcl": false, "sensitive": true, "description": ""}},
+		{"id": "var-5", "attributes": {"key": "TOKEN", "value": "abc", "category": "env", "hcl": false, "sensitive": false, "description": ""}}
+	]}`)
+
+	region, tags, password, zone := "us-west1", "{}", "hunter2", "b"
+	description, isHCL, sensitive := "Zone to deploy to", true, true
+
+	inputDeclared := []SyncVariable{
+		{Key: "region", Category: "terraform", Value: &region},
+		{Key: "tags", Category: "terraform", Value: &tags, HCL: &isHCL},
+		{Key: "db_password", Category: "terraform", Sensitive: &sensitive},
+		{Key: "api_key", Category: "terraform", Value: &password},
+		{Key: "zone", Category: "terraform", Value: &zone, Description: &description},
+	}
+
+	varsPath := "https://app.terraform.io/api/v2/workspaces/ws-1/vars"
+
+	expectedOutput := []SyncChange{
+		{
+			Target: "workspace:workspace_1", Operation: SyncUpdate, Key: "api_key", Category: "terraform",
+			Before:   &SyncAttributes{Sensitive: true, Withheld: true},
+			After:    &SyncAttributes{Value: "hunter2", Sensitive: true},
+			Changed:  []string{"value"},
+			varsPath: varsPath, variableID: "var-4",
+		},
+		{
+			Target: "workspace:workspace_1", Operation: SyncUpdate, Key: "region", Category: "terraform",
+			Before:   &SyncAttributes{Value: "us-east1"},
+			After:    &SyncAttributes{Value: "us-west1"},
+			Changed:  []string{"value"},
+			varsPath: varsPath, variableID: "var-1",
+		},
+		{
+			Target: "workspace:workspace_1", Operation: SyncCreate, Key: "zone", Category: "terraform",
+			After:    &SyncAttributes{Value: "b", Description: "Zone to deploy to"},
+			varsPath: varsPath,
+		},
+		{
+			Target: "workspace:workspace_1", Operation: SyncDelete, Key: "TOKEN", Category: "env",
+			Before:   &SyncAttributes{Value: "abc"},
+			varsPath: varsPath, variableID: "var-5",
+		},
+	}
+
+	output, err := planSyncChanges("workspace:workspace_1", varsPath, inputDeclared, inputResponse)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %+v, expected %+v", output, expectedOutput)
+	}
+
+	notSensitive := false
+	_, err = planSyncChanges("workspace:workspace_1", varsPath, []SyncVariable{
+		{Key: "db_password", Category: "terraform", Sensitive: &notSensitive},
+	}, inputResponse)
+	if err == nil {
+		t.Errorf("expected an error making a sensitive variable non-sensitive")
+	}
+
+	_, err = planSyncChanges("workspace:workspace_1", varsPath, []SyncVariable{
+		{Key: "new_password", Category: "terraform", Sensitive: &sensitive},
+	}, inputResponse)
+	if err == nil {
+		t.Errorf("expected an error creating a variable without a value")
+	}
+}
+
+func TestMaskSensitiveChanges(t *testing.T) {
+	before := SyncAttributes{Value: "abc"}
+	after := SyncAttributes{Value: "xyz", Sensitive: true}
+	region := SyncAttributes{Value: "us-east1"}
+
+	input := []SyncChange{
+		{Operation: SyncUpdate, Key: "api_key", Before: &before, After: &after, Changed: []string{"value", "sensitive"}},
+		{Operation: SyncCreate, Key: "region", After: &region},
+	}
+
+	expectedOutput := []SyncChange{
+		{
+			Operation: SyncUpdate, Key: "api_key",
+			Before:  &SyncAttributes{Value: "***", Masked: true},
+			After:   &SyncAttributes{Value: "***", Sensitive: true, Masked: true},
+			Changed: []string{"value", "sensitive"},
+		},
+		{Operation: SyncCreate, Key: "region", After: &region},
+	}
+
+	output := MaskSensitiveChanges(input)
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %+v, expected %+v", output, expectedOutput)
+	}
+
+	if before.Value != "abc" || after.Value != "xyz" {
+		t.Errorf("expected the input not to be modified, got %v and %v", before.Value, after.Value)
+	}
+}
+
+func TestApplyVariableSync(t *testing.T) {
+	type receivedRequest struct {
+		method string
+		path   string
+		body   map[string]interface{}
+	}
+
+	var received []receivedRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := receivedRequest{method: r.Method, path: r.URL.Path}
+
+		bodyBytes, _ := io.ReadAll(r.Body)
+		if len(bodyBytes) > 0 {
+			_ = json.Unmarshal(bodyBytes, &request.body)
+		}
+		received = append(received, request)
+
+		switch r.Method {
+		case "POST":
+			w.WriteHeader(http.StatusCreated)
+		case "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	tfc := tfCloud{config: &Config{TerraformCloudToken: "example_token"}, httpClient: http.Client{}}
+	varsPath := server.URL + "/api/v2/varsets/varset-1/relationships/vars"
+
+	inputChanges := []SyncChange{
+		{
+			Target: "varset:global", Operation: SyncCreate, Key: "region", Category: "terraform",
+			After:    &SyncAttributes{Value: "us-east1", Description: "Region"},
+			varsPath: varsPath,
+		},
+		{
+			Target: "varset:global", Operation: SyncUpdate, Key: "db_password", Category: "terraform",
+			Before:   &SyncAttributes{Sensitive: true, Withheld: true},
+			After:    &SyncAttributes{Sensitive: true, Description: "Password", Withheld: true},
+			Changed:  []string{"description"},
+			varsPath: varsPath, variableID: "var-2",
+		},
+		{
+			Target: "varset:global", Operation: SyncDelete, Key: "TOKEN", Category: "env",
+			Before:   &SyncAttributes{Value: "abc"},
+			varsPath: varsPath, variableID: "var-3",
+		},
+	}
+
+	expectedOutput := []receivedRequest{
+		{
+			method: "POST",
+			path:   "/api/v2/varsets/varset-1/relationships/vars",
+			body: map[string]interface{}{"data": map[string]interface{}{
+				"type": "vars",
+				"attributes": map[string]interface{}{
+					"key": "region", "value": "us-east1", "category": "terraform",
+					"hcl": false, "sensitive": false, "description": "Region",
+				},
+			}},
+		},
+		{
+			method: "PATCH",
+			path:   "/api/v2/varsets/varset-1/relationships/vars/var-2",
+			body: map[string]interface{}{"data": map[string]interface{}{
+				"type": "vars",
+				"id":   "var-2",
+				"attributes": map[string]interface{}{
+					"key": "db_password", "category": "terraform",
+					"hcl": false, "sensitive": true, "description": "Password",
+				},
+			}},
+		},
+		{
+			method: "DELETE",
+			path:   "/api/v2/varsets/varset-1/relationships/vars/var-3",
+		},
+	}
+
+	applied, err := tfc.ApplyVariableSync(context.Background(), inputChanges)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if applied != 3 {
+		t.Errorf("got %v, expected 3", applied)
+	}
+
+	if !reflect.DeepEqual(received, expectedOutput) {
+		t.Errorf("got %+v, expected %+v", received, expectedOutput)
+	}
+}
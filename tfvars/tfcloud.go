@@ -1,17 +1,28 @@
 package tfvars
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 
 	"github.com/Jeffail/gabs/v2"
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/internal/tfcapi"
+	"github.com/dragondrop-cloud/github-action-tfstate-migration/logging"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
 )
 
 // tfCloud implements the TFVars interface for a Terraform Cloud remote backend.
@@ -22,231 +33,371 @@ type tfCloud struct {
 
 	// httpClient contains an http.Client struct
 	httpClient http.Client
+
+	// secretSources supply sensitive variables in addition to those within config, each taking
+	// precedence over those before it.
+	secretSources []SecretSource
+
+	// writtenFiles are the paths of the variables files written, which Cleanup removes.
+	writtenFiles []string
 }
 
+// varsFileName is the name of the variables file written to each workspace directory. Terraform
+// loads .auto.tfvars files automatically, after terraform.tfvars and in lexical order, so the
+// file takes precedence over those of the user without replacing any of them.
+const varsFileName = "zz_dragondrop.auto.tfvars"
+
 // CreateAllWorkspaceVarsFiles extracts variables for all workspaces and saves them into
-// .tfvars files within the appropriate directory.
-func (tfc *tfCloud) CreateAllWorkspaceVarsFiles() error {
-	ctx := context.Background()
+// .tfvars files within the appropriate directory, returning the names of the variables sourced
+// for each workspace along with each workspace's environment variables.
+func (tfc *tfCloud) CreateAllWorkspaceVarsFiles(ctx context.Context) (map[string]SourcedVariables, map[string]VariableMap, error) {
+	workspaceToSourcedVariables := map[string]SourcedVariables{}
+	workspaceToEnv := map[string]VariableMap{}
 
 	if tfc.config.TerraformCloudToken == "null" {
-		fmt.Println("Job kicked off in test-mode (TerraformCloudToken == 'null').")
-		return nil
+		slog.Warn("Job kicked off in test-mode (TerraformCloudToken == 'null').")
+		return workspaceToSourcedVariables, workspaceToEnv, nil
 	}
 
-	workspaceToVarSetIDs, workspaceToVarSetVars, varSetIDsToName, err := tfc.getWorkspaceToVarSetVars()
+	err := tfc.loadSecretSources(ctx)
 	if err != nil {
-		return fmt.Errorf("[tfc.getWorkspaceToVarSetVars] %v", err)
+		return nil, nil, fmt.Errorf("[tfc.loadSecretSources] %w", err)
 	}
-	fmt.Println("Done pulling down workspace variables from variable sets.")
 
-	for workspace := range tfc.config.WorkspaceToDirectory {
-		err = tfc.PullWorkspaceVariables(ctx, workspace, workspaceToVarSetVars, workspaceToVarSetIDs, varSetIDsToName)
+	workspaces := tfc.workspaces()
+
+	workspaceToVarSetSources, err := tfc.getWorkspaceToVarSetSources(ctx, workspaces)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[tfc.getWorkspaceToVarSetSources] %w", err)
+	}
+	slog.Info("Done pulling down workspace variables from variable sets.")
+
+	// Missing sensitive variables are gathered across every workspace, so that all can be supplied at once.
+	var missingVariables []MissingSensitiveVariable
+
+	for _, workspace := range workspaces {
+		sourcedVariables, envVariables, err := tfc.PullWorkspaceVariables(ctx, workspace, workspaceToVarSetSources[workspace])
+
+		var missingErr *MissingSensitiveVariablesError
+		if errors.As(err, &missingErr) {
+			missingVariables = append(missingVariables, missingErr.Variables...)
+			continue
+		}
+
 		if err != nil {
-			return fmt.Errorf(
-				"[tfc.PullWorkspaceVariables] Error in workspace %v: %v",
+			return nil, nil, fmt.Errorf(
+				"[tfc.PullWorkspaceVariables] Error in workspace %v: %w",
 				workspace,
 				err,
 			)
 		}
-		fmt.Printf(
-			"Done pulling down workspace variables for workspace: %v\n", workspace,
-		)
+		workspaceToSourcedVariables[workspace] = sourcedVariables
+		workspaceToEnv[workspace] = envVariables
+		slog.Info("Done pulling down workspace variables.", "workspace", workspace)
 	}
-	return nil
+
+	if len(missingVariables) > 0 {
+		return nil, nil, &MissingSensitiveVariablesError{Variables: missingVariables}
+	}
+
+	return workspaceToSourcedVariables, workspaceToEnv, nil
 }
 
-// getWorkspaceToVarSetVars produces a map between a workspace name and variables associated
-// with that workspace from variable sets.
-func (tfc *tfCloud) getWorkspaceToVarSetVars() (map[string]map[string]bool, map[string]VariableMap, map[string]string, error) {
-	varSetIDsToName, err := tfc.getVarSetIdsForOrg()
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf("[tfc.getVarSetIdsForOrg] %v", err)
+// workspaces returns the names of the workspaces configured, sorted alphabetically.
+func (tfc *tfCloud) workspaces() []string {
+	workspaces := make([]string, 0, len(tfc.config.WorkspaceToDirectory))
+	for workspace := range tfc.config.WorkspaceToDirectory {
+		workspaces = append(workspaces, workspace)
+	}
+	sort.Strings(workspaces)
+
+	return workspaces
+}
+
+// Cleanup removes the variables files written by CreateAllWorkspaceVarsFiles, unless KeepVarsFiles is set.
+func (tfc *tfCloud) Cleanup() error {
+	if tfc.config.KeepVarsFiles {
+		slog.Info("Keeping the variables files written.", "files", tfc.writtenFiles)
+		return nil
 	}
 
-	varSetVars, err := tfc.getVarSetVars(varSetIDsToName)
+	var errs []error
+	for _, fileName := range tfc.writtenFiles {
+		err := os.Remove(fileName)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("[os.Remove] %w", err))
+		}
+	}
+	tfc.writtenFiles = nil
+
+	return errors.Join(errs...)
+}
+
+// loadSecretSources reads the sensitive variables of each secret source, adding them to those
+// configured directly and masking their values within logs.
+func (tfc *tfCloud) loadSecretSources(ctx context.Context) error {
+	for _, source := range tfc.secretSources {
+		sensitiveVars, err := source.Load(ctx)
+		if err != nil {
+			return fmt.Errorf("[%v] %w", source.Name(), err)
+		}
+
+		logging.AddSecrets(sensitiveVars.values()...)
+
+		tfc.config.TerraformWorkspaceSensitiveVars = tfc.config.TerraformWorkspaceSensitiveVars.Merge(sensitiveVars.Workspaces)
+		tfc.config.TerraformVarSetSensitiveVars = tfc.config.TerraformVarSetSensitiveVars.Merge(sensitiveVars.VarSets)
+
+		slog.Info(
+			"Loaded sensitive variables.", "source", source.Name(),
+			"workspaces", len(sensitiveVars.Workspaces), "var_sets", len(sensitiveVars.VarSets),
+		)
+	}
+
+	return nil
+}
+
+// getWorkspaceToVarSetSources produces a map between each workspace name and the variable sets
+// that apply to that workspace, along with their variables.
+func (tfc *tfCloud) getWorkspaceToVarSetSources(
+	ctx context.Context, workspaces []string,
+) (map[string][]VariableSource, error) {
+	varSets, err := tfc.getVarSetsForOrg(ctx)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("[tfc.getVarSetVars] %v", err)
+		return nil, fmt.Errorf("[tfc.getVarSetsForOrg] %w", err)
 	}
 
-	workspaceToVarSetIDs, err := tfc.getWorkspaceToVarSetIDs()
+	varSetVars, varSetWithheld, varSetHCL, err := tfc.getVarSetVars(ctx, varSets)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("[tfc.getWorkspaceToVarSetIDs] %v", err)
+		return nil, fmt.Errorf("[tfc.getVarSetVars] %w", err)
 	}
 
-	workspaceToVarSetVars, err := tfc.createWorkspaceToVarSetVars(varSetVars, workspaceToVarSetIDs)
+	workspaceToVarSetScopes, err := tfc.getWorkspaceToVarSetScopes(ctx, varSets, workspaces)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("[tfc.getWorkspaceToVarSetVars] %v", err)
+		return nil, fmt.Errorf("[tfc.getWorkspaceToVarSetScopes] %w", err)
 	}
 
-	return workspaceToVarSetIDs, workspaceToVarSetVars, varSetIDsToName, nil
+	return tfc.createWorkspaceToVarSetSources(
+		varSetVars, varSetWithheld, varSetHCL, workspaceToVarSetScopes, varSets,
+	), nil
 }
 
-// getVarSetIdsForOrg returns a map between var set ids and the var set's name.
-func (tfc *tfCloud) getVarSetIdsForOrg() (map[string]string, error) {
+// getVarSetsForOrg returns a map between var set ids and the var set's name, scope, and priority.
+func (tfc *tfCloud) getVarSetsForOrg(ctx context.Context) (map[string]VarSet, error) {
 	requestPath := fmt.Sprintf(
 		"https://app.terraform.io/api/v2/organizations/%v/varsets",
 		tfc.config.TerraformCloudOrganization,
 	)
 
 	httpRequest, err := tfc.buildTFCloudHTTPRequest(
-		context.Background(),
+		ctx,
 		"getAllVarSetIds",
 		"GET",
 		requestPath,
+		nil,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("[tfc.buildTFCloudHTTPRequest] %v", err)
+		return nil, fmt.Errorf("[tfc.buildTFCloudHTTPRequest] %w", err)
 	}
 
-	response, err := tfc.terraformCloudRequest(httpRequest, "getVarSetVars")
+	response, err := tfc.terraformCloudRequest(httpRequest, "getAllVarSetIds")
 	if err != nil {
-		return nil, fmt.Errorf("[tfc.buildTFCloudHTTPRequest] %v", err)
+		return nil, fmt.Errorf("[tfc.terraformCloudRequest] %w", err)
 	}
 
-	varSetIDToName, err := tfc.extractVarSetIDToName(response)
+	varSets, err := tfc.extractVarSets(response)
 	if err != nil {
-		return nil, fmt.Errorf("[tfc.extractVarSetInformation] %v", err)
+		return nil, fmt.Errorf("[tfc.extractVarSets] %w", err)
 	}
 
-	return varSetIDToName, nil
+	return varSets, nil
 }
 
-// extractVarSetIDToName extracts variable set ids from a response json
-// from the Terraform Cloud API.
-func (tfc *tfCloud) extractVarSetIDToName(response []byte) (map[string]string, error) {
-	varSetIDToName := map[string]string{}
+// extractVarSets extracts variable sets, along with the workspaces and projects they are attached
+// to, from a response json from the Terraform Cloud API.
+func (tfc *tfCloud) extractVarSets(response []byte) (map[string]VarSet, error) {
+	varSets := map[string]VarSet{}
 
 	container, err := gabs.ParseJSON(response)
 	if err != nil {
-		return nil, fmt.Errorf("[gabs.ParseJSON] %v", err)
+		return nil, fmt.Errorf("[gabs.ParseJSON] %w", err)
 	}
 
 	i := 0
 	for container.Exists("data", strconv.Itoa(i)) {
 		varSetID := container.Search("data", strconv.Itoa(i), "id").Data().(string)
-		varSetIDToName[varSetID] = container.Search("data", strconv.Itoa(i), "attributes", "name").Data().(string)
+		global, _ := container.Search("data", strconv.Itoa(i), "attributes", "global").Data().(bool)
+		priority, _ := container.Search("data", strconv.Itoa(i), "attributes", "priority").Data().(bool)
+
+		varSets[varSetID] = VarSet{
+			Name:         container.Search("data", strconv.Itoa(i), "attributes", "name").Data().(string),
+			Global:       global,
+			Priority:     priority,
+			WorkspaceIDs: extractRelationshipIDs(container.Search("data", strconv.Itoa(i), "relationships", "workspaces")),
+			ProjectIDs:   extractRelationshipIDs(container.Search("data", strconv.Itoa(i), "relationships", "projects")),
+		}
 		i++
 	}
 
-	return varSetIDToName, nil
+	return varSets, nil
 }
 
-// getVarSetVars pulls down from terraform cloud all variables for each variable set passed in via
-// varSetIDs.
-func (tfc *tfCloud) getVarSetVars(varSetIDsToName map[string]string) (map[string]VariableMap, error) {
-	varSetToVars := map[string]VariableMap{}
-
-	for varSetID := range varSetIDsToName {
-		requestPath := fmt.Sprintf("https://app.terraform.io/api/v2/varsets/%v/relationships/vars", varSetID)
+// extractRelationshipIDs extracts the set of ids within a JSON:API relationship.
+func extractRelationshipIDs(relationship *gabs.Container) map[string]bool {
+	ids := map[string]bool{}
 
-		httpRequest, err := tfc.buildTFCloudHTTPRequest(
-			context.Background(),
-			"getVarSetVars",
-			"GET",
-			requestPath,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("[tfc.buildTFCloudHTTPRequest] %v", err)
+	for _, child := range relationship.Search("data").Children() {
+		if id, ok := child.Search("id").Data().(string); ok {
+			ids[id] = true
 		}
+	}
+
+	return ids
+}
 
-		response, err := tfc.terraformCloudRequest(httpRequest, "getVarSetVars")
+// getVarSetVars pulls down from terraform cloud all variables for each variable set passed in via
+// varSets, keyed by category, along with the sensitive variables whose values are withheld and the
+// variables whose values are HCL.
+func (tfc *tfCloud) getVarSetVars(
+	ctx context.Context, varSets map[string]VarSet,
+) (map[string]map[string]VariableMap, map[string]map[string]string, map[string]map[string]bool, error) {
+	varSetToVars := map[string]map[string]VariableMap{}
+	varSetToWithheld := map[string]map[string]string{}
+	varSetToHCL := map[string]map[string]bool{}
+
+	for varSetID := range varSets {
+		response, err := tfc.getVarSetVariables(ctx, varSetID)
 		if err != nil {
-			return nil, fmt.Errorf("[tfc.buildTFCloudHTTPRequest] %v", err)
+			return nil, nil, nil, fmt.Errorf("[tfc.getVarSetVariables] %w", err)
 		}
 
 		varSetToVars, err = tfc.extractVarsFromVarSet(
 			response, varSetToVars, varSetID,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("[tfc.extractWorkspaceVars] %v", err)
+			return nil, nil, nil, fmt.Errorf("[tfc.extractVarsFromVarSet] %w", err)
 		}
 
+		varSetToWithheld[varSetID], err = extractWithheldVars(response)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("[extractWithheldVars] %w", err)
+		}
+
+		varSetToHCL[varSetID], err = extractHCLVars(response)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("[extractHCLVars] %w", err)
+		}
 	}
-	return varSetToVars, nil
+	return varSetToVars, varSetToWithheld, varSetToHCL, nil
 }
 
-// extractVarsFromVarSet extracts workspace variables from the current variable set's variables.
-func (tfc *tfCloud) extractVarsFromVarSet(
-	varSetVarsResponse []byte,
-	varSetToVars map[string]VariableMap,
-	varSetID string,
-) (map[string]VariableMap, error) {
-	container, err := gabs.ParseJSON(varSetVarsResponse)
+// getVarSetVariables calls the Terraform Cloud API and receives the variables of a variable set as
+// a []byte.
+func (tfc *tfCloud) getVarSetVariables(ctx context.Context, varSetID string) ([]byte, error) {
+	requestName := "getVarSetVars"
+	requestPath := fmt.Sprintf("https://app.terraform.io/api/v2/varsets/%v/relationships/vars", varSetID)
+
+	request, err := tfc.buildTFCloudHTTPRequest(ctx, requestName, "GET", requestPath, nil)
 	if err != nil {
-		return nil, fmt.Errorf("[gabs.ParseJSON] %v", err)
+		return nil, fmt.Errorf("[tfc.buildTFCloudHTTPRequest] %w", err)
 	}
 
-	varMap := VariableMap{}
-	i := 0
-
-	for container.Exists("data", strconv.Itoa(i)) {
-		varKey := container.Search("data", strconv.Itoa(i), "attributes", "key").Data().(string)
-		value := container.Search("data", strconv.Itoa(i), "attributes", "value").Data()
+	jsonResponseBytes, err := tfc.terraformCloudRequest(request, requestName)
+	if err != nil {
+		return nil, fmt.Errorf("[tfc.terraformCloudRequest] %w", err)
+	}
 
-		if value == nil {
-			i++
-			continue
-		}
+	return jsonResponseBytes, nil
+}
 
-		varValue := container.Search("data", strconv.Itoa(i), "attributes", "value").Data().(string)
-		varMap[varKey] = varValue
-		i++
+// extractVarsFromVarSet extracts the current variable set's variables, keyed by category.
+func (tfc *tfCloud) extractVarsFromVarSet(
+	varSetVarsResponse []byte,
+	varSetToVars map[string]map[string]VariableMap,
+	varSetID string,
+) (map[string]map[string]VariableMap, error) {
+	categoryToVars, err := extractVarsByCategory(varSetVarsResponse)
+	if err != nil {
+		return nil, fmt.Errorf("[extractVarsByCategory] %w", err)
 	}
 
-	varSetToVars[varSetID] = varMap
+	varSetToVars[varSetID] = categoryToVars
 
 	return varSetToVars, nil
 }
 
-// getWorkspaceToVarSetIDs produce a map of workspaces to the corresponding var set IDs.
-func (tfc *tfCloud) getWorkspaceToVarSetIDs() (map[string]map[string]bool, error) {
-	ctx := context.Background()
+// getWorkspaceToVarSetScopes produce a map of workspaces to the var set IDs that apply to them,
+// and the scope with which each applies: globally, through the workspace's project, or attached to
+// the workspace directly.
+func (tfc *tfCloud) getWorkspaceToVarSetScopes(
+	ctx context.Context, varSets map[string]VarSet, workspaces []string,
+) (map[string]map[string]VarSetScope, error) {
+	outputMap := map[string]map[string]VarSetScope{}
 
-	outputMap := map[string]map[string]bool{}
+	for _, workspace := range workspaces {
+		workspaceResponse, err := tfc.getWorkspace(ctx, workspace)
+		if err != nil {
+			return nil, fmt.Errorf("[tfc.getWorkspace] %w", err)
+		}
 
-	for workspace := range tfc.config.WorkspaceToDirectory {
-		workspaceID, err := tfc.getWorkspaceID(ctx, workspace)
+		workspaceID, err := extractWorkspaceID(workspaceResponse)
 		if err != nil {
-			return nil, fmt.Errorf("[tfc.getWorkspaceID] %v", err)
+			return nil, fmt.Errorf("[extractWorkspaceID] %w", err)
 		}
 
 		requestPath := fmt.Sprintf(
 			"https://app.terraform.io/api/v2/workspaces/%v/varsets", workspaceID,
 		)
 		httpRequest, err := tfc.buildTFCloudHTTPRequest(
-			context.Background(),
+			ctx,
 			"getWorkspaceVarSets",
 			"GET",
 			requestPath,
+			nil,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("[tfc.buildTFCloudHTTPRequest] %v", err)
+			return nil, fmt.Errorf("[tfc.buildTFCloudHTTPRequest] %w", err)
 		}
 
 		response, err := tfc.terraformCloudRequest(httpRequest, "getWorkspaceVarSets")
 		if err != nil {
-			return nil, fmt.Errorf("[tfc.terraformCloudRequest] %v", err)
+			return nil, fmt.Errorf("[tfc.terraformCloudRequest] %w", err)
 		}
 
 		varSetIDSet, err := tfc.extractVarSetIDsForWorkspace(response)
 		if err != nil {
-			return nil, fmt.Errorf("[tfc.terraformCloudRequest] %v", err)
+			return nil, fmt.Errorf("[tfc.terraformCloudRequest] %w", err)
 		}
 
-		outputMap[workspace] = varSetIDSet
-
+		outputMap[workspace] = workspaceVarSetScopes(
+			varSets, workspaceID, extractWorkspaceProjectID(workspaceResponse), varSetIDSet,
+		)
 	}
 
 	return outputMap, nil
 }
 
+// workspaceVarSetScopes determines which variable sets apply to a workspace, and the scope with
+// which each applies. attachedVarSetIDs are the variable sets Terraform Cloud lists for the workspace.
+func workspaceVarSetScopes(
+	varSets map[string]VarSet, workspaceID string, projectID string, attachedVarSetIDs map[string]bool,
+) map[string]VarSetScope {
+	scopes := map[string]VarSetScope{}
+
+	for varSetID, varSet := range varSets {
+		if scope, ok := varSet.scopeFor(workspaceID, projectID, attachedVarSetIDs[varSetID]); ok {
+			scopes[varSetID] = scope
+		}
+	}
+
+	return scopes
+}
+
 // extractVarSetIDsForWorkspace extracts variable set ids from a response to a request
 // for all resources within a workspace.
 func (tfc *tfCloud) extractVarSetIDsForWorkspace(response []byte) (map[string]bool, error) {
 	container, err := gabs.ParseJSON(response)
 	if err != nil {
-		return nil, fmt.Errorf("[gabs.ParseJSON] %v", err)
+		return nil, fmt.Errorf("[gabs.ParseJSON] %w", err)
 	}
 
 	outputMap := map[string]bool{}
@@ -262,79 +413,209 @@ func (tfc *tfCloud) extractVarSetIDsForWorkspace(response []byte) (map[string]bo
 	return outputMap, nil
 }
 
-// createWorkspaceToVarSetVars takes an input of two maps: var set ids to their variables and
-// workspace to var set ids and returns a map of workspace to variable maps.
-func (tfc *tfCloud) createWorkspaceToVarSetVars(
-	varSetVars map[string]VariableMap, workspaceToVarSetIDs map[string]map[string]bool,
-) (map[string]VariableMap, error) {
-	outputWorkspaceToVariable := map[string]VariableMap{}
+// createWorkspaceToVarSetSources takes an input of five maps: var set ids to their variables by
+// category, var set ids to their withheld sensitive variables, var set ids to their HCL variables,
+// workspace to the scopes of the var sets applied to it, and var set ids to the var sets, and
+// returns a map of workspace to the variable sets applied to it, sorted by name.
+func (tfc *tfCloud) createWorkspaceToVarSetSources(
+	varSetVars map[string]map[string]VariableMap,
+	varSetWithheld map[string]map[string]string,
+	varSetHCL map[string]map[string]bool,
+	workspaceToVarSetScopes map[string]map[string]VarSetScope,
+	varSets map[string]VarSet,
+) map[string][]VariableSource {
+	outputWorkspaceToSources := map[string][]VariableSource{}
+
+	for workspace, varSetScopes := range workspaceToVarSetScopes {
+		sources := []VariableSource{}
+
+		for varSetID, scope := range varSetScopes {
+			sources = append(sources, VariableSource{
+				VarSet:    varSets[varSetID].Name,
+				Scope:     scope,
+				Priority:  varSets[varSetID].Priority,
+				Variables: varSetVars[varSetID]["terraform"],
+				Env:       varSetVars[varSetID]["env"],
+				Withheld:  varSetWithheld[varSetID],
+				HCL:       varSetHCL[varSetID],
+			})
+		}
 
-	var workspaceNameList []string
-	for workspace := range workspaceToVarSetIDs {
-		workspaceNameList = append(workspaceNameList, workspace)
+		sort.Slice(sources, func(i, j int) bool {
+			return sources[i].VarSet < sources[j].VarSet
+		})
+
+		outputWorkspaceToSources[workspace] = sources
 	}
-	sort.Strings(workspaceNameList)
 
-	for _, workspace := range workspaceNameList {
-		varSetIDs := workspaceToVarSetIDs[workspace]
-		currentVarMap := VariableMap{}
+	return outputWorkspaceToSources
+}
 
-		for varSetID := range varSetIDs {
-			currentVarMap = currentVarMap.Merge(varSetVars[varSetID])
-		}
+// resolvedVariables are the Terraform and environment variables of a workspace, once the
+// precedence of their sources is resolved.
+type resolvedVariables struct {
 
-		outputWorkspaceToVariable[workspace] = currentVarMap
-	}
+	// terraform are the values of the Terraform variables.
+	terraform VariableMap
+
+	// terraformSources maps each Terraform variable to the source its value was taken from.
+	terraformSources map[string]string
+
+	// terraformHCL are the Terraform variables whose values are HCL expressions.
+	terraformHCL map[string]bool
 
-	return outputWorkspaceToVariable, nil
+	// terraformSensitive are the Terraform variables that are sensitive.
+	terraformSensitive map[string]bool
+
+	// env are the values of the environment variables.
+	env VariableMap
+
+	// envSources maps each environment variable to the source its value was taken from.
+	envSources map[string]string
+
+	// envSensitive are the environment variables that are sensitive.
+	envSensitive map[string]bool
 }
 
-// PullWorkspaceVariables extracts variables for a single workspace saves into a .tfvars
-// file within the appropriate directory.
-func (tfc *tfCloud) PullWorkspaceVariables(
+// resolveWorkspaceVariables downloads a workspace's variables and resolves them alongside those of
+// its variable sets and the sensitive variables configured. Withheld sensitive variables that take
+// precedence have a source but no value.
+func (tfc *tfCloud) resolveWorkspaceVariables(
 	ctx context.Context,
 	workspaceName string,
-	workspaceToVarSetVars map[string]VariableMap,
-	workspaceToVarSetIDs map[string]map[string]bool,
-	varSetIDsToName map[string]string,
-) error {
+	varSetSources []VariableSource,
+) (resolvedVariables, error) {
 	workspaceVarsContainer, err := tfc.DownloadWorkspaceVariables(ctx, workspaceName)
 	if err != nil {
-		return fmt.Errorf("[tfc.DownloadWorkspaceVariables] %v", err)
+		return resolvedVariables{}, fmt.Errorf("[tfc.DownloadWorkspaceVariables] %w", err)
 	}
 
 	workspaceVarsMap, err := tfc.extractWorkspaceVars(workspaceVarsContainer)
 	if err != nil {
-		return fmt.Errorf("[tfc.parseWorkspaceVars] %v", err)
+		return resolvedVariables{}, fmt.Errorf("[tfc.parseWorkspaceVars] %w", err)
 	}
 
-	workspaceSensitiveEnvMap, workspaceSensitiveVarsMap, err := tfc.createWorkspaceSensitiveVars(
-		workspaceName, workspaceToVarSetIDs, varSetIDsToName,
-	)
+	workspaceWithheld, err := extractWithheldVars(workspaceVarsContainer)
+	if err != nil {
+		return resolvedVariables{}, fmt.Errorf("[extractWithheldVars] %w", err)
+	}
+
+	workspaceHCL, err := extractHCLVars(workspaceVarsContainer)
 	if err != nil {
-		return fmt.Errorf("[tfc.workspaceSensitiveVars] %v", err)
+		return resolvedVariables{}, fmt.Errorf("[extractHCLVars] %w", err)
 	}
 
-	tfVarsFile, err := tfc.generateTFVarsFile(
-		workspaceVarsMap, workspaceToVarSetVars[workspaceName], workspaceSensitiveVarsMap,
+	terraformSources, envSources, err := tfc.createWorkspaceVariableSources(
+		workspaceName, workspaceVarsMap, workspaceWithheld, workspaceHCL, varSetSources,
 	)
 	if err != nil {
-		return fmt.Errorf("[tfc.generateTFVarsFile] %v", err)
+		return resolvedVariables{}, fmt.Errorf("[tfc.createWorkspaceVariableSources] %w", err)
+	}
+
+	var resolved resolvedVariables
+	resolved.terraform, resolved.terraformSources = resolveVariables(terraformSources)
+	resolved.terraformHCL = resolveHCLVariables(terraformSources, resolved.terraformSources)
+	resolved.terraformSensitive = resolveSensitiveVariables(terraformSources, resolved.terraformSources)
+	resolved.env, resolved.envSources = resolveVariables(envSources)
+	resolved.envSensitive = resolveSensitiveVariables(envSources, resolved.envSources)
+
+	return resolved, nil
+}
+
+// PullWorkspaceVariables extracts variables for a single workspace saves into a .tfvars
+// file within the appropriate directory, returning the names of the variables sourced and the
+// workspace's environment variables, which are set only for the workspace's own commands.
+func (tfc *tfCloud) PullWorkspaceVariables(
+	ctx context.Context,
+	workspaceName string,
+	varSetSources []VariableSource,
+) (SourcedVariables, VariableMap, error) {
+	resolved, err := tfc.resolveWorkspaceVariables(ctx, workspaceName, varSetSources)
+	if err != nil {
+		return SourcedVariables{}, nil, fmt.Errorf("[tfc.resolveWorkspaceVariables] %w", err)
 	}
 
-	err = tfc.updateEnvironmentVariables(workspaceSensitiveEnvMap)
+	terraformVariables, terraformVariableSources := resolved.terraform, resolved.terraformSources
+	envVariables, envVariableSources := resolved.env, resolved.envSources
+
+	err = tfc.filterDeclaredVariables(workspaceName, terraformVariables, terraformVariableSources)
 	if err != nil {
-		return fmt.Errorf("[tfc.updateEnvironmentVariables] %v", err)
+		return SourcedVariables{}, nil, fmt.Errorf("[tfc.filterDeclaredVariables] %w", err)
 	}
 
-	fileName := fmt.Sprintf(
-		"/github/workspace%vterraform.tfvars",
-		tfc.config.WorkspaceToDirectory[workspaceName],
+	missingVariables := append(
+		findMissingSensitiveVariables(workspaceName, "terraform", terraformVariables, terraformVariableSources),
+		findMissingSensitiveVariables(workspaceName, "env", envVariables, envVariableSources)...,
 	)
+	if len(missingVariables) > 0 {
+		return SourcedVariables{}, nil, &MissingSensitiveVariablesError{Variables: missingVariables}
+	}
 
-	err = os.WriteFile(fileName, tfVarsFile, 0400)
+	var tfVarsFile []byte
+	if tfc.config.TFVarsFormat == FormatJSON {
+		tfVarsFile, err = tfc.generateTFVarsJSONFile(terraformVariables, resolved.terraformHCL)
+	} else {
+		tfVarsFile, err = tfc.generateTFVarsFile(terraformVariables, resolved.terraformHCL)
+	}
 	if err != nil {
-		return fmt.Errorf("[os.WriteFile] %v", err)
+		return SourcedVariables{}, nil, fmt.Errorf("[tfc.generateTFVarsFile] %w", err)
+	}
+
+	fileName := filepath.Join(
+		tfc.config.RootDirectory, tfc.config.WorkspaceToDirectory[workspaceName], tfc.varsFileName(),
+	)
+
+	// The file may hold sensitive values, so is only readable by the job's user.
+	err = os.WriteFile(fileName, tfVarsFile, 0600)
+	if err != nil {
+		return SourcedVariables{}, nil, fmt.Errorf("[os.WriteFile] %w", err)
+	}
+	tfc.writtenFiles = append(tfc.writtenFiles, fileName)
+
+	return SourcedVariables{
+		Terraform:        terraformVariables.Keys(),
+		Env:              envVariables.Keys(),
+		TerraformSources: terraformVariableSources,
+		EnvSources:       envVariableSources,
+	}, envVariables, nil
+}
+
+// filterDeclaredVariables removes the Terraform variables that the workspace's root module does
+// not declare, unless IncludeUndeclaredVars is set, and warns of required variables that are not set.
+func (tfc *tfCloud) filterDeclaredVariables(
+	workspaceName string, variables VariableMap, variableSources map[string]string,
+) error {
+	directory := filepath.Join(tfc.config.RootDirectory, tfc.config.WorkspaceToDirectory[workspaceName])
+
+	declaredVariables, found, err := readDeclaredVariables(directory)
+	if err != nil {
+		return fmt.Errorf("[readDeclaredVariables] %w", err)
+	}
+
+	if !found {
+		slog.Warn(
+			"No Terraform configuration files were found, so variables are not filtered to those declared.",
+			"workspace", workspaceName, "directory", directory,
+		)
+		return nil
+	}
+
+	if tfc.config.IncludeUndeclaredVars {
+		for varKey := range variableSources {
+			if _, ok := declaredVariables[varKey]; !ok {
+				slog.Warn("Including a variable not declared by the root module.", "workspace", workspaceName, "variable", varKey)
+			}
+		}
+	} else {
+		undeclared := removeUndeclaredVariables(variables, variableSources, declaredVariables)
+		if len(undeclared) > 0 {
+			slog.Info("Omitted variables not declared by the root module.", "workspace", workspaceName, "variables", undeclared)
+		}
+	}
+
+	unset := findUnsetRequiredVariables(variableSources, declaredVariables)
+	if len(unset) > 0 {
+		slog.Warn("Required variables are not set by Terraform Cloud.", "workspace", workspaceName, "variables", unset)
 	}
 
 	return nil
@@ -344,12 +625,12 @@ func (tfc *tfCloud) PullWorkspaceVariables(
 func (tfc *tfCloud) DownloadWorkspaceVariables(ctx context.Context, workspaceName string) ([]byte, error) {
 	workspaceID, err := tfc.getWorkspaceID(ctx, workspaceName)
 	if err != nil {
-		return nil, fmt.Errorf("[tfc.getWorkspaceID] %v", err)
+		return nil, fmt.Errorf("[tfc.getWorkspaceID] %w", err)
 	}
 
 	varsJSON, err := tfc.getWorkspaceVariables(ctx, workspaceID)
 	if err != nil {
-		return nil, fmt.Errorf("[tfc.getWorkspaceVariables] %v", err)
+		return nil, fmt.Errorf("[tfc.getWorkspaceVariables] %w", err)
 	}
 
 	return varsJSON, nil
@@ -364,79 +645,243 @@ func (tfc *tfCloud) getWorkspaceVariables(ctx context.Context, workspaceID strin
 		workspaceID,
 	)
 
-	request, err := tfc.buildTFCloudHTTPRequest(ctx, requestName, "GET", requestPath)
+	request, err := tfc.buildTFCloudHTTPRequest(ctx, requestName, "GET", requestPath, nil)
 	if err != nil {
-		return nil, fmt.Errorf("[tfc.buildTFCloudHTTPRequest] %v", err)
+		return nil, fmt.Errorf("[tfc.buildTFCloudHTTPRequest] %w", err)
 	}
 
 	jsonResponseBytes, err := tfc.terraformCloudRequest(request, requestName)
 
 	if err != nil {
-		return nil, fmt.Errorf("[tfc.terraformCloudRequest] %v", err)
+		return nil, fmt.Errorf("[tfc.terraformCloudRequest] %w", err)
 	}
 
 	return jsonResponseBytes, nil
 }
 
-// extractWorkspaceVars extracts workspace variables from a []byte from the Terraform Cloud
-// endpoint and places them into a VariableMap.
-func (tfc *tfCloud) extractWorkspaceVars(workspaceResponse []byte) (VariableMap, error) {
-	container, err := gabs.ParseJSON(workspaceResponse)
+// extractWithheldVars extracts the sensitive variables whose values Terraform Cloud withholds from
+// a []byte from the Terraform Cloud endpoint, mapped to their category.
+func extractWithheldVars(response []byte) (map[string]string, error) {
+	container, err := gabs.ParseJSON(response)
 	if err != nil {
-		return nil, fmt.Errorf("[gabs.ParseJSON] %v", err)
+		return nil, fmt.Errorf("[gabs.ParseJSON] %w", err)
 	}
 
-	outputVarMap := VariableMap{}
+	withheld := map[string]string{}
 
-	i := 0
-	for container.Exists("data", strconv.Itoa(i)) {
-		varKey := container.Search("data", strconv.Itoa(i), "attributes", "key").Data().(string)
-		value := container.Search("data", strconv.Itoa(i), "attributes", "value").Data()
+	for _, variable := range container.Search("data").Children() {
+		sensitive, _ := variable.Search("attributes", "sensitive").Data().(bool)
+		if !sensitive || variable.Search("attributes", "value").Data() != nil {
+			continue
+		}
 
-		if value == nil {
-			i++
+		varKey, ok := variable.Search("attributes", "key").Data().(string)
+		if !ok {
 			continue
 		}
-		varValue := container.Search("data", strconv.Itoa(i), "attributes", "value").Data().(string)
-		outputVarMap[varKey] = varValue
-		i++
+
+		category, ok := variable.Search("attributes", "category").Data().(string)
+		if !ok {
+			category = "terraform"
+		}
+
+		withheld[varKey] = category
 	}
 
-	return outputVarMap, nil
+	return withheld, nil
 }
 
-// createWorkspaceSensitiveVars produces collections of sensitive workspace variables.
-func (tfc *tfCloud) createWorkspaceSensitiveVars(
-	workspaceName string,
-	workspaceToVarSetIDs map[string]map[string]bool,
-	varSetIDToName map[string]string,
-) (VariableMap, VariableMap, error) {
-	allVariablesEnv := VariableMap{}
-	allVariablesTerraform := VariableMap{}
-
-	for varSetID := range workspaceToVarSetIDs[workspaceName] {
-		varSetName := varSetIDToName[varSetID]
-		if _, ok := tfc.config.TerraformVarSetSensitiveVars[varSetName]; !ok {
+// extractHCLVars extracts the Terraform variables whose values are HCL expressions from a []byte
+// from the Terraform Cloud endpoint.
+func extractHCLVars(response []byte) (map[string]bool, error) {
+	container, err := gabs.ParseJSON(response)
+	if err != nil {
+		return nil, fmt.Errorf("[gabs.ParseJSON] %w", err)
+	}
+
+	hclVars := map[string]bool{}
+
+	for _, variable := range container.Search("data").Children() {
+		isHCL, _ := variable.Search("attributes", "hcl").Data().(bool)
+		category, _ := variable.Search("attributes", "category").Data().(string)
+		varKey, ok := variable.Search("attributes", "key").Data().(string)
+
+		if isHCL && category == "terraform" && ok {
+			hclVars[varKey] = true
+		}
+	}
+
+	return hclVars, nil
+}
+
+// extractWorkspaceVars extracts workspace variables from a []byte from the Terraform Cloud
+// endpoint and places them into a VariableMap for each category.
+func (tfc *tfCloud) extractWorkspaceVars(workspaceResponse []byte) (map[string]VariableMap, error) {
+	categoryToVars, err := extractVarsByCategory(workspaceResponse)
+	if err != nil {
+		return nil, fmt.Errorf("[extractVarsByCategory] %w", err)
+	}
+
+	return categoryToVars, nil
+}
+
+// extractVarsByCategory extracts the variables with values from a []byte from the Terraform Cloud
+// endpoint into a VariableMap for each of the "terraform" and "env" categories. Variables without
+// a category are Terraform variables.
+func extractVarsByCategory(response []byte) (map[string]VariableMap, error) {
+	container, err := gabs.ParseJSON(response)
+	if err != nil {
+		return nil, fmt.Errorf("[gabs.ParseJSON] %w", err)
+	}
+
+	categoryToVars := map[string]VariableMap{
+		"terraform": {},
+		"env":       {},
+	}
+
+	for _, variable := range container.Search("data").Children() {
+		varKey, ok := variable.Search("attributes", "key").Data().(string)
+		if !ok {
 			continue
 		}
-		currentVarSetSensitiveVars := tfc.config.TerraformVarSetSensitiveVars[varSetName]
-		currentVarMapEnv, currentVarMapTerraform, err := tfc.variablesToVariableMaps(currentVarSetSensitiveVars)
+
+		varValue, ok := variable.Search("attributes", "value").Data().(string)
+		if !ok {
+			continue
+		}
+
+		category, ok := variable.Search("attributes", "category").Data().(string)
+		if !ok {
+			category = "terraform"
+		}
+
+		if _, ok := categoryToVars[category]; !ok {
+			return nil, fmt.Errorf("variable %v has an unknown category: %v", varKey, category)
+		}
+		categoryToVars[category][varKey] = varValue
+	}
+
+	return categoryToVars, nil
+}
+
+// createWorkspaceVariableSources produces the sources of a workspace's Terraform and environment
+// variables: the workspace's own variables and each of its variable sets. The sensitive variables
+// configured for the workspace or a variable set are added to that source, as Terraform Cloud does
+// not return their values, so that they take the same precedence as they do within Terraform Cloud.
+// Sensitive variables that Terraform Cloud withholds and are not configured remain withheld, and
+// configured values of HCL variables are read as HCL, as Terraform Cloud would.
+func (tfc *tfCloud) createWorkspaceVariableSources(
+	workspaceName string,
+	workspaceVars map[string]VariableMap,
+	workspaceWithheld map[string]string,
+	workspaceHCL map[string]bool,
+	varSetSources []VariableSource,
+) ([]VariableSource, []VariableSource, error) {
+	var terraformSources []VariableSource
+	var envSources []VariableSource
+
+	for _, varSetSource := range varSetSources {
+		varMapEnv, varMapTerraform, err := tfc.variablesToVariableMaps(
+			tfc.config.TerraformVarSetSensitiveVars[varSetSource.VarSet],
+		)
 		if err != nil {
-			return nil, nil, fmt.Errorf("[tfc.variablesToVariableMaps] %v", err)
+			return nil, nil, fmt.Errorf("[tfc.variablesToVariableMaps] %w", err)
 		}
 
-		allVariablesTerraform = allVariablesTerraform.Merge(currentVarMapTerraform)
-		allVariablesEnv = allVariablesEnv.Merge(currentVarMapEnv)
+		terraformSource := varSetSource
+		terraformSource.Variables = varSetSource.Variables.Merge(varMapTerraform)
+		terraformSource.Env = nil
+		terraformSource.Withheld = unsuppliedVariables(varSetSource.Withheld, "terraform", varMapTerraform)
+		terraformSource.Sensitive = sensitiveVariables(varSetSource.Withheld, "terraform", varMapTerraform)
+		terraformSources = append(terraformSources, terraformSource)
+
+		envSource := varSetSource
+		envSource.Variables = varSetSource.Env.Merge(varMapEnv)
+		envSource.Env = nil
+		envSource.Withheld = unsuppliedVariables(varSetSource.Withheld, "env", varMapEnv)
+		envSource.Sensitive = sensitiveVariables(varSetSource.Withheld, "env", varMapEnv)
+		envSource.HCL = nil
+		envSources = append(envSources, envSource)
 	}
 
-	workspaceSensitiveVars := tfc.config.TerraformWorkspaceSensitiveVars[workspaceName]
-	workspaceVarMapEnv, workspaceVarMapTerraform, err := tfc.variablesToVariableMaps(workspaceSensitiveVars)
+	varMapEnv, varMapTerraform, err := tfc.variablesToVariableMaps(
+		tfc.config.TerraformWorkspaceSensitiveVars[workspaceName],
+	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("[tfc.variablesToVariableMaps] %v", err)
+		return nil, nil, fmt.Errorf("[tfc.variablesToVariableMaps] %w", err)
 	}
-	allVariablesEnv = allVariablesEnv.Merge(workspaceVarMapEnv)
-	allVariablesTerraform = allVariablesTerraform.Merge(workspaceVarMapTerraform)
-	return allVariablesEnv, allVariablesTerraform, nil
+
+	workspaceTerraformVars, workspaceEnvVars := workspaceVars["terraform"], workspaceVars["env"]
+
+	terraformSources = append(terraformSources, VariableSource{
+		Variables: workspaceTerraformVars.Merge(varMapTerraform),
+		Withheld:  unsuppliedVariables(workspaceWithheld, "terraform", varMapTerraform),
+		Sensitive: sensitiveVariables(workspaceWithheld, "terraform", varMapTerraform),
+		HCL:       workspaceHCL,
+	})
+	envSources = append(envSources, VariableSource{
+		Variables: workspaceEnvVars.Merge(varMapEnv),
+		Withheld:  unsuppliedVariables(workspaceWithheld, "env", varMapEnv),
+		Sensitive: sensitiveVariables(workspaceWithheld, "env", varMapEnv),
+	})
+
+	return terraformSources, envSources, nil
+}
+
+// unsuppliedVariables returns the withheld variables of the category that have not been supplied.
+func unsuppliedVariables(withheld map[string]string, category string, supplied VariableMap) map[string]string {
+	unsupplied := map[string]string{}
+
+	for varKey, varCategory := range withheld {
+		if _, ok := supplied[varKey]; varCategory == category && !ok {
+			unsupplied[varKey] = varCategory
+		}
+	}
+
+	return unsupplied
+}
+
+// sensitiveVariables returns the sensitive variables of the category: those withheld by Terraform
+// Cloud, and those supplied by the sensitive variables configured.
+func sensitiveVariables(withheld map[string]string, category string, supplied VariableMap) map[string]bool {
+	sensitive := map[string]bool{}
+
+	for varKey, varCategory := range withheld {
+		if varCategory == category {
+			sensitive[varKey] = true
+		}
+	}
+
+	for varKey := range supplied {
+		sensitive[varKey] = true
+	}
+
+	return sensitive
+}
+
+// findMissingSensitiveVariables returns the withheld variables of the category that take
+// precedence within a workspace, which have a source but no value, sorted by key.
+func findMissingSensitiveVariables(
+	workspaceName string, category string, variables VariableMap, variableSources map[string]string,
+) []MissingSensitiveVariable {
+	var missingVariables []MissingSensitiveVariable
+
+	for varKey, source := range variableSources {
+		if _, ok := variables[varKey]; !ok {
+			missingVariables = append(missingVariables, MissingSensitiveVariable{
+				Workspace: workspaceName,
+				Key:       varKey,
+				Category:  category,
+				Source:    source,
+			})
+		}
+	}
+
+	sort.Slice(missingVariables, func(i, j int) bool {
+		return missingVariables[i].Key < missingVariables[j].Key
+	})
+
+	return missingVariables
 }
 
 // variablesToVariableMaps converts a sensitive variables object to two VariableMaps,
@@ -462,76 +907,160 @@ func (tfc *tfCloud) variablesToVariableMaps(vars Variables) (VariableMap, Variab
 	return varMapEnv, varMapTerraform, nil
 }
 
-// generateTFVarsFile aggregates varset variables and workspace-specific variables to create a
-// .tfvars file for the current workspace.
+// generateTFVarsFile creates a .tfvars file for the current workspace from its resolved variables.
+// The values of hclVariables are HCL expressions, which are written as the values they evaluate to.
 func (tfc *tfCloud) generateTFVarsFile(
-	workspaceVars VariableMap,
-	workspaceVarSetVars VariableMap,
-	workspaceSensitiveVars VariableMap,
+	workspaceCompleteVariableMap VariableMap, hclVariables map[string]bool,
 ) ([]byte, error) {
-	// workspace variables assigned to the variable itself has priority in Terraform cloud,
-	// which is reflected here
-	workspaceVariableMap := workspaceVarSetVars.Merge(workspaceVars)
-	workspaceCompleteVariableMap := workspaceVariableMap.Merge(workspaceSensitiveVars)
-
 	f := hclwrite.NewEmptyFile()
 	body := f.Body()
 
-	allKeys := []string{}
+	// This sorting is helpful for cleaner output and allows unit tests to be deterministic.
+	for _, k := range workspaceCompleteVariableMap.Keys() {
+		value, err := variableValue(k, workspaceCompleteVariableMap[k], hclVariables[k])
+		if err != nil {
+			return nil, fmt.Errorf("[variableValue] %w", err)
+		}
+		body.SetAttributeValue(k, value)
+	}
+
+	return f.Bytes(), nil
+}
+
+// generateTFVarsJSONFile creates a .tfvars.json file for the current workspace from its resolved
+// variables. Strings, numbers, lists, and objects are encoded as their JSON equivalents, with keys
+// sorted and indented by two spaces, so that the same variables always produce the same file.
+func (tfc *tfCloud) generateTFVarsJSONFile(
+	workspaceCompleteVariableMap VariableMap, hclVariables map[string]bool,
+) ([]byte, error) {
+	jsonValues := map[string]interface{}{}
 
-	for k := range workspaceCompleteVariableMap {
-		allKeys = append(allKeys, k)
+	for _, k := range workspaceCompleteVariableMap.Keys() {
+		jsonValue, err := variableJSONValue(k, workspaceCompleteVariableMap[k], hclVariables[k])
+		if err != nil {
+			return nil, fmt.Errorf("[variableJSONValue] %w", err)
+		}
+		jsonValues[k] = jsonValue
 	}
 
-	// This sorting is helpful for cleaner output and allows unit tests to be deterministic.
-	sort.Strings(allKeys)
+	jsonBytes, err := encodeJSON(jsonValues)
+	if err != nil {
+		return nil, fmt.Errorf("[encodeJSON] %w", err)
+	}
+
+	return jsonBytes, nil
+}
+
+// variableJSONValue returns the value of a variable as Terraform reads it, ready to be encoded
+// by encodeJSON.
+func variableJSONValue(varKey string, varValue string, isHCL bool) (interface{}, error) {
+	value, err := variableValue(varKey, varValue, isHCL)
+	if err != nil {
+		return nil, fmt.Errorf("[variableValue] %w", err)
+	}
+
+	if value.IsNull() {
+		return nil, nil
+	}
+
+	jsonBytes, err := ctyjson.Marshal(value, value.Type())
+	if err != nil {
+		return nil, fmt.Errorf("[ctyjson.Marshal] %v: %w", varKey, err)
+	}
+
+	// The value is decoded again so that encodeJSON sorts its keys and does not escape HTML
+	// characters, keeping numbers exactly as written.
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.UseNumber()
+
+	var jsonValue interface{}
+	err = decoder.Decode(&jsonValue)
+	if err != nil {
+		return nil, fmt.Errorf("[decoder.Decode] %v: %w", varKey, err)
+	}
+
+	return jsonValue, nil
+}
+
+// encodeJSON encodes a value as JSON indented by two spaces, with the keys of maps sorted and
+// HTML characters left unescaped.
+func encodeJSON(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	err := encoder.Encode(value)
+	if err != nil {
+		return nil, fmt.Errorf("[encoder.Encode] %w", err)
+	}
 
-	for _, k := range allKeys {
-		if workspaceCompleteVariableMap[k] == "null" {
-			fmt.Printf(
-				"null value has been specified for variable %v - this variable might need to be specified as a sensitive variable",
-				k,
+	return buffer.Bytes(), nil
+}
+
+// variableValue returns the value of a variable as Terraform reads it: a string, unless the
+// variable is HCL, in which case its value must be an HCL expression of literal values.
+func variableValue(varKey string, varValue string, isHCL bool) (cty.Value, error) {
+	if !isHCL {
+		if varValue == "null" {
+			slog.Warn(
+				"null value has been specified for a variable - this variable might need to be specified as a sensitive variable",
+				"variable", varKey,
 			)
 		}
-		body.SetAttributeValue(k, cty.StringVal(workspaceCompleteVariableMap[k]))
+		return cty.StringVal(varValue), nil
 	}
 
-	return f.Bytes(), nil
+	expression, diags := hclsyntax.ParseExpression([]byte(varValue), varKey, hcl.InitialPos)
+	if diags.HasErrors() {
+		return cty.NilVal, fmt.Errorf("[hclsyntax.ParseExpression] %v: %w", varKey, diags)
+	}
+
+	value, diags := expression.Value(nil)
+	if diags.HasErrors() {
+		return cty.NilVal, fmt.Errorf("the HCL variable %v must only contain literal values: %w", varKey, diags)
+	}
+
+	return value, nil
 }
 
-// updateEnvironmentVariables
-func (tfc *tfCloud) updateEnvironmentVariables(workspaceSensitiveEnvMap VariableMap) error {
-	for k, v := range workspaceSensitiveEnvMap {
-		err := os.Setenv(k, v)
-		if err != nil {
-			return fmt.Errorf("[os.Setenv] %v", err)
-		}
+// varsFileName returns the name of the variables file written to each workspace directory, in
+// the format configured.
+func (tfc *tfCloud) varsFileName() string {
+	if tfc.config.TFVarsFormat == FormatJSON {
+		return varsFileName + ".json"
 	}
-	return nil
+
+	return varsFileName
 }
 
 // getWorkspaceID calls the Terraform Cloud API and gets the workspace ID for the
 // relevant workspace name in the relevant organization.
 func (tfc *tfCloud) getWorkspaceID(ctx context.Context, workspaceName string) (string, error) {
+	jsonResponseBytes, err := tfc.getWorkspace(ctx, workspaceName)
+	if err != nil {
+		return "", err
+	}
+
+	return extractWorkspaceID(jsonResponseBytes)
+}
+
+// getWorkspace calls the Terraform Cloud API and gets the workspace for the relevant workspace
+// name in the relevant organization.
+func (tfc *tfCloud) getWorkspace(ctx context.Context, workspaceName string) ([]byte, error) {
 	requestName := "getWorkspaceID"
 	requestPath := fmt.Sprintf(
 		"https://app.terraform.io/api/v2/organizations/%v/workspaces/%v",
 		tfc.config.TerraformCloudOrganization, workspaceName,
 	)
 
-	request, err := tfc.buildTFCloudHTTPRequest(ctx, requestName, "GET", requestPath)
+	request, err := tfc.buildTFCloudHTTPRequest(ctx, requestName, "GET", requestPath, nil)
 
 	if err != nil {
-		return "", fmt.Errorf("[%v] error in newRequest: %v", requestName, err)
+		return nil, fmt.Errorf("[%v] error in newRequest: %w", requestName, err)
 	}
 
-	jsonResponseBytes, err := tfc.terraformCloudRequest(request, requestName)
-
-	if err != nil {
-		return "", err
-	}
-
-	return extractWorkspaceID(jsonResponseBytes)
+	return tfc.terraformCloudRequest(request, requestName)
 }
 
 // extractWorkspaceID is a helper function that uses the gabs library to pull out the workspace ID
@@ -539,7 +1068,7 @@ func (tfc *tfCloud) getWorkspaceID(ctx context.Context, workspaceName string) (s
 func extractWorkspaceID(jsonBytes []byte) (string, error) {
 	jsonParsed, err := gabs.ParseJSON(jsonBytes)
 	if err != nil {
-		return "", fmt.Errorf("[getWorkspaceID] error in parsing bytes array to json via 'gabs': %v", err)
+		return "", fmt.Errorf("[getWorkspaceID] error in parsing bytes array to json via 'gabs': %w", err)
 	}
 
 	value, ok := jsonParsed.Path("data.id").Data().(string)
@@ -550,25 +1079,46 @@ func extractWorkspaceID(jsonBytes []byte) (string, error) {
 	return value, nil
 }
 
+// extractWorkspaceProjectID is a helper function that uses the gabs library to pull out the ID of
+// the project a workspace belongs to from a Terraform Cloud API response, returning an empty
+// string if the workspace is not within a project.
+func extractWorkspaceProjectID(jsonBytes []byte) string {
+	jsonParsed, err := gabs.ParseJSON(jsonBytes)
+	if err != nil {
+		return ""
+	}
+
+	value, _ := jsonParsed.Path("data.relationships.project.data.id").Data().(string)
+	return value
+}
+
 // terraformCloudRequest build, executes, and processes an API call to the Terraform Cloud API.
 func (tfc *tfCloud) terraformCloudRequest(request *http.Request, requestName string) ([]byte, error) {
 
 	response, err := tfc.httpClient.Do(request)
 
 	if err != nil {
-		return nil, fmt.Errorf("[%v] error in http GET request to Terraform cloud: %v", requestName, err)
+		return nil, fmt.Errorf("[%v] error in http GET request to Terraform cloud: %w", requestName, err)
 	}
 
 	defer response.Body.Close()
-	if response.StatusCode != 200 {
-		return nil, fmt.Errorf("[%v] was unsuccessful, with the server returning: %v", requestName, response.StatusCode)
-	}
 
 	// Read in response body to bytes array.
 	outputBytes, err := io.ReadAll(response.Body)
 
 	if err != nil {
-		return nil, fmt.Errorf("[%v] error in reading response into bytes array: %v", requestName, err)
+		return nil, fmt.Errorf("[%v] error in reading response into bytes array: %w", requestName, err)
+	}
+
+	if !(response.StatusCode <= 299) {
+		return nil, &APIError{APIError: tfcapi.APIError{
+			Request:    requestName,
+			Method:     request.Method,
+			Path:       request.URL.Path,
+			StatusCode: response.StatusCode,
+			Body:       string(outputBytes),
+			Errors:     tfcapi.ExtractErrorDetails(outputBytes),
+		}}
 	}
 
 	return outputBytes, nil
@@ -576,11 +1126,11 @@ func (tfc *tfCloud) terraformCloudRequest(request *http.Request, requestName str
 
 // buildTFCloudHTTPRequest structures a request to the Terraform Cloud api.
 func (tfc *tfCloud) buildTFCloudHTTPRequest(
-	ctx context.Context, requestName string, method string, requestPath string,
+	ctx context.Context, requestName string, method string, requestPath string, body io.Reader,
 ) (*http.Request, error) {
-	request, err := http.NewRequestWithContext(ctx, method, requestPath, nil)
+	request, err := http.NewRequestWithContext(ctx, method, requestPath, body)
 	if err != nil {
-		return nil, fmt.Errorf("[%v] error in http request instantiation: %v", requestName, err)
+		return nil, fmt.Errorf("[%v] error in http request instantiation: %w", requestName, err)
 	}
 
 	request.Header = http.Header{
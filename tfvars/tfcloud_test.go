@@ -2,9 +2,12 @@ package tfvars
 
 import (
 	"context"
+	"errors"
+	"io/fs"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"testing"
@@ -77,128 +80,219 @@ func CreateTFC(t *testing.T) tfCloud {
 	return tfc
 }
 
-func TestCreateWorkspaceSensitiveVars(t *testing.T) {
+func TestCreateWorkspaceVariableSources(t *testing.T) {
 	tfc := CreateTFC(t)
 
 	inputWorkspaceName := "workspace_example"
 
-	inputWorkspaceToVarSetIDs := map[string]map[string]bool{
-		"workspace_example": {
-			"asdqw123_1": true,
-			"asdqw123_2": true,
+	inputWorkspaceVars := map[string]VariableMap{
+		"terraform": {"key_xyz": "null", "key_5": "val_5"},
+		"env":       {"TF_LOG": "DEBUG"},
+	}
+
+	inputVarSetSources := []VariableSource{
+		{
+			VarSet: "var_set_1", Scope: ScopeWorkspace, Variables: VariableMap{"key_6": "val_6"},
+			Env: VariableMap{"AWS_REGION": "us-east1"},
+		},
+		{VarSet: "var_set_2", Scope: ScopeWorkspace, Variables: VariableMap{}},
+	}
+
+	expectedTerraformSources := []VariableSource{
+		{
+			VarSet: "var_set_1", Scope: ScopeWorkspace,
+			Variables: VariableMap{"key_2": "val_2", "key_6": "val_6"}, Withheld: map[string]string{},
+			Sensitive: map[string]bool{"key_2": true},
+		},
+		{
+			VarSet: "var_set_2", Scope: ScopeWorkspace, Variables: VariableMap{"key_3": "val_3"}, Withheld: map[string]string{},
+			Sensitive: map[string]bool{"key_3": true},
 		},
-		"workspace_example_2": {
-			"asdqw123_3": true,
-			"asdqw123_4": true,
+		{
+			Variables: VariableMap{"key_5": "val_5", "key_xyz": "val_2"}, Withheld: map[string]string{},
+			HCL: map[string]bool{"key_5": true}, Sensitive: map[string]bool{"key_xyz": true},
 		},
 	}
 
-	inputVarSetIDToName := map[string]string{
-		"asdqw123_1": "var_set_1",
-		"asdqw123_2": "var_set_2",
-		"asdqw123_3": "var_set_3",
-		"asdqw123_4": "var_set_4",
+	expectedEnvSources := []VariableSource{
+		{
+			VarSet: "var_set_1", Scope: ScopeWorkspace, Variables: VariableMap{"key_1": "val_1", "AWS_REGION": "us-east1"},
+			Withheld: map[string]string{}, Sensitive: map[string]bool{"key_1": true},
+		},
+		{
+			VarSet: "var_set_2", Scope: ScopeWorkspace, Variables: VariableMap{"key_4": "val_4"}, Withheld: map[string]string{},
+			Sensitive: map[string]bool{"key_4": true},
+		},
+		{
+			Variables: VariableMap{"key_1": "val_new_1", "TF_LOG": "DEBUG"}, Withheld: map[string]string{},
+			Sensitive: map[string]bool{"key_1": true},
+		},
 	}
 
-	expectedEnvVarMap := VariableMap{
-		"key_1": "val_new_1",
-		"key_4": "val_4",
+	outputTerraformSources, outputEnvSources, err := tfc.createWorkspaceVariableSources(
+		inputWorkspaceName,
+		inputWorkspaceVars,
+		map[string]string{},
+		map[string]bool{"key_5": true},
+		inputVarSetSources,
+	)
+	if err != nil {
+		t.Errorf("unexpected error from tfc.createWorkspaceVariableSources: %v", err)
 	}
 
-	expectedTerraformVarMap := VariableMap{
-		"key_2":   "val_2",
-		"key_3":   "val_3",
-		"key_xyz": "val_2",
+	if !reflect.DeepEqual(expectedTerraformSources, outputTerraformSources) {
+		t.Errorf("got %v, expected %v", outputTerraformSources, expectedTerraformSources)
 	}
 
-	outputEnvVarMap, outputTerraformVarMap, err := tfc.createWorkspaceSensitiveVars(
-		inputWorkspaceName,
-		inputWorkspaceToVarSetIDs,
-		inputVarSetIDToName,
+	if !reflect.DeepEqual(expectedEnvSources, outputEnvSources) {
+		t.Errorf("got %v, expected %v", outputEnvSources, expectedEnvSources)
+	}
+
+	envVariables, envVariableSources := resolveVariables(outputEnvSources)
+
+	expectedEnvVariables := VariableMap{"key_1": "val_new_1", "key_4": "val_4", "AWS_REGION": "us-east1", "TF_LOG": "DEBUG"}
+	if !reflect.DeepEqual(expectedEnvVariables, envVariables) {
+		t.Errorf("got %v, expected %v", envVariables, expectedEnvVariables)
+	}
+
+	expectedEnvVariableSources := map[string]string{
+		"key_1": "workspace", "key_4": "varset:var_set_2", "AWS_REGION": "varset:var_set_1", "TF_LOG": "workspace",
+	}
+	if !reflect.DeepEqual(expectedEnvVariableSources, envVariableSources) {
+		t.Errorf("got %v, expected %v", envVariableSources, expectedEnvVariableSources)
+	}
+}
+
+func TestFindMissingSensitiveVariables(t *testing.T) {
+	tfc := CreateTFC(t)
+
+	inputWorkspaceVars := map[string]VariableMap{"terraform": {"region": "us-east-1"}, "env": {}}
+
+	// key_xyz is supplied by the config of workspace_example, while db_password is not.
+	inputWorkspaceWithheld := map[string]string{"key_xyz": "terraform", "db_password": "terraform"}
+
+	inputVarSetSources := []VariableSource{
+		{
+			VarSet: "var_set_1", Scope: ScopeWorkspace, Variables: VariableMap{},
+			// key_2 and key_1 are supplied by the config of var_set_1, while AWS_SECRET_ACCESS_KEY is not.
+			Withheld: map[string]string{"key_2": "terraform", "key_1": "env", "AWS_SECRET_ACCESS_KEY": "env"},
+		},
+		{
+			VarSet: "global", Scope: ScopeGlobal, Variables: VariableMap{},
+			// region is overridden by the workspace, so it need not be supplied.
+			Withheld: map[string]string{"region": "terraform"},
+		},
+	}
+
+	terraformSources, envSources, err := tfc.createWorkspaceVariableSources(
+		"workspace_example", inputWorkspaceVars, inputWorkspaceWithheld, map[string]bool{}, inputVarSetSources,
 	)
 	if err != nil {
-		t.Errorf("unexpected error from tfc.createWorkspaceSensitiveVars")
+		t.Errorf("unexpected error from tfc.createWorkspaceVariableSources: %v", err)
 	}
 
-	if !reflect.DeepEqual(expectedEnvVarMap, outputEnvVarMap) {
-		t.Errorf("got %v, expected %v", outputEnvVarMap, expectedEnvVarMap)
+	terraformVariables, terraformVariableSources := resolveVariables(terraformSources)
+	envVariables, envVariableSources := resolveVariables(envSources)
+
+	output := append(
+		findMissingSensitiveVariables("workspace_example", "terraform", terraformVariables, terraformVariableSources),
+		findMissingSensitiveVariables("workspace_example", "env", envVariables, envVariableSources)...,
+	)
+
+	expectedOutput := []MissingSensitiveVariable{
+		{Workspace: "workspace_example", Key: "db_password", Category: "terraform", Source: "workspace"},
+		{Workspace: "workspace_example", Key: "AWS_SECRET_ACCESS_KEY", Category: "env", Source: "varset:var_set_1"},
 	}
 
-	if !reflect.DeepEqual(expectedTerraformVarMap, outputTerraformVarMap) {
-		t.Errorf("got %v, expected %v", outputTerraformVarMap, expectedTerraformVarMap)
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
 	}
 }
 
-func TestCreateWorkspaceToVarSetVars(t *testing.T) {
-	inputVarSetVars := map[string]VariableMap{
+func TestCreateWorkspaceToVarSetSources(t *testing.T) {
+	inputVarSetVars := map[string]map[string]VariableMap{
 		"var_set_id_1": {
-			"var1": "abc",
-			"var2": "abc",
+			"terraform": {"var1": "abc", "var2": "abc"},
+			"env":       {},
 		},
 		"var_set_id_2": {
-			"var1": "edf",
-			"var3": "xyz",
+			"terraform": {"var1": "edf", "var3": "xyz"},
+			"env":       {},
 		},
 		"var_set_id_3": {
-			"var4": "123",
+			"terraform": {"var4": "123"},
+			"env":       {"AWS_REGION": "us-east1"},
 		},
 	}
 
-	inputWorkspaceToVarSetIDs := map[string]map[string]bool{
-		"workspace_1": {"var_set_id_1": true, "var_set_id_2": true},
-		"workspace_2": {"var_set_id_3": true},
+	inputWorkspaceToVarSetScopes := map[string]map[string]VarSetScope{
+		"workspace_1": {"var_set_id_1": ScopeWorkspace, "var_set_id_2": ScopeWorkspace},
+		"workspace_2": {"var_set_id_3": ScopeGlobal},
 	}
 
-	expectedOutput := map[string]VariableMap{
+	inputVarSets := map[string]VarSet{
+		"var_set_id_1": {Name: "var_set_b"},
+		"var_set_id_2": {Name: "var_set_a"},
+		"var_set_id_3": {Name: "var_set_c", Global: true, Priority: true},
+	}
+
+	expectedOutput := map[string][]VariableSource{
 		"workspace_1": {
-			"var1": "edf",
-			"var2": "abc",
-			"var3": "xyz",
+			{
+				VarSet: "var_set_a", Scope: ScopeWorkspace, Variables: VariableMap{"var1": "edf", "var3": "xyz"},
+				Env: VariableMap{}, HCL: map[string]bool{"var3": true},
+			},
+			{
+				VarSet: "var_set_b", Scope: ScopeWorkspace, Variables: VariableMap{"var1": "abc", "var2": "abc"},
+				Env: VariableMap{},
+			},
 		},
 		"workspace_2": {
-			"var4": "123",
+			{
+				VarSet: "var_set_c", Scope: ScopeGlobal, Priority: true, Variables: VariableMap{"var4": "123"},
+				Env: VariableMap{"AWS_REGION": "us-east1"},
+			},
 		},
 	}
 
 	tfc := CreateTFC(t)
 
-	outputWorkspaceToVarSetVars, err := tfc.createWorkspaceToVarSetVars(
+	outputWorkspaceToVarSetSources := tfc.createWorkspaceToVarSetSources(
 		inputVarSetVars,
-		inputWorkspaceToVarSetIDs,
+		map[string]map[string]string{},
+		map[string]map[string]bool{"var_set_id_2": {"var3": true}},
+		inputWorkspaceToVarSetScopes,
+		inputVarSets,
 	)
-	if err != nil {
-		t.Errorf("unexpected error: %v", err)
+
+	if !reflect.DeepEqual(expectedOutput, outputWorkspaceToVarSetSources) {
+		t.Errorf("got %v, expected %v", outputWorkspaceToVarSetSources, expectedOutput)
 	}
 
-	if !reflect.DeepEqual(expectedOutput, outputWorkspaceToVarSetVars) {
-		t.Errorf("got %v, expected %v", outputWorkspaceToVarSetVars, expectedOutput)
+	// var_set_a is lexically first, so its value of var1 takes precedence.
+	outputVariables, _ := resolveVariables(outputWorkspaceToVarSetSources["workspace_1"])
+	expectedVariables := VariableMap{"var1": "edf", "var2": "abc", "var3": "xyz"}
+
+	if !reflect.DeepEqual(expectedVariables, outputVariables) {
+		t.Errorf("got %v, expected %v", outputVariables, expectedVariables)
 	}
 }
 
 func TestGenerateTFVarsFile(t *testing.T) {
-	inputWorkspaceVars := VariableMap{
+	inputVariables := VariableMap{
+		"varTHM": "non-null value",
+		"varXYZ": "non-null value",
 		"var_1":  "val_1",
 		"var_2":  "val_2",
-		"varXYZ": "null",
-	}
-
-	inputWorkspaceVarSetVars := VariableMap{
-		"var_2":  "val_xyz",
 		"var_3":  "val_3",
-		"varTHM": "null",
-	}
-
-	inputWorkspaceSensitiveVars := VariableMap{
-		"varXYZ": "non-null value",
-		"varTHM": "non-null value",
+		"varNUL": "null",
 	}
 
 	tfc := CreateTFC(t)
-	byteArray, _ := tfc.generateTFVarsFile(
-		inputWorkspaceVars, inputWorkspaceVarSetVars, inputWorkspaceSensitiveVars,
-	)
+	byteArray, _ := tfc.generateTFVarsFile(inputVariables, map[string]bool{})
 
-	expectedOutput := `varTHM = "non-null value"
+	expectedOutput := `varNUL = "null"
+varTHM = "non-null value"
 varXYZ = "non-null value"
 var_1  = "val_1"
 var_2  = "val_2"
@@ -210,75 +304,188 @@ var_3  = "val_3"
 			strconv.Quote(string(byteArray)),
 			strconv.Quote(expectedOutput))
 	}
+}
 
-	// Testing that the value for tfe_token gets replaced correctly
-	inputWorkspaceVars = VariableMap{
-		"var_1":     "val_1",
-		"var_2":     "val_2",
-		"tfe_token": "value_to_replace",
+func TestGenerateTFVarsFileHCLVariables(t *testing.T) {
+	inputVariables := VariableMap{
+		"count":   "3",
+		"name":    `app "<main>"`,
+		"tags":    `{ team = "platform", tier = 2 }`,
+		"subnets": `["10.0.0.0/24", "10.0.1.0/24"]`,
+		"owner":   "null",
 	}
+	inputHCLVariables := map[string]bool{"count": true, "tags": true, "subnets": true, "owner": true}
 
-	byteArray, _ = tfc.generateTFVarsFile(inputWorkspaceVars, inputWorkspaceVarSetVars, inputWorkspaceSensitiveVars)
+	tfc := CreateTFC(t)
+	byteArray, err := tfc.generateTFVarsFile(inputVariables, inputHCLVariables)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 
-	expectedNotOutput := `tfe_token = "value_to_replace"
-varTHM = "non-null value"
-varXYZ = "non-null value"
-var_1 = "val_1"
-var_2 = "val_2"
-var_3 = "val_3"
+	expectedOutput := `count   = 3
+name    = "app \"<main>\""
+owner   = null
+subnets = ["10.0.0.0/24", "10.0.1.0/24"]
+tags = {
+  team = "platform"
+  tier = 2
+}
 `
 
-	if string(byteArray) == expectedNotOutput {
-		t.Errorf("got:\n%v\nexpected value not to be:\n%v",
+	if expectedOutput != string(byteArray) {
+		t.Errorf("got:\n%v\nexpected:\n%v",
 			strconv.Quote(string(byteArray)),
 			strconv.Quote(expectedOutput))
 	}
 }
 
-func TestGetWorkspaceToVarSetIds(t *testing.T) {
+func TestGenerateTFVarsJSONFile(t *testing.T) {
+	inputVariables := VariableMap{
+		"count":   "3",
+		"name":    `app "<main>"`,
+		"port":    "8080",
+		"tags":    `{ tier = 2, team = "platform" }`,
+		"subnets": `["10.0.0.0/24", "10.0.1.0/24"]`,
+		"owner":   "null",
+		"ratio":   "0.25",
+	}
+	inputHCLVariables := map[string]bool{"count": true, "tags": true, "subnets": true, "owner": true, "ratio": true}
+
 	tfc := CreateTFC(t)
 
-	output, err := tfc.getWorkspaceToVarSetIDs()
+	expectedOutput := `{
+  "count": 3,
+  "name": "app \"<main>\"",
+  "owner": null,
+  "port": "8080",
+  "ratio": 0.25,
+  "subnets": [
+    "10.0.0.0/24",
+    "10.0.1.0/24"
+  ],
+  "tags": {
+    "team": "platform",
+    "tier": 2
+  }
+}
+`
+
+	// The output is generated twice, as it must not depend upon map ordering.
+	for i := 0; i < 2; i++ {
+		byteArray, err := tfc.generateTFVarsJSONFile(inputVariables, inputHCLVariables)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if expectedOutput != string(byteArray) {
+			t.Errorf("got:\n%v\nexpected:\n%v",
+				strconv.Quote(string(byteArray)),
+				strconv.Quote(expectedOutput))
+		}
+	}
+
+	// HCL variables can only hold literal values, as Terraform Cloud does not evaluate them.
+	_, err := tfc.generateTFVarsJSONFile(VariableMap{"region": "var.default_region"}, map[string]bool{"region": true})
+	if err == nil {
+		t.Errorf("expected an error for an HCL variable that references another variable")
+	}
+}
+
+func TestVarsFileName(t *testing.T) {
+	tfc := tfCloud{config: &Config{TFVarsFormat: FormatHCL}}
+	if tfc.varsFileName() != "zz_dragondrop.auto.tfvars" {
+		t.Errorf("got %v, expected %v", tfc.varsFileName(), "zz_dragondrop.auto.tfvars")
+	}
+
+	tfc.config.TFVarsFormat = FormatJSON
+	if tfc.varsFileName() != "zz_dragondrop.auto.tfvars.json" {
+		t.Errorf("got %v, expected %v", tfc.varsFileName(), "zz_dragondrop.auto.tfvars.json")
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	directory := t.TempDir()
+	varsFile := filepath.Join(directory, varsFileName)
+	userVarsFile := filepath.Join(directory, "terraform.tfvars")
+
+	for _, fileName := range []string{varsFile, userVarsFile} {
+		err := os.WriteFile(fileName, []byte(`region = "us-east1"`), 0600)
+		if err != nil {
+			t.Fatalf("unexpected error writing %v: %v", fileName, err)
+		}
+	}
+
+	tfc := tfCloud{config: &Config{KeepVarsFiles: true}, writtenFiles: []string{varsFile}}
+
+	err := tfc.Cleanup()
 	if err != nil {
-		t.Errorf("unexpected error: %v", err)
+		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if output == nil {
-		t.Errorf("expected non-nil output from tfc.getWorkspaceToVarSetIDs")
+	if _, err := os.Stat(varsFile); err != nil {
+		t.Errorf("expected %v to be kept, got %v", varsFile, err)
+	}
+
+	tfc.config.KeepVarsFiles = false
+
+	err = tfc.Cleanup()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(varsFile); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected %v to be removed, got %v", varsFile, err)
+	}
+
+	if _, err := os.Stat(userVarsFile); err != nil {
+		t.Errorf("expected %v to be untouched, got %v", userVarsFile, err)
+	}
+
+	// A file that is already removed is not an error.
+	tfc.writtenFiles = []string{varsFile}
+
+	err = tfc.Cleanup()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
 	}
 }
 
-func TestGetVarSetIdsForOrg(t *testing.T) {
+func TestGetWorkspaceToVarSetScopes(t *testing.T) {
 	tfc := CreateTFC(t)
 
-	output, err := tfc.getVarSetIdsForOrg()
+	output, err := tfc.getWorkspaceToVarSetScopes(context.Background(), map[string]VarSet{}, tfc.workspaces())
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 
 	if output == nil {
-		t.Errorf("expected non-nil output from tfc.getVarSetIdsForOrg")
+		t.Errorf("expected non-nil output from tfc.getWorkspaceToVarSetScopes")
 	}
 }
 
-func TestGetWorkspaceToVarSetVars(t *testing.T) {
+func TestGetVarSetsForOrg(t *testing.T) {
 	tfc := CreateTFC(t)
 
-	workspaceToVarSetIDs, output, varSetIDToName, err := tfc.getWorkspaceToVarSetVars()
+	output, err := tfc.getVarSetsForOrg(context.Background())
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 
-	if varSetIDToName == nil {
-		t.Errorf("expected non-nil varSetIDToName from tfc.getWorkspaceToVarSetVars")
+	if output == nil {
+		t.Errorf("expected non-nil output from tfc.getVarSetsForOrg")
 	}
+}
+
+func TestGetWorkspaceToVarSetSources(t *testing.T) {
+	tfc := CreateTFC(t)
 
-	if workspaceToVarSetIDs == nil {
-		t.Errorf("expected non-nil workspaceToVarSetIDs from tfc.getWorkspaceToVarSetVars")
+	output, err := tfc.getWorkspaceToVarSetSources(context.Background(), tfc.workspaces())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
 	}
 
 	if output == nil {
-		t.Errorf("expected non-nil output from tfc.getWorkspaceToVarSetVars")
+		t.Errorf("expected non-nil output from tfc.getWorkspaceToVarSetSources")
 	}
 }
 
@@ -299,9 +506,10 @@ func TestGetWorkspaceVariables(t *testing.T) {
 func TestGetVarSetVars(t *testing.T) {
 	tfc := CreateTFC(t)
 
-	output, err := tfc.getVarSetVars(
-		map[string]string{
-			os.Getenv("TerraformCloudVarSetID"): "filler var set name",
+	output, _, _, err := tfc.getVarSetVars(
+		context.Background(),
+		map[string]VarSet{
+			os.Getenv("TerraformCloudVarSetID"): {Name: "filler var set name"},
 		},
 	)
 	if err != nil {
@@ -327,7 +535,7 @@ func TestBuildTFCloudHTTPRequest(t *testing.T) {
 	}
 
 	request, err := tfc.buildTFCloudHTTPRequest(
-		ctx, "testRequest", "GET", "https://test.com/",
+		ctx, "testRequest", "GET", "https://test.com/", nil,
 	)
 	if err != nil {
 		t.Errorf("Error in buildTFCloudHTTPRequest: %v", err)
@@ -365,6 +573,48 @@ func TestExtractWorkspaceID(t *testing.T) {
 	}
 }
 
+func TestExtractWorkspaceProjectID(t *testing.T) {
+	jsonBytes := []byte(`{
+		"data": {
+			"id": "ws-8675309",
+			"relationships": {"project": {"data": {"id": "prj-8675309", "type": "projects"}}}
+		}
+	}`)
+
+	outputValue := extractWorkspaceProjectID(jsonBytes)
+	if outputValue != "prj-8675309" {
+		t.Errorf("got %v, expected %v", outputValue, "prj-8675309")
+	}
+
+	outputValue = extractWorkspaceProjectID([]byte(`{"data": {"id": "ws-8675309"}}`))
+	if outputValue != "" {
+		t.Errorf("got %v, expected an empty project id", outputValue)
+	}
+}
+
+func TestWorkspaceVarSetScopes(t *testing.T) {
+	inputVarSets := map[string]VarSet{
+		"varset-global":    {Name: "global", Global: true},
+		"varset-project":   {Name: "project", ProjectIDs: map[string]bool{"prj-1": true}},
+		"varset-workspace": {Name: "workspace", WorkspaceIDs: map[string]bool{"ws-1": true}},
+		"varset-listed":    {Name: "listed"},
+		"varset-other":     {Name: "other", WorkspaceIDs: map[string]bool{"ws-2": true}, ProjectIDs: map[string]bool{"prj-2": true}},
+	}
+
+	expectedOutput := map[string]VarSetScope{
+		"varset-global":    ScopeGlobal,
+		"varset-project":   ScopeProject,
+		"varset-workspace": ScopeWorkspace,
+		"varset-listed":    ScopeWorkspace,
+	}
+
+	output := workspaceVarSetScopes(inputVarSets, "ws-1", "prj-1", map[string]bool{"varset-listed": true})
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+}
+
 func TestExtractWorkspaceVars(t *testing.T) {
 	inputResponse := []byte(`
 {
@@ -397,14 +647,30 @@ func TestExtractWorkspaceVars(t *testing.T) {
 			"sensitive": true,
             "hcl":false
          }
+      },
+      {
+         "id":"var-Rx3dy4pibb9nxo1",
+         "type":"vars",
+         "attributes":{
+            "key":"AWS_REGION",
+            "value":"us-east1",
+            "category":"env",
+            "sensitive": false,
+            "hcl":false
+         }
       }
    ]
 }
 `)
 
-	expectedOutput := VariableMap{
-		"varKey_1": "varVal_1",
-		"varKey_3": "varValue_3",
+	expectedOutput := map[string]VariableMap{
+		"terraform": {
+			"varKey_1": "varVal_1",
+			"varKey_3": "varValue_3",
+		},
+		"env": {
+			"AWS_REGION": "us-east1",
+		},
 	}
 
 	tfc := tfCloud{}
@@ -419,7 +685,93 @@ func TestExtractWorkspaceVars(t *testing.T) {
 	}
 }
 
-func TestExtractVarSetIDToName(t *testing.T) {
+func TestExtractHCLVars(t *testing.T) {
+	inputResponse := []byte(`
+{
+   "data":[
+      {
+         "id":"var-AD4pibb9nxo1468E",
+         "type":"vars",
+         "attributes":{"key":"varKey_1", "value":"varVal_1", "category":"terraform", "hcl":false}
+      },
+      {
+         "id":"var-dewc9nxoasdE",
+         "type":"vars",
+         "attributes":{"key":"varKey_2", "value":"[1, 2]", "category":"terraform", "hcl":true}
+      },
+      {
+         "id":"var-SDBnxoasdE",
+         "type":"vars",
+         "attributes":{"key":"VAR_KEY_3", "value":"{}", "category":"env", "hcl":true}
+      },
+      {
+         "id":"var-SDBnxoasdF",
+         "type":"vars",
+         "attributes":{"key":"varKey_4", "value":null, "category":"terraform", "sensitive":true, "hcl":true}
+      }
+   ]
+}
+`)
+
+	expectedOutput := map[string]bool{
+		"varKey_2": true,
+		"varKey_4": true,
+	}
+
+	output, err := extractHCLVars(inputResponse)
+	if err != nil {
+		t.Errorf("unexpected err in extractHCLVars: %v", err)
+	}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+}
+
+func TestExtractWithheldVars(t *testing.T) {
+	inputResponse := []byte(`
+{
+   "data":[
+      {
+         "id":"var-AD4pibb9nxo1468E",
+         "type":"vars",
+         "attributes":{"key":"varKey_1", "value":"varVal_1", "category":"terraform", "sensitive":false}
+      },
+      {
+         "id":"var-dewc9nxoasdE",
+         "type":"vars",
+         "attributes":{"key":"varKey_2", "value":null, "category":"terraform", "sensitive":true}
+      },
+      {
+         "id":"var-SDBnxoasdE",
+         "type":"vars",
+         "attributes":{"key":"VAR_KEY_3", "value":null, "category":"env", "sensitive":true}
+      },
+      {
+         "id":"var-SDBnxoasdF",
+         "type":"vars",
+         "attributes":{"key":"varKey_4", "value":"varValue_4", "sensitive":true}
+      }
+   ]
+}
+`)
+
+	expectedOutput := map[string]string{
+		"varKey_2":  "terraform",
+		"VAR_KEY_3": "env",
+	}
+
+	output, err := extractWithheldVars(inputResponse)
+	if err != nil {
+		t.Errorf("unexpected err in extractWithheldVars: %v", err)
+	}
+
+	if !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("got %v, expected %v", output, expectedOutput)
+	}
+}
+
+func TestExtractVarSets(t *testing.T) {
 	inputResponse := []byte(`{
   "data": [
     {
@@ -451,6 +803,8 @@ func TestExtractVarSetIDToName(t *testing.T) {
       "type": "varsets",
       "attributes":  {
          "name": "name_2",
+         "global": true,
+         "priority": true,
          "workspace-count": 2
       },
       "relationships": {
@@ -467,6 +821,11 @@ func TestExtractVarSetIDToName(t *testing.T) {
            {"id": "ws-xyze12345", "type": "workspaces"},
            {"id": "ws-xyze12346", "type": "workspaces"}
           ]
+        },
+        "projects": {
+          "data": [
+           {"id": "prj-abcd12345", "type": "projects"}
+          ]
         }
       }
     }
@@ -474,16 +833,26 @@ func TestExtractVarSetIDToName(t *testing.T) {
 }
 `)
 
-	expectedOutputMapToSet := map[string]string{
-		"varset-mio9UUFyFMjU33S4":  "name_1",
-		"varset-tuyo9UUFyFMjU33S4": "name_2",
+	expectedOutputMapToSet := map[string]VarSet{
+		"varset-mio9UUFyFMjU33S4": {
+			Name:         "name_1",
+			WorkspaceIDs: map[string]bool{"ws-abcd12345": true, "ws-abcd12346": true},
+			ProjectIDs:   map[string]bool{},
+		},
+		"varset-tuyo9UUFyFMjU33S4": {
+			Name:         "name_2",
+			Global:       true,
+			Priority:     true,
+			WorkspaceIDs: map[string]bool{"ws-xyze12345": true, "ws-xyze12346": true},
+			ProjectIDs:   map[string]bool{"prj-abcd12345": true},
+		},
 	}
 
 	tfc := tfCloud{}
 
-	outputMapToSet, err := tfc.extractVarSetIDToName(inputResponse)
+	outputMapToSet, err := tfc.extractVarSets(inputResponse)
 	if err != err {
-		t.Errorf("unexpected error in tfc.extractVarSetInformation: %v", err)
+		t.Errorf("unexpected error in tfc.extractVarSets: %v", err)
 	}
 
 	if !reflect.DeepEqual(outputMapToSet, expectedOutputMapToSet) {
@@ -564,19 +933,37 @@ func TestExtractVarsFromVarSet(t *testing.T) {
         "category": "terraform",
         "hcl": false,
         "created-at": "2021-10-29T18:54:29.379Z",
+        "description": ""
+		}
+	},
+	{
+      "id": "var-6bb9nxo1468E",
+      "type": "vars",
+      "attributes": {
+        "key": "TF_LOG",
+        "value": "DEBUG",
+        "sensitive": false,
+        "category": "env",
+        "hcl": false,
+        "created-at": "2021-10-29T18:54:29.379Z",
         "description": ""
 		}
 	}
   	]
 	}`)
 
-	inputVarSetToVars := map[string]VariableMap{}
+	inputVarSetToVars := map[string]map[string]VariableMap{}
 
 	inputVarSetID := "test-var-set-id"
 
-	expectedOutput := map[string]VariableMap{
+	expectedOutput := map[string]map[string]VariableMap{
 		"test-var-set-id": {
-			"asd7558b045dd82da40b089e5db745": "asdazxc0dfd3060e2c37890422905f",
+			"terraform": {
+				"asd7558b045dd82da40b089e5db745": "asdazxc0dfd3060e2c37890422905f",
+			},
+			"env": {
+				"TF_LOG": "DEBUG",
+			},
 		},
 	}
 
@@ -619,7 +1006,7 @@ func TestTerraformCloudRequest(t *testing.T) {
 	}
 
 	request, _ := tfc.buildTFCloudHTTPRequest(
-		ctx, "testRequest", "GET", server.URL+"/terraform/cloud/",
+		ctx, "testRequest", "GET", server.URL+"/terraform/cloud/", nil,
 	)
 
 	output, err := tfc.terraformCloudRequest(request, "testRequest")
@@ -633,23 +1020,6 @@ func TestTerraformCloudRequest(t *testing.T) {
 	}
 }
 
-func TestUpdateEnvironmentVariables(t *testing.T) {
-	tfc := CreateTFC(t)
-	inputMap := VariableMap{
-		"example_var": "example_vars_value",
-	}
-
-	err := tfc.updateEnvironmentVariables(inputMap)
-
-	if err != nil {
-		t.Errorf("unexpected error in tfc.updateEnvironmentVariables: %v", err)
-	}
-
-	if os.Getenv("example_var") != "example_vars_value" {
-		t.Errorf("got %v, expected 'example_vars_value'", os.Getenv("example_var"))
-	}
-}
-
 func TestVariablesToVariableMaps(t *testing.T) {
 	tfc := CreateTFC(t)
 
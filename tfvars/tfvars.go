@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"os"
 )
 
 // TFVars is an interface that allows for the extraction of
@@ -15,25 +14,55 @@ type TFVars interface {
 	DownloadWorkspaceVariables(ctx context.Context, workspaceName string) ([]byte, error)
 
 	// CreateAllWorkspaceVarsFiles extracts variables for all workspaces and saves them into
-	// .tfvars files within the appropriate directory.
-	CreateAllWorkspaceVarsFiles() error
+	// .tfvars files within the appropriate directory, returning the names of the variables
+	// sourced for each workspace along with each workspace's environment variables.
+	CreateAllWorkspaceVarsFiles(ctx context.Context) (map[string]SourcedVariables, map[string]VariableMap, error)
+
+	// Cleanup removes the .tfvars files written by CreateAllWorkspaceVarsFiles, unless they are to be kept.
+	Cleanup() error
+
+	// ExportWorkspaceVariables resolves the variables of each workspace, or of every configured
+	// workspace when none are given, without writing them anywhere.
+	ExportWorkspaceVariables(ctx context.Context, workspaces []string) (map[string][]ExportedVariable, error)
+
+	// PlanVariableSync compares the variables declared by a SyncFile with those within Terraform
+	// Cloud, returning the changes that make them match.
+	PlanVariableSync(ctx context.Context, syncFile SyncFile) ([]SyncChange, error)
+
+	// ApplyVariableSync makes the changes of PlanVariableSync within Terraform Cloud, returning the
+	// number of changes made.
+	ApplyVariableSync(ctx context.Context, changes []SyncChange) (int, error)
+}
+
+// SourcedVariables lists the names, but never the values, of the variables sourced for a workspace.
+type SourcedVariables struct {
+
+	// Terraform are the names of the variables written to the workspace's .tfvars file.
+	Terraform []string `json:"terraform"`
+
+	// Env are the names of the environment variables set for the workspace's commands.
+	Env []string `json:"env"`
+
+	// TerraformSources maps each Terraform variable to the source its value was taken from, either
+	// "workspace" or "varset:<name>".
+	TerraformSources map[string]string `json:"terraform_sources"`
+
+	// EnvSources maps each environment variable to the source its value was taken from.
+	EnvSources map[string]string `json:"env_sources"`
 }
 
 // NewTFVars instantiates a new implementation of the tfVars interface.
 func NewTFVars() (TFVars, error) {
 	conf, err := NewConfig()
 	if err != nil {
-		return nil, fmt.Errorf("[NewConfig] %v", err)
+		return nil, fmt.Errorf("[NewConfig] %w", err)
 	}
 
-	// This allows the terraform command to make calls to Terraform Cloud
-	err = os.Setenv("TF_TOKEN_app_terraform_io", conf.TerraformCloudToken)
-	if err != nil {
-		return nil, fmt.Errorf("[os.Setenv] %v", err)
-	}
+	httpClient := http.Client{}
 
 	return &tfCloud{
-		config:     conf,
-		httpClient: http.Client{},
+		config:        conf,
+		httpClient:    httpClient,
+		secretSources: newSecretSources(conf, httpClient),
 	}, nil
 }
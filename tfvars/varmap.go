@@ -1,5 +1,7 @@
 package tfvars
 
+import "sort"
+
 // VariableMap is a collection of variable key value pairs stored within a map.
 type VariableMap map[string]string
 
@@ -18,3 +20,25 @@ func (vm *VariableMap) Merge(other VariableMap) VariableMap {
 
 	return combinationMap
 }
+
+// Keys returns the variable names within the VariableMap, sorted alphabetically.
+func (vm VariableMap) Keys() []string {
+	keys := make([]string, 0, len(vm))
+	for k := range vm {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Environ returns the variables as "KEY=value" entries, sorted by name, for use as a command's
+// environment.
+func (vm VariableMap) Environ() []string {
+	environ := make([]string, 0, len(vm))
+	for _, k := range vm.Keys() {
+		environ = append(environ, k+"="+vm[k])
+	}
+
+	return environ
+}
@@ -30,3 +30,34 @@ func TestMerge(t *testing.T) {
 		t.Errorf("got %v, expected %v", newMap, expectedOutput)
 	}
 }
+
+func TestKeys(t *testing.T) {
+	varMap := VariableMap{
+		"var_2": "val_2",
+		"var_1": "val_1",
+		"var_3": "val_3",
+	}
+
+	expectedOutput := []string{"var_1", "var_2", "var_3"}
+
+	if !reflect.DeepEqual(varMap.Keys(), expectedOutput) {
+		t.Errorf("got %v, expected %v", varMap.Keys(), expectedOutput)
+	}
+
+	if len(VariableMap{}.Keys()) != 0 {
+		t.Errorf("expected no keys for an empty VariableMap")
+	}
+}
+
+func TestEnviron(t *testing.T) {
+	varMap := VariableMap{
+		"TF_LOG":     "DEBUG",
+		"AWS_REGION": "us-east-1",
+	}
+
+	expectedOutput := []string{"AWS_REGION=us-east-1", "TF_LOG=DEBUG"}
+
+	if !reflect.DeepEqual(varMap.Environ(), expectedOutput) {
+		t.Errorf("got %v, expected %v", varMap.Environ(), expectedOutput)
+	}
+}